@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"path"
 	"path/filepath"
@@ -13,6 +12,8 @@ import (
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+
+	"github.com/gisvr/golib/log"
 )
 
 const (
@@ -153,14 +154,14 @@ func (f *file) daemon() {
 	defer f.wg.Done()
 	fswatcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		log.Printf("create file watcher fail! reload function will lose efficacy error: %s", err)
+		log.Errorf("create file watcher fail! reload function will lose efficacy error: %s", err)
 		return
 	}
 	if err = fswatcher.Add(f.base); err != nil {
-		log.Printf("create fsnotify for base path %s fail %s, reload function will lose efficacy", f.base, err)
+		log.Errorf("create fsnotify for base path %s fail %s, reload function will lose efficacy", f.base, err)
 		return
 	}
-	log.Printf("start watch filepath: %s", f.base)
+	log.Infof("start watch filepath: %s", f.base)
 	for event := range fswatcher.Events {
 		switch event.Op {
 		// use vim edit config will trigger rename
@@ -168,7 +169,7 @@ func (f *file) daemon() {
 			f.reloadFile(event.Name)
 		case fsnotify.Chmod:
 		default:
-			log.Printf("unsupport event %s ingored", event)
+			log.Warnf("unsupport event %s ingored", event)
 		}
 	}
 }
@@ -183,7 +184,7 @@ func (f *file) reloadFile(name string) {
 	key := filepath.Base(name)
 	val, err := loadValue(name)
 	if err != nil {
-		log.Printf("load file %s error: %s, skipped", name, err)
+		log.Errorf("load file %s error: %s, skipped", name, err)
 		return
 	}
 	f.rawVal[key] = val
@@ -197,7 +198,7 @@ func (f *file) reloadFile(name string) {
 		select {
 		case ch <- Event{Event: EventUpdate, Key: key, Value: val.raw}:
 		default:
-			log.Printf("event channel full discard file %s update event", name)
+			log.Warnf("event channel full discard file %s update event", name)
 		}
 	}
 }
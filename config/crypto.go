@@ -6,10 +6,10 @@ import (
 	"encoding/base64"
 	"errors"
 	"io"
-	"log"
 	"reflect"
 
 	"github.com/gisvr/golib/crypt"
+	"github.com/gisvr/golib/log"
 )
 
 const (
@@ -209,7 +209,7 @@ func Decrypt(v string) (string, error) {
 
 	dat, err := base64.StdEncoding.DecodeString(v)
 	if err != nil {
-		log.Printf("decrypt base64 decode error: %v", err)
+		log.Warnf("decrypt base64 decode error: %v", err)
 		return v, nil
 	}
 
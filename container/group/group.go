@@ -35,6 +35,13 @@ func (g *Group) Get(key string) interface{} {
 	return obj
 }
 
+// Range calls f for each existing object, it stops if f returns false.
+func (g *Group) Range(f func(key string, obj interface{}) bool) {
+	g.objs.Range(func(key, value interface{}) bool {
+		return f(key.(string), value)
+	})
+}
+
 // Reset resets the new function and deletes all existing objects.
 func (g *Group) Reset(new func() interface{}) {
 	if new == nil {
@@ -116,6 +116,12 @@ func (q *Queue) Push(ctx context.Context) (err error) {
 	return
 }
 
+// Judge reports whether the request enqueued at ts should be dropped by its
+// queueing delay, it is used by the callers which keep their own waiters.
+func (q *Queue) Judge(ts time.Time) bool {
+	return q.judge(packet{ts: ts.UnixNano() / int64(time.Millisecond)})
+}
+
 // Pop req from CoDel request buffer queue.
 func (q *Queue) Pop() {
 	for {
@@ -0,0 +1,252 @@
+package log
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gisvr/golib/stat/metric"
+)
+
+// overflow policies of the async handler.
+const (
+	// PolicyBlock blocks the caller until there is room.
+	PolicyBlock = "block"
+	// PolicyDropNewest drops the log being added.
+	PolicyDropNewest = "drop_newest"
+	// PolicyDropLowest drops the oldest log of the lowest level, which may be
+	// the log being added if its level is the lowest.
+	PolicyDropLowest = "drop_lowest"
+)
+
+var _metricAsyncDropped = metric.NewCounterVec(&metric.CounterVecOpts{
+	Namespace: "log",
+	Subsystem: "async",
+	Name:      "dropped_total",
+	Help:      "log async handler dropped logs.",
+	Labels:    []string{"level", "policy"},
+})
+
+// AsyncOption is the async handler option.
+type AsyncOption struct {
+	// BufferSize is the max buffered logs, default 8192.
+	BufferSize int `yaml:"bufferSize"`
+	// BatchSize is the max logs handled by a batch, default 128.
+	BatchSize int `yaml:"batchSize"`
+	// Policy is the overflow policy, block(default), drop_newest or drop_lowest.
+	Policy string `yaml:"policy"`
+}
+
+// entry is a buffered log.
+type entry struct {
+	ctx   context.Context
+	depth int
+	lv    Level
+	d     []D
+}
+
+// ring is a bounded fifo of entries.
+type ring struct {
+	buf  []entry
+	head int
+	n    int
+}
+
+func (r *ring) at(i int) *entry {
+	return &r.buf[(r.head+i)%len(r.buf)]
+}
+
+func (r *ring) push(e entry) {
+	*r.at(r.n) = e
+	r.n++
+}
+
+func (r *ring) pop() entry {
+	e := *r.at(0)
+	*r.at(0) = entry{}
+	r.head = (r.head + 1) % len(r.buf)
+	r.n--
+	return e
+}
+
+// remove removes the i-th entry, the following ones are moved forward.
+func (r *ring) remove(i int) {
+	for ; i < r.n-1; i++ {
+		*r.at(i) = *r.at(i + 1)
+	}
+	*r.at(r.n - 1) = entry{}
+	r.n--
+}
+
+// AsyncHandler handles the logs of the next handler in a goroutine, the logs
+// are buffered and handled in batches.
+type AsyncHandler struct {
+	next Handler
+	opt  *AsyncOption
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	drained  *sync.Cond
+	ring     ring
+	busy     bool
+	closed   bool
+	done     chan struct{}
+}
+
+// NewAsync returns an async handler in front of next.
+func NewAsync(next Handler, opt *AsyncOption) *AsyncHandler {
+	o := &AsyncOption{}
+	if opt != nil {
+		*o = *opt
+	}
+	if o.BufferSize <= 0 {
+		o.BufferSize = 8192
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 128
+	}
+	if o.Policy == "" {
+		o.Policy = PolicyBlock
+	}
+	h := &AsyncHandler{
+		next: next,
+		opt:  o,
+		ring: ring{buf: make([]entry, o.BufferSize)},
+		done: make(chan struct{}),
+	}
+	h.notEmpty = sync.NewCond(&h.mu)
+	h.notFull = sync.NewCond(&h.mu)
+	h.drained = sync.NewCond(&h.mu)
+	go h.proc()
+	return h
+}
+
+// Log buffers the log, the fatal logs are flushed before it returns.
+func (h *AsyncHandler) Log(ctx context.Context, depth int, lv Level, args ...D) {
+	e := entry{ctx: ctx, depth: depth, lv: lv, d: append([]D(nil), args...)}
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		h.next.Log(ctx, depth, lv, args...)
+		return
+	}
+	if h.ring.n >= len(h.ring.buf) && !h.overflow(&e) {
+		closed := h.closed
+		h.mu.Unlock()
+		if closed {
+			h.next.Log(ctx, depth, lv, args...)
+		}
+		return
+	}
+	h.ring.push(e)
+	h.notEmpty.Signal()
+	if lv < _fatalLevel {
+		h.mu.Unlock()
+		return
+	}
+	h.flush()
+	h.mu.Unlock()
+	if f, ok := h.next.(interface{ Flush() }); ok {
+		f.Flush()
+	}
+}
+
+// overflow makes room for e by the policy, it returns false if e is dropped.
+func (h *AsyncHandler) overflow(e *entry) bool {
+	switch h.opt.Policy {
+	case PolicyDropNewest:
+		_metricAsyncDropped.Inc(e.lv.String(), h.opt.Policy)
+		return false
+	case PolicyDropLowest:
+		lowest := -1
+		for i := 0; i < h.ring.n; i++ {
+			if lv := h.ring.at(i).lv; lv < e.lv && (lowest < 0 || lv < h.ring.at(lowest).lv) {
+				lowest = i
+			}
+		}
+		if lowest < 0 {
+			_metricAsyncDropped.Inc(e.lv.String(), h.opt.Policy)
+			return false
+		}
+		_metricAsyncDropped.Inc(h.ring.at(lowest).lv.String(), h.opt.Policy)
+		h.ring.remove(lowest)
+		return true
+	default:
+		for h.ring.n >= len(h.ring.buf) && !h.closed {
+			h.notFull.Wait()
+		}
+		return !h.closed
+	}
+}
+
+// flush waits until the buffered logs are handled, the lock must be held.
+func (h *AsyncHandler) flush() {
+	for (h.ring.n > 0 || h.busy) && !h.closed {
+		h.drained.Wait()
+	}
+}
+
+// Flush waits until the buffered logs are handled, and flushes the next
+// handler if it has a Flush method.
+func (h *AsyncHandler) Flush() {
+	h.mu.Lock()
+	h.flush()
+	h.mu.Unlock()
+	if f, ok := h.next.(interface{ Flush() }); ok {
+		f.Flush()
+	}
+}
+
+func (h *AsyncHandler) proc() {
+	defer close(h.done)
+	batch := make([]entry, 0, h.opt.BatchSize)
+	for {
+		h.mu.Lock()
+		for h.ring.n == 0 && !h.closed {
+			h.drained.Broadcast()
+			h.notEmpty.Wait()
+		}
+		if h.ring.n == 0 {
+			h.drained.Broadcast()
+			h.mu.Unlock()
+			return
+		}
+		for h.ring.n > 0 && len(batch) < cap(batch) {
+			batch = append(batch, h.ring.pop())
+		}
+		h.busy = true
+		h.notFull.Broadcast()
+		h.mu.Unlock()
+
+		for i := range batch {
+			h.next.Log(batch[i].ctx, batch[i].depth, batch[i].lv, batch[i].d...)
+			batch[i] = entry{}
+		}
+		batch = batch[:0]
+
+		h.mu.Lock()
+		h.busy = false
+		h.mu.Unlock()
+	}
+}
+
+// Close handles the buffered logs and closes the next handler.
+func (h *AsyncHandler) Close() error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil
+	}
+	h.closed = true
+	h.notEmpty.Broadcast()
+	h.notFull.Broadcast()
+	h.drained.Broadcast()
+	h.mu.Unlock()
+	<-h.done
+	return h.next.Close()
+}
+
+// SetFormat sets the format of the next handler.
+func (h *AsyncHandler) SetFormat(format string) {
+	h.next.SetFormat(format)
+}
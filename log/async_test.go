@@ -0,0 +1,111 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordHandler records the messages, it blocks until release is closed.
+type recordHandler struct {
+	mu      sync.Mutex
+	msgs    []string
+	release chan struct{}
+	flushed int
+	closed  bool
+}
+
+func newRecordHandler() *recordHandler {
+	return &recordHandler{release: make(chan struct{})}
+}
+
+func (h *recordHandler) Log(ctx context.Context, depth int, lv Level, args ...D) {
+	<-h.release
+	h.mu.Lock()
+	h.msgs = append(h.msgs, args[0].StringVal)
+	h.mu.Unlock()
+}
+
+func (h *recordHandler) Flush() {
+	h.mu.Lock()
+	h.flushed++
+	h.mu.Unlock()
+}
+
+func (h *recordHandler) SetFormat(string) {}
+
+func (h *recordHandler) Close() error {
+	h.closed = true
+	return nil
+}
+
+func TestAsyncClose(t *testing.T) {
+	next := newRecordHandler()
+	close(next.release)
+	h := NewAsync(next, &AsyncOption{BufferSize: 4, BatchSize: 2})
+	for _, msg := range []string{"a", "b", "c", "d", "e", "f"} {
+		h.Log(context.Background(), 0, _infoLevel, KVMessage(msg))
+	}
+	assert.NoError(t, h.Close())
+	assert.Equal(t, []string{"a", "b", "c", "d", "e", "f"}, next.msgs, "the logs are handled in order and flushed")
+	assert.True(t, next.closed)
+
+	h.Log(context.Background(), 0, _infoLevel, KVMessage("g"))
+	assert.Equal(t, "g", next.msgs[6], "the logs are handled synchronously after closed")
+}
+
+func TestAsyncDrop(t *testing.T) {
+	next := newRecordHandler()
+	h := NewAsync(next, &AsyncOption{BufferSize: 2, BatchSize: 1, Policy: PolicyDropNewest})
+	// the first log is taken by the blocked worker.
+	h.Log(context.Background(), 0, _infoLevel, KVMessage("a"))
+	for busy := false; !busy; time.Sleep(time.Millisecond) {
+		h.mu.Lock()
+		busy = h.busy
+		h.mu.Unlock()
+	}
+	for _, msg := range []string{"b", "c", "d"} {
+		h.Log(context.Background(), 0, _infoLevel, KVMessage(msg))
+	}
+	close(next.release)
+	h.Close()
+	assert.Equal(t, []string{"a", "b", "c"}, next.msgs)
+}
+
+func TestAsyncDropLowest(t *testing.T) {
+	next := newRecordHandler()
+	h := NewAsync(next, &AsyncOption{BufferSize: 3, BatchSize: 1, Policy: PolicyDropLowest})
+	h.mu.Lock()
+	// buffer the logs without the worker.
+	for _, e := range []struct {
+		lv  Level
+		msg string
+	}{{_warnLevel, "w1"}, {_infoLevel, "i1"}, {_infoLevel, "i2"}} {
+		h.ring.push(entry{ctx: context.Background(), lv: e.lv, d: []D{KVMessage(e.msg)}})
+	}
+	e := entry{lv: _errorLevel, d: []D{KVMessage("e1")}}
+	assert.True(t, h.overflow(&e))
+	h.ring.push(e)
+	e = entry{lv: _infoLevel, d: []D{KVMessage("i3")}}
+	assert.False(t, h.overflow(&e), "the newest log of the lowest level is dropped")
+	h.mu.Unlock()
+	close(next.release)
+	h.Close()
+	assert.Equal(t, []string{"w1", "i2", "e1"}, next.msgs)
+}
+
+func TestAsyncFatal(t *testing.T) {
+	next := newRecordHandler()
+	close(next.release)
+	h := NewAsync(next, nil)
+	h.Log(context.Background(), 0, _infoLevel, KVMessage("a"))
+	h.Log(context.Background(), 0, _fatalLevel, KVMessage("b"))
+	next.mu.Lock()
+	assert.Equal(t, []string{"a", "b"}, next.msgs, "the fatal log is flushed")
+	assert.Equal(t, 1, next.flushed)
+	next.mu.Unlock()
+	h.Close()
+}
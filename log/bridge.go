@@ -0,0 +1,98 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	stdlog "log"
+	"runtime"
+	"strings"
+)
+
+const _stdWriterWrite = "github.com/gisvr/golib/log.(*stdWriter).Write"
+
+// _restores restores the loggers redirected by Init, they are run by Close.
+var _restores []func()
+
+// redirect redirects the loggers of the stdlib and grpc by the config.
+func redirect(conf *Config) {
+	unredirect()
+	if conf.RedirectStdLog {
+		_restores = append(_restores, RedirectStdLog(_infoLevel))
+	}
+	if conf.RedirectGRPCLog {
+		_restores = append(_restores, RedirectGRPCLog())
+	}
+}
+
+// unredirect restores the loggers redirected by Init.
+func unredirect() {
+	for _, restore := range _restores {
+		restore()
+	}
+	_restores = nil
+}
+
+// bridgeDepth returns the depth of the first caller not skipped, which is
+// passed to h.Log by the caller of bridgeDepth, it also returns the last
+// skipped function, eg: log.Fatalf of the stdlib.
+// The bridges use it because the call stacks of the libraries vary.
+func bridgeDepth(skip func(fn string) bool) (depth int, last string) {
+	pcs := make([]uintptr, 32)
+	// pcs[0] is the caller of bridgeDepth.
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for i := 0; ; i++ {
+		f, more := frames.Next()
+		if !skip(f.Function) {
+			// h.Log reports the depth+1 caller of the bridge.
+			return i - 1, last
+		}
+		last = f.Function
+		if !more {
+			return 0, ""
+		}
+	}
+}
+
+// stdWriter writes the logs of the stdlib log to the handlers.
+type stdWriter struct {
+	lv Level
+}
+
+func isStdLog(fn string) bool {
+	return strings.HasPrefix(fn, "log.") || fn == _stdWriterWrite
+}
+
+// Write logs p at the level of the writer, or the fatal and error level
+// if it is written by the Fatal and Panic functions.
+func (w *stdWriter) Write(p []byte) (int, error) {
+	depth, fn := bridgeDepth(isStdLog)
+	lv := w.lv
+	switch {
+	case strings.Contains(fn, ".Fatal"):
+		lv = _fatalLevel
+	case strings.Contains(fn, ".Panic"):
+		lv = _errorLevel
+	}
+	h.Log(context.Background(), depth, lv, KVString(_log, string(bytes.TrimRight(p, "\n"))))
+	return len(p), nil
+}
+
+// NewStdLogger returns a stdlib logger which logs to the handlers at the level.
+func NewStdLogger(lv Level) *stdlog.Logger {
+	return stdlog.New(&stdWriter{lv: lv}, "", 0)
+}
+
+// RedirectStdLog redirects the standard logger of the stdlib log to the
+// handlers at the level, Fatal and Panic are logged at the fatal and error
+// level, it returns a function which restores the standard logger.
+func RedirectStdLog(lv Level) (restore func()) {
+	flags, prefix, w := stdlog.Flags(), stdlog.Prefix(), stdlog.Writer()
+	stdlog.SetOutput(&stdWriter{lv: lv})
+	stdlog.SetFlags(0)
+	return func() {
+		stdlog.SetOutput(w)
+		stdlog.SetFlags(flags)
+		stdlog.SetPrefix(prefix)
+	}
+}
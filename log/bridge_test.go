@@ -0,0 +1,65 @@
+package log
+
+import (
+	stdlog "log"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/grpclog"
+)
+
+func TestRedirectStdLog(t *testing.T) {
+	fh := withFieldsHandler(t)
+	restore := RedirectStdLog(_warnLevel)
+	defer restore()
+
+	stdlog.Printf("std %d", 1)
+	assert.Panics(t, func() { stdlog.Panicf("std %d", 2) })
+	NewStdLogger(_infoLevel).Println("std 3")
+
+	assert.Len(t, fh.logs, 3)
+	for i, lv := range []Level{_warnLevel, _errorLevel, _infoLevel} {
+		assert.Equal(t, lv.String(), fh.logs[i][_level])
+		assert.Equal(t, "bridge_test.go", filepath.Base(fh.logs[i][_fileName].(string)))
+	}
+	assert.Equal(t, "std 1", fh.logs[0][_log])
+	assert.Equal(t, "std 3", fh.logs[2][_log])
+}
+
+func TestRedirectGRPCLog(t *testing.T) {
+	fh := withFieldsHandler(t)
+	restore := RedirectGRPCLog()
+	defer restore()
+
+	grpclog.Infof("grpc %d", 1)
+	grpclog.Warning("grpc ", 2)
+	grpclog.Errorln("grpc", 3)
+
+	assert.Len(t, fh.logs, 3)
+	for i, lv := range []Level{_infoLevel, _warnLevel, _errorLevel} {
+		assert.Equal(t, lv.String(), fh.logs[i][_level])
+		assert.Equal(t, "bridge_test.go", filepath.Base(fh.logs[i][_fileName].(string)))
+	}
+	assert.Equal(t, "grpc 1", fh.logs[0][_log])
+	assert.Equal(t, "grpc 2", fh.logs[1][_log])
+	assert.Equal(t, "grpc 3", fh.logs[2][_log])
+}
+
+func TestInitRedirect(t *testing.T) {
+	w := stdlog.Writer()
+	Init(&Config{Stdout: &StdoutOption{}, RedirectStdLog: true})
+	_, ok := stdlog.Writer().(*stdWriter)
+	assert.True(t, ok)
+	Close()
+	assert.Equal(t, w, stdlog.Writer())
+
+	Init(&Config{Stdout: &StdoutOption{}})
+	assert.Equal(t, w, stdlog.Writer())
+	Close()
+
+	// the default config does not take over the stdlib log.
+	Init(nil)
+	assert.Equal(t, w, stdlog.Writer())
+	Close()
+}
@@ -0,0 +1,55 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+)
+
+// the builtin encoders selectable by the Encoder of the handler options.
+const (
+	// EncoderPattern renders the % pattern of Fmt, see StdoutHandler.SetFormat.
+	EncoderPattern = "pattern"
+	// EncoderJSON renders the fields selected by the % pattern of Fmt as json.
+	EncoderJSON = "json"
+	// EncoderLogfmt renders the fields selected by the % pattern of Fmt as logfmt.
+	EncoderLogfmt = "logfmt"
+	// EncoderOTLP renders the OTLP/JSON logs of the OpenTelemetry log data
+	// model, one ExportLogsServiceRequest per line, Fmt is ignored.
+	EncoderOTLP = "otlp"
+)
+
+var (
+	_encodersMu sync.RWMutex
+	_encoders   = map[string]func(format string) Render{
+		EncoderPattern: newPatternRender,
+		EncoderJSON:    newJsonPatternRender,
+		EncoderLogfmt:  newLogfmtRender,
+		EncoderOTLP:    newOTLPRender,
+	}
+)
+
+// RegisterEncoder registers an encoder, which is selectable by the Encoder of
+// the handler options, fn returns the Render of the format.
+func RegisterEncoder(name string, fn func(format string) Render) {
+	_encodersMu.Lock()
+	_encoders[name] = fn
+	_encodersMu.Unlock()
+}
+
+// newRender returns the Render of the encoder, the json encoder is used if
+// encoder is empty and json is true, which is the legacy option.
+func newRender(encoder string, json bool, format string) Render {
+	if encoder == "" {
+		encoder = EncoderPattern
+		if json {
+			encoder = EncoderJSON
+		}
+	}
+	_encodersMu.RLock()
+	fn, ok := _encoders[encoder]
+	_encodersMu.RUnlock()
+	if !ok {
+		panic(fmt.Sprintf("log: unknown encoder: %s", encoder))
+	}
+	return fn(format)
+}
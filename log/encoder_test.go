@@ -0,0 +1,100 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogfmtRender(t *testing.T) {
+	buf := &bytes.Buffer{}
+	p := newRender(EncoderLogfmt, false, "%L %s %M")
+	fields := append(testFields(), KVString("a b", `say "hi"`), KVString("empty", ""))
+	assert.NoError(t, p.RenderFields(buf, fields))
+	assert.Equal(t, `level=INFO filename=c.go linenum=12 log=hello n=1 f=0.5 d=1s user=u2 a_b="say \"hi\"" empty=""`+"\n", buf.String())
+}
+
+func TestOTLPRender(t *testing.T) {
+	buf := &bytes.Buffer{}
+	p := newRender(EncoderOTLP, false, "")
+	fields := append(testFields(), KVString(_tid, "1f:2a:0:1"))
+	assert.NoError(t, p.RenderFields(buf, fields))
+
+	var req struct {
+		ResourceLogs []struct {
+			Resource struct {
+				Attributes []map[string]interface{} `json:"attributes"`
+			} `json:"resource"`
+			ScopeLogs []struct {
+				LogRecords []struct {
+					TimeUnixNano   string                   `json:"timeUnixNano"`
+					SeverityNumber int                      `json:"severityNumber"`
+					SeverityText   string                   `json:"severityText"`
+					Body           map[string]interface{}   `json:"body"`
+					Attributes     []map[string]interface{} `json:"attributes"`
+					TraceID        string                   `json:"traceId"`
+					SpanID         string                   `json:"spanId"`
+				} `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &req), buf.String())
+	assert.Len(t, req.ResourceLogs, 1)
+	assert.Equal(t, "service.name", req.ResourceLogs[0].Resource.Attributes[0]["key"])
+
+	r := req.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	assert.NotEmpty(t, r.TimeUnixNano)
+	assert.Equal(t, 9, r.SeverityNumber)
+	assert.Equal(t, "INFO", r.SeverityText)
+	assert.Equal(t, "hello", r.Body["stringValue"])
+	assert.Equal(t, "0000000000000000000000000000001f", r.TraceID)
+	assert.Equal(t, "000000000000002a", r.SpanID)
+
+	attrs := make(map[string]interface{})
+	for _, a := range r.Attributes {
+		attrs[a["key"].(string)] = a["value"]
+	}
+	assert.Equal(t, map[string]interface{}{"stringValue": "/a/b/c.go"}, attrs["code.filepath"])
+	assert.Equal(t, map[string]interface{}{"intValue": "12"}, attrs["code.lineno"])
+	assert.Equal(t, map[string]interface{}{"stringValue": "u2"}, attrs["user"])
+	assert.Equal(t, map[string]interface{}{"intValue": "1"}, attrs["n"])
+	assert.Equal(t, map[string]interface{}{"doubleValue": 0.5}, attrs["f"])
+	assert.Nil(t, attrs[_tid])
+	assert.Nil(t, attrs[_level])
+
+	// the 128 bits trace id of the W3C and B3 propagations.
+	buf.Reset()
+	fields = append(testFields(), KVString(_tid, "a3ce929d0e0e4736:b7ad6b7169203331:0:1:h-4bf92f3577b34da6"))
+	assert.NoError(t, p.RenderFields(buf, fields))
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &req), buf.String())
+	r = req.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", r.TraceID)
+	assert.Equal(t, "b7ad6b7169203331", r.SpanID)
+}
+
+type upperRender struct {
+	*pattern
+}
+
+func (r upperRender) RenderFields(w io.Writer, fields []D) error {
+	buf := &bytes.Buffer{}
+	r.pattern.RenderFields(buf, fields)
+	_, err := w.Write(bytes.ToUpper(buf.Bytes()))
+	return err
+}
+
+func TestRegisterEncoder(t *testing.T) {
+	RegisterEncoder("upper", func(format string) Render {
+		return upperRender{newPatternRender(format).(*pattern)}
+	})
+	buf := &bytes.Buffer{}
+	newRender("upper", false, "%M").RenderFields(buf, []D{KVString(_log, "hello")})
+	assert.Equal(t, "HELLO\n", buf.String())
+
+	_, ok := newRender("", true, "%M").(*jsonpattern)
+	assert.True(t, ok)
+	assert.Panics(t, func() { newRender("unknown", false, "%M") })
+}
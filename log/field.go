@@ -16,6 +16,11 @@ func KVString(key string, value string) D {
 	return D{Key: key, Type: core.StringType, StringVal: value}
 }
 
+// KVMessage construct the log message Field, which is rendered by %M.
+func KVMessage(value string) D {
+	return KVString(_log, value)
+}
+
 // KVInt construct Field with int value.
 func KVInt(key string, value int) D {
 	return D{Key: key, Type: core.IntTpye, Int64Val: int64(value)}
@@ -51,6 +56,11 @@ func KVDuration(key string, value time.Duration) D {
 	return D{Key: key, Type: core.DurationType, Int64Val: int64(value)}
 }
 
+// KVTime construct Field with time value.
+func KVTime(key string, value time.Time) D {
+	return D{Key: key, Type: core.TimeType, Int64Val: value.UnixNano()}
+}
+
 // KV return a log kv for logging field.
 // NOTE: use KV{type name} can avoid object alloc and get better performance. []~(￣▽￣)~*干杯
 func KV(key string, value interface{}) D {
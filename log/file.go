@@ -35,7 +35,7 @@ type FileHandler struct {
 func NewFile(opt *FileRotateOption) *FileHandler {
 
 	// new info writer
-	newWriter := func(name string, opt *FileRotateOption) *filewriter.FileWriter {
+	newWriter := func(name, link string, opt *FileRotateOption) *filewriter.FileWriter {
 		var options []filewriter.Option
 		if opt.RotateSize > 0 {
 			options = append(options, filewriter.MaxSize(opt.RotateSize))
@@ -43,6 +43,24 @@ func NewFile(opt *FileRotateOption) *FileHandler {
 		if opt.MaxLogFile > 0 {
 			options = append(options, filewriter.MaxFile(opt.MaxLogFile))
 		}
+		if opt.RotateInterval > 0 {
+			options = append(options, filewriter.RotateInterval(time.Duration(opt.RotateInterval)))
+		}
+		if opt.WriteTimeout > 0 {
+			options = append(options, filewriter.WriteTimeout(time.Duration(opt.WriteTimeout)))
+		}
+		if opt.Compress {
+			options = append(options, filewriter.Compress(true))
+		}
+		if opt.MaxAge > 0 {
+			options = append(options, filewriter.MaxAge(time.Duration(opt.MaxAge)))
+		}
+		if opt.MaxTotalSize > 0 {
+			options = append(options, filewriter.MaxTotalSize(opt.MaxTotalSize))
+		}
+		if link != "" {
+			options = append(options, filewriter.Symlink(link))
+		}
 		w, err := filewriter.New(filepath.Join(opt.Dir, name), options...)
 		if err != nil {
 			panic(err)
@@ -53,31 +71,27 @@ func NewFile(opt *FileRotateOption) *FileHandler {
 		opt.Fmt = defaultFilePattern
 	}
 	h := &FileHandler{opt: opt}
-	if opt.Json {
-		h.render = newJsonPatternRender(opt.Fmt)
-	} else {
-		h.render = newPatternRender(opt.Fmt)
-	}
+	h.render = newRender(opt.Encoder, opt.Json, opt.Fmt)
 	filename := processName()
 	if c.Family != "" {
 		filename = filename + "_" + c.Family
 	}
 	if opt.SplitLevelOutput {
 		for idx, name := range _fileNames {
-			h.fws[idx] = newWriter(filename+"."+name, opt)
+			var link string
+			if opt.Symlink != "" {
+				link = opt.Symlink + "." + name
+			}
+			h.fws[idx] = newWriter(filename+"."+name, link, opt)
 		}
 	} else {
-		h.fws[0] = newWriter(filename+".log", opt)
+		h.fws[0] = newWriter(filename+".log", opt.Symlink, opt)
 	}
 	return h
 }
 
 // Log loggint to file .
 func (h *FileHandler) Log(ctx context.Context, depth int, lv Level, args ...D) {
-	d := toMap(args...)
-	// add extra fields
-	addExtraField(ctx, d)
-	d[_time] = time.Now().Format(_timeFormat)
 	var w io.Writer
 	if h.opt.SplitLevelOutput {
 		switch lv {
@@ -91,19 +105,30 @@ func (h *FileHandler) Log(ctx context.Context, depth int, lv Level, args ...D) {
 	} else {
 		w = h.fws[0]
 	}
-	h.render.Render(w, d)
+	h.render.RenderFields(w, args)
+}
+
+// Flush writes the buffered logs to the files.
+func (h *FileHandler) Flush() {
+	for _, fw := range h.fws {
+		if fw != nil {
+			fw.Flush()
+		}
+	}
 }
 
 // Close log handler
 func (h *FileHandler) Close() error {
 	for _, fw := range h.fws {
-		// ignored error
-		fw.Close()
+		if fw != nil {
+			// ignored error
+			fw.Close()
+		}
 	}
 	return nil
 }
 
 // SetFormat set log format
 func (h *FileHandler) SetFormat(format string) {
-	h.render = newPatternRender(format)
+	h.render = newRender(h.opt.Encoder, h.opt.Json, format)
 }
@@ -0,0 +1,165 @@
+// Package glog is a drop-in replacement of github.com/golang/glog, which
+// routes the logs to the handlers of the log package with the glog severities
+// mapped to the log levels, and V to the verbose of the log package.
+package glog
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gisvr/golib/log"
+)
+
+// Level is the verbose level of V.
+type Level int32
+
+// Verbose is a boolean type that implements Infof (like Printf) etc.
+type Verbose bool
+
+// V reports whether verbosity at the call site is at least the requested level,
+// see log.V.
+func V(level Level) Verbose {
+	return Verbose(log.VDepth(0, int32(level)))
+}
+
+// Info logs to the info log if v is true.
+func (v Verbose) Info(args ...interface{}) {
+	if v {
+		log.InfoDepth(0, fmt.Sprint(args...))
+	}
+}
+
+// Infoln logs to the info log if v is true.
+func (v Verbose) Infoln(args ...interface{}) {
+	if v {
+		log.InfoDepth(0, sprintln(args...))
+	}
+}
+
+// Infof logs to the info log if v is true.
+func (v Verbose) Infof(format string, args ...interface{}) {
+	if v {
+		log.InfoDepth(0, fmt.Sprintf(format, args...))
+	}
+}
+
+// Flush is a no-op, the handlers are flushed by log.Close.
+func Flush() {}
+
+func sprintln(args ...interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
+
+// Info logs to the info log.
+func Info(args ...interface{}) {
+	log.InfoDepth(0, fmt.Sprint(args...))
+}
+
+// InfoDepth logs to the info log, the depth is the callers to skip.
+func InfoDepth(depth int, args ...interface{}) {
+	log.InfoDepth(depth, fmt.Sprint(args...))
+}
+
+// Infoln logs to the info log.
+func Infoln(args ...interface{}) {
+	log.InfoDepth(0, sprintln(args...))
+}
+
+// Infof logs to the info log.
+func Infof(format string, args ...interface{}) {
+	log.InfoDepth(0, fmt.Sprintf(format, args...))
+}
+
+// Warning logs to the warning log.
+func Warning(args ...interface{}) {
+	log.WarnDepth(0, fmt.Sprint(args...))
+}
+
+// WarningDepth logs to the warning log, the depth is the callers to skip.
+func WarningDepth(depth int, args ...interface{}) {
+	log.WarnDepth(depth, fmt.Sprint(args...))
+}
+
+// Warningln logs to the warning log.
+func Warningln(args ...interface{}) {
+	log.WarnDepth(0, sprintln(args...))
+}
+
+// Warningf logs to the warning log.
+func Warningf(format string, args ...interface{}) {
+	log.WarnDepth(0, fmt.Sprintf(format, args...))
+}
+
+// Error logs to the error log.
+func Error(args ...interface{}) {
+	log.ErrorDepth(0, fmt.Sprint(args...))
+}
+
+// ErrorDepth logs to the error log, the depth is the callers to skip.
+func ErrorDepth(depth int, args ...interface{}) {
+	log.ErrorDepth(depth, fmt.Sprint(args...))
+}
+
+// Errorln logs to the error log.
+func Errorln(args ...interface{}) {
+	log.ErrorDepth(0, sprintln(args...))
+}
+
+// Errorf logs to the error log.
+func Errorf(format string, args ...interface{}) {
+	log.ErrorDepth(0, fmt.Sprintf(format, args...))
+}
+
+func exit(code int) {
+	log.Close()
+	os.Exit(code)
+}
+
+// Fatal logs to the fatal log, then exits with 255 like glog.
+func Fatal(args ...interface{}) {
+	log.FatalDepth(0, fmt.Sprint(args...))
+	exit(255)
+}
+
+// FatalDepth logs to the fatal log, then exits with 255 like glog.
+func FatalDepth(depth int, args ...interface{}) {
+	log.FatalDepth(depth, fmt.Sprint(args...))
+	exit(255)
+}
+
+// Fatalln logs to the fatal log, then exits with 255 like glog.
+func Fatalln(args ...interface{}) {
+	log.FatalDepth(0, sprintln(args...))
+	exit(255)
+}
+
+// Fatalf logs to the fatal log, then exits with 255 like glog.
+func Fatalf(format string, args ...interface{}) {
+	log.FatalDepth(0, fmt.Sprintf(format, args...))
+	exit(255)
+}
+
+// Exit logs to the fatal log, then exits with 1 like glog.
+func Exit(args ...interface{}) {
+	log.FatalDepth(0, fmt.Sprint(args...))
+	exit(1)
+}
+
+// ExitDepth logs to the fatal log, then exits with 1 like glog.
+func ExitDepth(depth int, args ...interface{}) {
+	log.FatalDepth(depth, fmt.Sprint(args...))
+	exit(1)
+}
+
+// Exitln logs to the fatal log, then exits with 1 like glog.
+func Exitln(args ...interface{}) {
+	log.FatalDepth(0, sprintln(args...))
+	exit(1)
+}
+
+// Exitf logs to the fatal log, then exits with 1 like glog.
+func Exitf(format string, args ...interface{}) {
+	log.FatalDepth(0, fmt.Sprintf(format, args...))
+	exit(1)
+}
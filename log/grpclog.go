@@ -0,0 +1,103 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc/grpclog"
+)
+
+const _grpcLoggerPrefix = "github.com/gisvr/golib/log.(*grpcLogger)."
+
+// grpcLogger implements grpclog.LoggerV2 by the handlers, the source is the
+// caller of grpclog.
+type grpcLogger struct{}
+
+var _ grpclog.LoggerV2 = &grpcLogger{}
+
+// RedirectGRPCLog redirects the grpclog to the handlers, it is not mutex-protected
+// as grpclog.SetLoggerV2, so it should be called before any grpc functions.
+// It returns a function which restores the default logger of grpc, which
+// writes the errors to stderr.
+func RedirectGRPCLog() (restore func()) {
+	grpclog.SetLoggerV2(&grpcLogger{})
+	return func() {
+		grpclog.SetLoggerV2(grpclog.NewLoggerV2(ioutil.Discard, ioutil.Discard, os.Stderr))
+	}
+}
+
+func isGRPCLog(fn string) bool {
+	return strings.HasPrefix(fn, _grpcLoggerPrefix) ||
+		strings.HasPrefix(fn, "google.golang.org/grpc/grpclog.") ||
+		strings.HasPrefix(fn, "google.golang.org/grpc/internal/grpclog.")
+}
+
+func (l *grpcLogger) log(lv Level, msg string) {
+	depth, _ := bridgeDepth(isGRPCLog)
+	h.Log(context.Background(), depth, lv, KVString(_log, msg))
+}
+
+func (l *grpcLogger) Info(args ...interface{}) {
+	l.log(_infoLevel, fmt.Sprint(args...))
+}
+
+func (l *grpcLogger) Infoln(args ...interface{}) {
+	l.log(_infoLevel, strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+}
+
+func (l *grpcLogger) Infof(format string, args ...interface{}) {
+	l.log(_infoLevel, fmt.Sprintf(format, args...))
+}
+
+func (l *grpcLogger) Warning(args ...interface{}) {
+	l.log(_warnLevel, fmt.Sprint(args...))
+}
+
+func (l *grpcLogger) Warningln(args ...interface{}) {
+	l.log(_warnLevel, strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+}
+
+func (l *grpcLogger) Warningf(format string, args ...interface{}) {
+	l.log(_warnLevel, fmt.Sprintf(format, args...))
+}
+
+func (l *grpcLogger) Error(args ...interface{}) {
+	l.log(_errorLevel, fmt.Sprint(args...))
+}
+
+func (l *grpcLogger) Errorln(args ...interface{}) {
+	l.log(_errorLevel, strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+}
+
+func (l *grpcLogger) Errorf(format string, args ...interface{}) {
+	l.log(_errorLevel, fmt.Sprintf(format, args...))
+}
+
+// Fatal logs at the fatal level and exits as the grpclog.LoggerV2 requires.
+func (l *grpcLogger) Fatal(args ...interface{}) {
+	l.log(_fatalLevel, fmt.Sprint(args...))
+	Close()
+	os.Exit(1)
+}
+
+// Fatalln logs at the fatal level and exits as the grpclog.LoggerV2 requires.
+func (l *grpcLogger) Fatalln(args ...interface{}) {
+	l.log(_fatalLevel, strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+	Close()
+	os.Exit(1)
+}
+
+// Fatalf logs at the fatal level and exits as the grpclog.LoggerV2 requires.
+func (l *grpcLogger) Fatalf(format string, args ...interface{}) {
+	l.log(_fatalLevel, fmt.Sprintf(format, args...))
+	Close()
+	os.Exit(1)
+}
+
+// V reports whether the verbose level is at least l.
+func (l *grpcLogger) V(v int) bool {
+	return loadVerbosity().v >= int32(v)
+}
@@ -2,6 +2,7 @@ package log
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	pkgerr "github.com/pkg/errors"
@@ -67,7 +68,8 @@ const (
 // It is left up to Handlers to implement thread-safety.
 type Handler interface {
 	// Log handle log
-	// variadic D is k-v struct represent log content
+	// variadic D is k-v struct represent log content, which is reused
+	// after Log returns, so copy it if it is kept.
 	// int is depth
 	Log(context.Context, int, Level, ...D)
 
@@ -79,6 +81,12 @@ type Handler interface {
 	Close() error
 }
 
+// NewHandlers returns a Handler which masks the fields in filters, and adds the
+// source, time and level fields before calling the handlers, like the global logger.
+func NewHandlers(filters []string, handlers ...Handler) *Handlers {
+	return newHandlers(filters, handlers...)
+}
+
 func newHandlers(filters []string, handlers ...Handler) *Handlers {
 	set := make(map[string]struct{})
 	for _, k := range filters {
@@ -90,33 +98,87 @@ func newHandlers(filters []string, handlers ...Handler) *Handlers {
 // Handlers a bundle for hander with filter function.
 type Handlers struct {
 	filters  map[string]struct{}
+	redactor *Redactor
+	sampler  *sampler
 	handlers []Handler
 }
 
 // Log handlers logging.
 func (hs Handlers) Log(ctx context.Context, depth int, lv Level, d ...D) {
-	hasSource := false
+	var (
+		fn        string
+		lno       int
+		hasSource bool
+	)
 	for i := range d {
-		if _, ok := hs.filters[d[i].Key]; ok {
-			d[i].Value = "***"
-		}
 		if d[i].Key == _source {
 			hasSource = true
+			break
+		}
+	}
+	if !hasSource {
+		fn, lno = funcFileLine(3 + depth)
+		if hs.sampler != nil && !hs.sampler.allow(lv, fn, lno, d, time.Now()) {
+			return
+		}
+	}
+	fields := getFields()
+	defer putFields(fields)
+	ds := append(*fields, d...)
+	for i := range ds {
+		if _, ok := hs.filters[ds[i].Key]; ok {
+			ds[i] = KVString(ds[i].Key, _masked)
+		} else if hs.redactor != nil && !isInternalKey(ds[i].Key) {
+			ds[i] = hs.redactor.Field(ds[i])
 		}
 	}
 	if !hasSource {
-		fn, lno := funcFileLine(3 + depth)
 		errIncr(lv, fn)
-		d = append(d, KVString(_fileName, fn), KVInt(_line, lno))
+		ds = append(ds, KVString(_fileName, fn), KVInt(_line, lno))
 	}
-	d = append(d, KV(_time, time.Now()), KVInt64(_levelValue, int64(lv)), KVString(_level, lv.String()))
+	ds = append(ds, KVTime(_time, time.Now()), KVInt64(_levelValue, int64(lv)), KVString(_level, lv.String()))
 	for _, h := range hs.handlers {
-		h.Log(ctx, depth, lv, d...)
+		h.Log(ctx, depth, lv, ds...)
+	}
+	*fields = ds
+}
+
+var _fieldsPool = sync.Pool{New: func() interface{} {
+	fields := make([]D, 0, 16)
+	return &fields
+}}
+
+// getFields returns a pooled fields slice, so the fields added by Handlers
+// do not allocate for each log.
+func getFields() *[]D {
+	return _fieldsPool.Get().(*[]D)
+}
+
+func putFields(fields *[]D) {
+	for i := range *fields {
+		(*fields)[i] = D{}
+	}
+	*fields = (*fields)[:0]
+	_fieldsPool.Put(fields)
+}
+
+// levelHandler drops the logs lower than the global level.
+type levelHandler struct {
+	Handler
+}
+
+func (h levelHandler) Log(ctx context.Context, depth int, lv Level, d ...D) {
+	if lv < GetLevel() {
+		return
 	}
+	h.Handler.Log(ctx, depth+1, lv, d...)
 }
 
 // Close close resource.
 func (hs Handlers) Close() (err error) {
+	if hs.sampler != nil {
+		hs.sampler.close()
+	}
 	for _, h := range hs.handlers {
 		if e := h.Close(); e != nil {
 			err = pkgerr.WithStack(e)
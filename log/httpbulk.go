@@ -0,0 +1,189 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	xtime "github.com/gisvr/golib/time"
+)
+
+// HTTPOption is the option of the http bulk handler, which posts the logs in
+// batches as newline delimited json.
+type HTTPOption struct {
+	URL string `yaml:"url"`
+	// Header is the extra request headers, eg: Authorization.
+	Header map[string]string `yaml:"header"`
+	// BatchSize is the max logs of a request, default 100.
+	BatchSize int `yaml:"batchsize"`
+	// FlushInterval is the max time a log waits for the batch, default 1s.
+	FlushInterval xtime.Duration `yaml:"flushinterval"`
+	// Timeout is the timeout of a request, default 5s.
+	Timeout xtime.Duration `yaml:"timeout"`
+	// MaxRetry is the max retries of a batch, after the network errors, 429
+	// and 5xx responses, default 3.
+	MaxRetry int `yaml:"maxretry"`
+	// RetryBackoff is the first retry interval which is doubled after each
+	// retry, default 100ms.
+	RetryBackoff xtime.Duration `yaml:"retrybackoff"`
+	// ChanSize is the max pending logs, the logs are dropped if it is full, default 1024.
+	ChanSize int `yaml:"chansize"`
+	// Encoder is the encoder of the lines, json, logfmt or otlp, the json line
+	// with all the fields and the RFC3339 time by default.
+	Encoder string `yaml:"encoder"`
+}
+
+// HTTPHandler posts the logs in batches in a goroutine.
+type HTTPHandler struct {
+	opt    *HTTPOption
+	client *http.Client
+	encode func([]D) []byte
+
+	mu     sync.RWMutex
+	closed bool
+	ch     chan []byte
+	done   chan struct{}
+}
+
+// NewHTTP create a http bulk log handler.
+func NewHTTP(opt *HTTPOption) *HTTPHandler {
+	if opt.BatchSize <= 0 {
+		opt.BatchSize = 100
+	}
+	if opt.FlushInterval <= 0 {
+		opt.FlushInterval = xtime.Duration(time.Second)
+	}
+	if opt.Timeout <= 0 {
+		opt.Timeout = xtime.Duration(5 * time.Second)
+	}
+	if opt.MaxRetry <= 0 {
+		opt.MaxRetry = 3
+	}
+	if opt.RetryBackoff <= 0 {
+		opt.RetryBackoff = xtime.Duration(100 * time.Millisecond)
+	}
+	if opt.ChanSize <= 0 {
+		opt.ChanSize = 1024
+	}
+	h := &HTTPHandler{
+		opt:    opt,
+		client: &http.Client{Timeout: time.Duration(opt.Timeout)},
+		encode: newLineEncoder(opt.Encoder),
+		ch:     make(chan []byte, opt.ChanSize),
+		done:   make(chan struct{}),
+	}
+	go h.proc()
+	return h
+}
+
+// Log posts the log asynchronously, it is dropped if the buffer is full.
+func (h *HTTPHandler) Log(ctx context.Context, depth int, lv Level, args ...D) {
+	line := h.encode(args)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		return
+	}
+	select {
+	case h.ch <- line:
+	default:
+		_metricNetDropped.Inc("http", _dropFull)
+	}
+}
+
+func (h *HTTPHandler) proc() {
+	defer close(h.done)
+	tk := time.NewTicker(time.Duration(h.opt.FlushInterval))
+	defer tk.Stop()
+	buf := new(bytes.Buffer)
+	var n int
+	flush := func() {
+		if n > 0 {
+			h.post(buf.Bytes(), n)
+			buf.Reset()
+			n = 0
+		}
+	}
+	for {
+		select {
+		case line, ok := <-h.ch:
+			if !ok {
+				flush()
+				return
+			}
+			buf.Write(line)
+			if n++; n >= h.opt.BatchSize {
+				flush()
+			}
+		case <-tk.C:
+			flush()
+		}
+	}
+}
+
+// post posts the batch of n logs, it is retried with backoff.
+func (h *HTTPHandler) post(body []byte, n int) {
+	backoff := time.Duration(h.opt.RetryBackoff)
+	for i := 0; ; i++ {
+		retry, err := h.do(body)
+		if err == nil {
+			return
+		}
+		if !retry || i >= h.opt.MaxRetry {
+			fmt.Fprintf(os.Stderr, "log: post %d logs to %s error(%v)\n", n, h.opt.URL, err)
+			_metricNetDropped.Add(float64(n), "http", _dropSend)
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// do posts the body once, it returns whether the error is retryable.
+func (h *HTTPHandler) do(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, h.opt.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	for k, v := range h.opt.Header {
+		req.Header.Set(k, v)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("http status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("http status %d", resp.StatusCode)
+	}
+}
+
+// Close posts the pending logs.
+func (h *HTTPHandler) Close() error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil
+	}
+	h.closed = true
+	close(h.ch)
+	h.mu.Unlock()
+	<-h.done
+	return nil
+}
+
+// SetFormat is ignored, the lines are encoded by the Encoder option.
+func (h *HTTPHandler) SetFormat(string) {}
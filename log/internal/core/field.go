@@ -22,6 +22,7 @@ const (
 	Float32Type
 	Float64Type
 	DurationType
+	TimeType
 )
 
 // Field is for encoder
@@ -57,6 +58,8 @@ func (f Field) AddTo(enc ObjectEncoder) {
 		enc.AddFloat64(f.Key, math.Float64frombits(uint64(f.Int64Val)))
 	case DurationType:
 		enc.AddDuration(f.Key, time.Duration(f.Int64Val))
+	case TimeType:
+		enc.AddTime(f.Key, time.Unix(0, f.Int64Val))
 	default:
 		panic(fmt.Sprintf("unknown field type: %v", f))
 	}
@@ -301,6 +301,40 @@ func (enc *jsonEncoder) Encode(buf *Buffer, fields ...Field) error {
 	return nil
 }
 
+// AppendJSONObject appends the object to buf by a pooled encoder, it does not
+// allocate unless the object adds reflected values.
+func AppendJSONObject(cfg *EncoderConfig, buf *Buffer, obj ObjectMarshaler) error {
+	enc := getJSONEncoder()
+	enc.EncoderConfig = cfg
+	enc.buf = buf
+	buf.AppendByte('{')
+	err := obj.MarshalLogObject(enc)
+	enc.closeOpenNamespaces()
+	buf.AppendByte('}')
+	putJSONEncoder(enc)
+	return err
+}
+
+// AppendJSONString appends the quoted json string to buf.
+func AppendJSONString(buf *Buffer, s string) {
+	enc := getJSONEncoder()
+	enc.buf = buf
+	buf.AppendByte('"')
+	enc.safeAddString(s)
+	buf.AppendByte('"')
+	putJSONEncoder(enc)
+}
+
+// AppendJSONByteString appends the quoted json string of the UTF-8 bytes to buf.
+func AppendJSONByteString(buf *Buffer, s []byte) {
+	enc := getJSONEncoder()
+	enc.buf = buf
+	buf.AppendByte('"')
+	enc.safeAddByteString(s)
+	buf.AppendByte('"')
+	putJSONEncoder(enc)
+}
+
 func (enc *jsonEncoder) closeOpenNamespaces() {
 	for i := 0; i < enc.openNamespaces; i++ {
 		enc.buf.AppendByte('}')
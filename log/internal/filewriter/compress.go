@@ -0,0 +1,98 @@
+package filewriter
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	_gzSuffix  = ".gz"
+	_tmpSuffix = ".tmp"
+)
+
+// enqueue adds the rotated file to the compression queue.
+func (f *FileWriter) enqueue(fname string) {
+	f.cmu.Lock()
+	f.pending = append(f.pending, fname)
+	f.cmu.Unlock()
+	select {
+	case f.compress <- struct{}{}:
+	default:
+	}
+}
+
+// compressor compresses the queued files one by one, the queue is drained
+// before it exits.
+func (f *FileWriter) compressor() {
+	defer f.cwg.Done()
+	for range f.compress {
+		for {
+			f.cmu.Lock()
+			if len(f.pending) == 0 {
+				f.cmu.Unlock()
+				break
+			}
+			fname := f.pending[0]
+			f.pending = f.pending[1:]
+			f.cmu.Unlock()
+			if err := compressFile(filepath.Join(f.dir, fname)); err != nil {
+				f.stdlog.Printf("compress file %s error: %s", fname, err)
+			}
+		}
+	}
+}
+
+// compressFile gzips src to src.gz and removes src, the modification time
+// is kept for the age based retention.
+func compressFile(src string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// removed by the retention.
+			return nil
+		}
+		return
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return
+	}
+	dst := src + _gzSuffix
+	tmp := dst + _tmpSuffix
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp)
+		}
+	}()
+	zw := gzip.NewWriter(out)
+	if _, err = io.Copy(zw, in); err != nil {
+		out.Close()
+		return
+	}
+	if err = zw.Close(); err != nil {
+		out.Close()
+		return
+	}
+	if err = out.Close(); err != nil {
+		return
+	}
+	if err = os.Chtimes(tmp, fi.ModTime(), fi.ModTime()); err != nil {
+		return
+	}
+	if err = os.Rename(tmp, dst); err != nil {
+		return
+	}
+	if err = os.Remove(src); os.IsNotExist(err) {
+		// src is removed by the retention during the compression.
+		os.Remove(dst)
+		return nil
+	}
+	return
+}
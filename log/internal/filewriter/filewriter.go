@@ -33,12 +33,22 @@ type FileWriter struct {
 
 	closed int32
 	wg     sync.WaitGroup
+	flush  chan chan struct{}
+	exited chan struct{}
+
+	// pending are the rotated files waiting for compression.
+	cmu      sync.Mutex
+	pending  []string
+	compress chan struct{}
+	cwg      sync.WaitGroup
 }
 
 type rotateItem struct {
 	rotateTime int64
 	rotateNum  int
+	// fname is the rotated file name without the compressed suffix.
 	fname      string
+	compressed bool
 }
 
 func parseRotateItem(dir, fname, rotateFormat string) (*list.List, error) {
@@ -49,9 +59,10 @@ func parseRotateItem(dir, fname, rotateFormat string) (*list.List, error) {
 
 	// parse exists log file filename
 	parse := func(s string) (rt rotateItem, err error) {
-		// remove filename and left "." error.log.2018-09-12.001 -> 2018-09-12.001
-		rt.fname = s
-		s = strings.TrimLeft(s[len(fname):], ".")
+		// remove filename and left "." error.log.2018-09-12.001.gz -> 2018-09-12.001
+		rt.fname = strings.TrimSuffix(s, _gzSuffix)
+		rt.compressed = rt.fname != s
+		s = strings.TrimLeft(rt.fname[len(fname):], ".")
 		seqs := strings.Split(s, ".")
 		var t time.Time
 		switch len(seqs) {
@@ -65,11 +76,16 @@ func parseRotateItem(dir, fname, rotateFormat string) (*list.List, error) {
 				return
 			}
 			rt.rotateTime = t.Unix()
+		default:
+			err = fmt.Errorf("invalid rotated file name: %s", rt.fname)
 		}
 		return
 	}
 
 	var items []rotateItem
+	// the file may be both uncompressed and compressed if the compression
+	// was interrupted, the uncompressed one is complete.
+	seen := make(map[string]int)
 	for _, fi := range fis {
 		if strings.HasPrefix(fi.Name(), fname) && fi.Name() != fname {
 			rt, err := parse(fi.Name())
@@ -77,14 +93,20 @@ func parseRotateItem(dir, fname, rotateFormat string) (*list.List, error) {
 				// TODO deal with error
 				continue
 			}
+			if i, ok := seen[rt.fname]; ok {
+				items[i].compressed = false
+				continue
+			}
+			seen[rt.fname] = len(items)
 			items = append(items, rt)
 		}
 	}
+	// the oldest file is at the front.
 	sort.Slice(items, func(i, j int) bool {
 		if items[i].rotateTime == items[j].rotateTime {
-			return items[i].rotateNum > items[j].rotateNum
+			return items[i].rotateNum < items[j].rotateNum
 		}
-		return items[i].rotateTime > items[j].rotateTime
+		return items[i].rotateTime < items[j].rotateTime
 	})
 	l := list.New()
 
@@ -161,10 +183,10 @@ func New(fpath string, fns ...Option) (*FileWriter, error) {
 	lastRotateFormat := time.Now().Format(opt.RotateFormat)
 	var lastSplitNum int
 	if files.Len() > 0 {
-		rt := files.Front().Value.(rotateItem)
+		rt := files.Back().Value.(rotateItem)
 		//  check contains is mush esay than compared with timestamp
 		if strings.Contains(rt.fname, lastRotateFormat) {
-			lastSplitNum = rt.rotateNum
+			lastSplitNum = rt.rotateNum + 1
 		}
 	}
 
@@ -181,6 +203,25 @@ func New(fpath string, fns ...Option) (*FileWriter, error) {
 
 		files:   files,
 		current: current,
+		flush:   make(chan chan struct{}),
+		exited:  make(chan struct{}),
+	}
+	fw.link()
+
+	if opt.Compress {
+		// remove the temporary files of the interrupted compression.
+		tmps, _ := filepath.Glob(filepath.Join(dir, fname+".*"+_tmpSuffix))
+		for _, tmp := range tmps {
+			os.Remove(tmp)
+		}
+		fw.compress = make(chan struct{}, 1)
+		fw.cwg.Add(1)
+		go fw.compressor()
+		for e := files.Front(); e != nil; e = e.Next() {
+			if rt := e.Value.(rotateItem); !rt.compressed {
+				fw.enqueue(rt.fname)
+			}
+		}
 	}
 
 	fw.wg.Add(1)
@@ -238,6 +279,19 @@ func (f *FileWriter) daemon() {
 				aggsbuf.Write(buf.Bytes())
 				f.putBuf(buf)
 			}
+		case ack := <-f.flush:
+			for n := len(f.ch); n > 0; n-- {
+				buf := <-f.ch
+				aggsbuf.Write(buf.Bytes())
+				f.putBuf(buf)
+			}
+			if aggsbuf.Len() > 0 {
+				if err = f.write(aggsbuf.Bytes()); err != nil {
+					f.stdlog.Printf("write log error: %s", err)
+				}
+				aggsbuf.Reset()
+			}
+			close(ack)
 		case <-aggstk.C:
 			if aggsbuf.Len() > 0 {
 				if err = f.write(aggsbuf.Bytes()); err != nil {
@@ -261,14 +315,40 @@ func (f *FileWriter) daemon() {
 		}
 		break
 	}
+	close(f.exited)
 	f.wg.Done()
 }
 
-// Close close file writer
+// Flush writes the buffered data to the file, it returns after the data
+// written before it is written.
+func (f *FileWriter) Flush() {
+	if atomic.LoadInt32(&f.closed) == 1 {
+		return
+	}
+	ack := make(chan struct{})
+	select {
+	case f.flush <- ack:
+	case <-f.exited:
+		return
+	}
+	select {
+	case <-ack:
+	case <-f.exited:
+	}
+}
+
+// Close close file writer, it waits for the pending compression.
 func (f *FileWriter) Close() error {
 	atomic.StoreInt32(&f.closed, 1)
 	close(f.ch)
 	f.wg.Wait()
+	if f.current != nil {
+		f.current.fp.Close()
+	}
+	if f.compress != nil {
+		close(f.compress)
+		f.cwg.Wait()
+	}
 	return nil
 }
 
@@ -281,14 +361,11 @@ func (f *FileWriter) checkRotate(t time.Time) {
 	}
 	format := t.Format(f.opt.RotateFormat)
 
-	if f.opt.MaxFile != 0 {
-		for f.files.Len() > f.opt.MaxFile {
-			rt := f.files.Remove(f.files.Front()).(rotateItem)
-			fpath := filepath.Join(f.dir, rt.fname)
-			if err := os.Remove(fpath); err != nil {
-				f.stdlog.Printf("remove file %s error: %s", fpath, err)
-			}
-		}
+	if f.current == nil {
+		// the file failed to be created by the last rotation, retry it.
+		f.reopen()
+		f.prune(t)
+		return
 	}
 
 	if format != f.lastRotateFormat || (f.opt.MaxSize != 0 && f.current.size() > f.opt.MaxSize) {
@@ -308,6 +385,9 @@ func (f *FileWriter) checkRotate(t time.Time) {
 		}
 
 		f.files.PushBack(rotateItem{fname: fname /*rotateNum: f.lastSplitNum, rotateTime: t.Unix() unnecessary*/})
+		if f.compress != nil {
+			f.enqueue(fname)
+		}
 
 		if format != f.lastRotateFormat {
 			f.lastRotateFormat = format
@@ -317,11 +397,107 @@ func (f *FileWriter) checkRotate(t time.Time) {
 		}
 
 		// recreate current file
-		f.current, err = newWrapFile(filepath.Join(f.dir, f.fname))
-		if err != nil {
-			f.stdlog.Printf("create log file error: %s", err)
+		f.reopen()
+	}
+	f.prune(t)
+}
+
+func (f *FileWriter) reopen() {
+	var err error
+	if f.current, err = newWrapFile(filepath.Join(f.dir, f.fname)); err != nil {
+		f.stdlog.Printf("create log file error: %s", err)
+		return
+	}
+	f.link()
+}
+
+// prune removes the rotated files exceed MaxFile, MaxAge or MaxTotalSize,
+// the oldest files are removed first.
+func (f *FileWriter) prune(now time.Time) {
+	if f.opt.MaxFile != 0 {
+		for f.files.Len() > f.opt.MaxFile {
+			f.remove(f.files.Remove(f.files.Front()).(rotateItem))
 		}
 	}
+	if f.opt.MaxAge <= 0 && f.opt.MaxTotalSize <= 0 {
+		return
+	}
+	var total int64
+	if f.current != nil {
+		total = f.current.size()
+	}
+	for e := f.files.Back(); e != nil; {
+		prev := e.Prev()
+		rt := e.Value.(rotateItem)
+		fi := f.stat(rt)
+		if fi == nil {
+			// removed by others.
+			f.files.Remove(e)
+			e = prev
+			continue
+		}
+		// the total is accumulated from the newest file, so all the older
+		// files are removed once it exceeds.
+		total += fi.Size()
+		if (f.opt.MaxTotalSize > 0 && total > f.opt.MaxTotalSize) ||
+			(f.opt.MaxAge > 0 && now.Sub(fi.ModTime()) > f.opt.MaxAge) {
+			f.remove(rt)
+			f.files.Remove(e)
+		}
+		e = prev
+	}
+}
+
+// stat returns the file info of the rotated file, either uncompressed or
+// compressed, nil if neither exists.
+func (f *FileWriter) stat(rt rotateItem) os.FileInfo {
+	for _, name := range []string{rt.fname, rt.fname + _gzSuffix} {
+		if fi, err := os.Stat(filepath.Join(f.dir, name)); err == nil {
+			return fi
+		}
+	}
+	return nil
+}
+
+func (f *FileWriter) remove(rt rotateItem) {
+	for _, name := range []string{rt.fname, rt.fname + _gzSuffix} {
+		fpath := filepath.Join(f.dir, name)
+		if err := os.Remove(fpath); err != nil && !os.IsNotExist(err) {
+			f.stdlog.Printf("remove file %s error: %s", fpath, err)
+		}
+	}
+}
+
+// link points the symlink to the current file, it is replaced atomically.
+func (f *FileWriter) link() {
+	if f.opt.Symlink == "" {
+		return
+	}
+	link := f.opt.Symlink
+	if !filepath.IsAbs(link) {
+		link = filepath.Join(f.dir, link)
+	}
+	target := filepath.Join(f.dir, f.fname)
+	absLink, err1 := filepath.Abs(link)
+	absTarget, err2 := filepath.Abs(target)
+	if err1 == nil && err2 == nil {
+		if rel, err := filepath.Rel(filepath.Dir(absLink), absTarget); err == nil {
+			target = rel
+		}
+	}
+	if dst, err := os.Readlink(link); err == nil && dst == target {
+		return
+	}
+	tmp := link + _tmpSuffix
+	os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		f.stdlog.Printf("create symlink %s error: %s", link, err)
+		return
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		f.stdlog.Printf("rename symlink %s error: %s", link, err)
+		os.Remove(tmp)
+	}
 }
 
 func (f *FileWriter) write(p []byte) error {
@@ -329,6 +505,7 @@ func (f *FileWriter) write(p []byte) error {
 	if f.current == nil {
 		f.stdlog.Printf("can't write log to file, please check stderr log for detail")
 		f.stdlog.Printf("%s", p)
+		return nil
 	}
 	_, err := f.current.write(p)
 	return err
@@ -1,6 +1,7 @@
 package filewriter
 
 import (
+	"compress/gzip"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -49,7 +50,7 @@ func TestParseRotate(t *testing.T) {
 
 	assert.Equal(t, len(names), l.Len())
 
-	rt := l.Front().Value.(rotateItem)
+	rt := l.Back().Value.(rotateItem)
 
 	assert.Equal(t, 5, rt.rotateNum)
 }
@@ -219,3 +220,108 @@ func BenchmarkFileWriter(b *testing.B) {
 		}
 	}
 }
+
+func TestFlush(t *testing.T) {
+	fpath := filepath.Join(logdir, "test-flush", "info.log")
+	os.Remove(fpath)
+	fw, err := New(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte("hello\n"))
+	fw.Flush()
+	data, err := ioutil.ReadFile(fpath)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello\n", string(data))
+
+	fw.Close()
+	fw.Flush()
+}
+
+func TestCompress(t *testing.T) {
+	dir := filepath.Join(logdir, "test-compress")
+	os.MkdirAll(dir, 0755)
+	ioutil.WriteFile(filepath.Join(dir, "info.log.2018-12-01"), []byte("hello\n"), 0644)
+	touch(dir, "info.log.2018-12-02.gz.tmp")
+	fw, err := New(filepath.Join(dir, "info.log"),
+		MaxSize(1024*1024),
+		Compress(true),
+		Symlink("current"),
+		RotateInterval(time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 1024)
+	for i := 0; i < 3; i++ {
+		for i := 0; i < 1024; i++ {
+			fw.Write(data)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	fw.Close()
+
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gz int
+	for _, fi := range fis {
+		switch {
+		case fi.Name() == "info.log" || fi.Name() == "current":
+		case filepath.Ext(fi.Name()) == _gzSuffix:
+			gz++
+		default:
+			t.Errorf("unexpected file %s", fi.Name())
+		}
+	}
+	assert.True(t, gz > 1, "expect more than 1 compressed file get %d", gz)
+
+	fp, err := os.Open(filepath.Join(dir, "info.log.2018-12-01.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fp.Close()
+	zr, err := gzip.NewReader(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(zr)
+	assert.Equal(t, "hello\n", string(b))
+
+	target, err := os.Readlink(filepath.Join(dir, "current"))
+	assert.NoError(t, err)
+	assert.Equal(t, "info.log", target)
+}
+
+func TestRetention(t *testing.T) {
+	dir := filepath.Join(logdir, "test-retention")
+	old := time.Now().Add(-48 * time.Hour)
+	for _, name := range []string{"info.log.2018-12-01", "info.log.2018-12-02.gz"} {
+		touch(dir, name)
+		os.Chtimes(filepath.Join(dir, name), old, old)
+	}
+	for _, name := range []string{"info.log.2018-12-03", "info.log.2018-12-04", "info.log.2018-12-05"} {
+		ioutil.WriteFile(filepath.Join(dir, name), make([]byte, 1024), 0644)
+	}
+	fw, err := New(filepath.Join(dir, "info.log"),
+		MaxAge(24*time.Hour),
+		MaxTotalSize(2048),
+		RotateInterval(time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	fw.Close()
+
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, fi := range fis {
+		names = append(names, fi.Name())
+	}
+	assert.Equal(t, []string{"info.log", "info.log.2018-12-04", "info.log.2018-12-05"}, names)
+}
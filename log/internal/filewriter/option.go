@@ -24,9 +24,13 @@ type option struct {
 	MaxSize      int64
 	ChanSize     int
 
-	// TODO export Option
 	RotateInterval time.Duration
 	WriteTimeout   time.Duration
+
+	Compress     bool
+	MaxAge       time.Duration
+	MaxTotalSize int64
+	Symlink      string
 }
 
 // Option filewriter option
@@ -67,3 +71,49 @@ func ChanSize(n int) Option {
 		opt.ChanSize = n
 	}
 }
+
+// RotateInterval set the interval of checking rotation and retention, default 10s.
+func RotateInterval(d time.Duration) Option {
+	return func(opt *option) {
+		opt.RotateInterval = d
+	}
+}
+
+// WriteTimeout set the max time Write waits when the internal chan is full,
+// default 0 meaning the log is discarded immediately.
+func WriteTimeout(d time.Duration) Option {
+	return func(opt *option) {
+		opt.WriteTimeout = d
+	}
+}
+
+// Compress gzip the rotated files in background, the compressed file
+// is named as the rotated file with suffix ".gz".
+func Compress(b bool) Option {
+	return func(opt *option) {
+		opt.Compress = b
+	}
+}
+
+// MaxAge remove the rotated files modified before d, 0 meaning unlimit.
+func MaxAge(d time.Duration) Option {
+	return func(opt *option) {
+		opt.MaxAge = d
+	}
+}
+
+// MaxTotalSize remove the oldest rotated files when the total size of the
+// log files exceeds n, 0 meaning unlimit.
+func MaxTotalSize(n int64) Option {
+	return func(opt *option) {
+		opt.MaxTotalSize = n
+	}
+}
+
+// Symlink keep a symlink point to the file being written, a relative path
+// is relative to the log dir, e.g. "current".
+func Symlink(path string) Option {
+	return func(opt *option) {
+		opt.Symlink = path
+	}
+}
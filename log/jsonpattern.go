@@ -1,16 +1,15 @@
 package log
 
 import (
-	"fmt"
-	"github.com/gisvr/golib/log/internal/core"
 	"io"
 	"path"
-	"time"
+
+	"github.com/gisvr/golib/log/internal/core"
 )
 
 type jsonFuncMap struct {
 	pattern  string
-	function func(core.Encoder, map[string]interface{})
+	function func(core.ObjectEncoder, *renderEntry)
 }
 
 var jsonPatternMap = map[string]jsonFuncMap{
@@ -31,6 +30,10 @@ var jsonPatternMap = map[string]jsonFuncMap{
 func newJsonPatternRender(format string) Render {
 	p := &jsonpattern{
 		funcs: make([]jsonFuncMap, 0),
+		cfg: core.EncoderConfig{
+			EncodeTime:     core.EpochTimeEncoder,
+			EncodeDuration: core.SecondsDurationEncoder,
+		},
 	}
 	for i := 0; i < len(format); i++ {
 		if format[i] != '%' {
@@ -51,103 +54,112 @@ func newJsonPatternRender(format string) Render {
 
 type jsonpattern struct {
 	funcs []jsonFuncMap
+	cfg   core.EncoderConfig
 }
 
-func (p *jsonpattern) format(d map[string]interface{}) []byte {
-	enc := core.NewJSONEncoder(
-		core.EncoderConfig{
-			EncodeTime:     core.EpochTimeEncoder,
-			EncodeDuration: core.SecondsDurationEncoder,
-		},
-		core.GetPool(),
-	)
-
-	for _, f := range p.funcs {
-		f.function(enc, d)
+// MarshalLogObject encodes the entry by the pattern funcs.
+func (e *renderEntry) MarshalLogObject(enc core.ObjectEncoder) error {
+	for _, f := range e.json.funcs {
+		f.function(enc, e)
 	}
+	return nil
+}
+
+func (p *jsonpattern) format(buf *core.Buffer, fields []D) {
+	e := getRenderEntry(fields)
+	e.json = p
+	core.AppendJSONObject(&p.cfg, buf, e)
+	buf.AppendByte('\n')
+	putRenderEntry(e)
+}
+
+// RenderFields implemet Formater by the typed fields.
+func (p *jsonpattern) RenderFields(w io.Writer, fields []D) error {
 	buf := core.GetPool()
-	enc.Encode(buf)
 	defer buf.Free()
-	return buf.Bytes()
+	p.format(buf, fields)
+	_, err := w.Write(buf.Bytes())
+	return err
 }
 
 // Render implemet Formater
 func (p *jsonpattern) Render(w io.Writer, d map[string]interface{}) error {
-	buf := p.format(d)
-	_, err := w.Write(buf)
-	return err
+	return p.RenderFields(w, mapFields(d))
 }
 
 // Render implemet Formater as string
 func (p *jsonpattern) RenderString(d map[string]interface{}) string {
-	buf := p.format(d)
-	return string(buf)
+	buf := core.GetPool()
+	defer buf.Free()
+	p.format(buf, mapFields(d))
+	return buf.String()
 }
 
-func message2Codec(enc core.Encoder, md map[string]interface{}) {
-	for key, val := range md {
-		if isInternalKey(key) {
+func message2Codec(enc core.ObjectEncoder, e *renderEntry) {
+	for i, d := range e.fields {
+		if isInternalKey(d.Key) || e.overridden(i) {
 			continue
 		}
-		KV(key, val).AddTo(enc)
+		d.AddTo(enc)
 	}
 }
-func keyFactory2Codec(key string) func(core.Encoder, map[string]interface{}) {
-	return func(enc core.Encoder, md map[string]interface{}) {
-		if v, ok := md[key]; ok {
-			if s, ok := v.(string); ok {
-				KVString(key, s).AddTo(enc)
-			} else {
-				KVString(key, fmt.Sprint(v)).AddTo(enc)
-			}
-		} else {
-			KVString(key, "").AddTo(enc)
+
+func keyFactory2Codec(key string) func(core.ObjectEncoder, *renderEntry) {
+	return func(enc core.ObjectEncoder, e *renderEntry) {
+		d, ok := e.lookup(key)
+		switch {
+		case !ok:
+			enc.AddString(key, "")
+		case d.Type == core.StringType:
+			enc.AddString(key, d.StringVal)
+		default:
+			enc.AddByteString(key, e.text(d))
 		}
 	}
 }
 
-func longSource2Codec(enc core.Encoder, d map[string]interface{}) {
-	fn, ok := d[_fileName]
-	if ok {
-		KVString(_fileName, fn.(string)).AddTo(enc)
+func source2Codec(enc core.ObjectEncoder, e *renderEntry, short bool) {
+	if fn, ok := e.lookup(_fileName); ok {
+		if short {
+			enc.AddString(_fileName, path.Base(fn.StringVal))
+		} else {
+			enc.AddString(_fileName, fn.StringVal)
+		}
 	} else {
-		KVString(_fileName, "unknown").AddTo(enc)
+		enc.AddString(_fileName, "unknown")
 	}
-	lno, ok := d[_line]
-	if ok {
-		KVInt64(_line, lno.(int64)).AddTo(enc)
+	if lno, ok := e.lookup(_line); ok {
+		lno.AddTo(enc)
 	} else {
-		KVInt64(_line, 0).AddTo(enc)
+		enc.AddInt64(_line, 0)
 	}
 }
 
-func shortSource2Codec(enc core.Encoder, d map[string]interface{}) {
-	fn, ok := d[_fileName]
-	if ok {
-		KVString(_fileName, path.Base(fn.(string))).AddTo(enc)
-	} else {
-		KVString(_fileName, "unknown").AddTo(enc)
-	}
-	lno, ok := d[_line]
-	if ok {
-		KVInt64(_line, lno.(int64)).AddTo(enc)
-	} else {
-		KVInt64(_line, 0).AddTo(enc)
-	}
+func longSource2Codec(enc core.ObjectEncoder, e *renderEntry) {
+	source2Codec(enc, e, false)
+}
+
+func shortSource2Codec(enc core.ObjectEncoder, e *renderEntry) {
+	source2Codec(enc, e, true)
+}
+
+func time2Codec(enc core.ObjectEncoder, e *renderEntry, layout string) {
+	e.scratch = e.time().AppendFormat(e.scratch[:0], layout)
+	enc.AddByteString(_time, e.scratch)
 }
 
-func longTime2Codec(enc core.Encoder, d map[string]interface{}) {
-	KVString(_time, time.Now().Format(_timeFormat)).AddTo(enc)
+func longTime2Codec(enc core.ObjectEncoder, e *renderEntry) {
+	time2Codec(enc, e, _timeFormat)
 }
 
-func shortTime2Codec(enc core.Encoder, d map[string]interface{}) {
-	KVString(_time, time.Now().Format("2006-01-02T15:04:05")).AddTo(enc)
+func shortTime2Codec(enc core.ObjectEncoder, e *renderEntry) {
+	time2Codec(enc, e, "2006-01-02T15:04:05")
 }
 
-func longDate2Codec(enc core.Encoder, d map[string]interface{}) {
-	KVString(_time, time.Now().Format("2006-01-02")).AddTo(enc)
+func longDate2Codec(enc core.ObjectEncoder, e *renderEntry) {
+	time2Codec(enc, e, "2006-01-02")
 }
 
-func shortDate2Codec(enc core.Encoder, d map[string]interface{}) {
-	KVString(_time, time.Now().Format("01/02")).AddTo(enc)
+func shortDate2Codec(enc core.ObjectEncoder, e *renderEntry) {
+	time2Codec(enc, e, "01/02")
 }
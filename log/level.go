@@ -1,5 +1,12 @@
 package log
 
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
 // Level of severity.
 type Level int
 
@@ -15,6 +22,15 @@ const (
 	_fatalLevel
 )
 
+// exported log level, eg: for calling a Handler directly.
+const (
+	DebugLevel = _debugLevel
+	InfoLevel  = _infoLevel
+	WarnLevel  = _warnLevel
+	ErrorLevel = _errorLevel
+	FatalLevel = _fatalLevel
+)
+
 var levelNames = [...]string{
 	_debugLevel: "DEBUG",
 	_infoLevel:  "INFO",
@@ -27,3 +43,29 @@ var levelNames = [...]string{
 func (l Level) String() string {
 	return levelNames[l]
 }
+
+// _minLevel is the min level of the global logger.
+var _minLevel = int32(_debugLevel)
+
+// ParseLevel parses the level name, eg: debug, INFO, warn.
+func ParseLevel(name string) (Level, error) {
+	for lv, n := range levelNames {
+		if strings.EqualFold(n, name) {
+			return Level(lv), nil
+		}
+	}
+	if strings.EqualFold(name, "warning") {
+		return _warnLevel, nil
+	}
+	return 0, errors.Errorf("log: invalid level: %s", name)
+}
+
+// SetLevel sets the min level of the global logger, the lower logs are dropped.
+func SetLevel(lv Level) {
+	atomic.StoreInt32(&_minLevel, int32(lv))
+}
+
+// GetLevel returns the min level of the global logger.
+func GetLevel() Level {
+	return Level(atomic.LoadInt32(&_minLevel))
+}
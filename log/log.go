@@ -9,6 +9,8 @@ import (
 	"io"
 	"os"
 	"strconv"
+
+	xtime "github.com/gisvr/golib/time"
 )
 
 type JsonRotateOption struct {
@@ -29,17 +31,38 @@ type FileRotateOption struct {
 	MaxLogFile int `yaml:"maxlogfile"`
 	// RotateSize
 	RotateSize int64 `yaml:"rotatesize"`
+	// RotateInterval is the interval of checking rotation and retention, default 10s.
+	RotateInterval xtime.Duration `yaml:"rotateinterval"`
+	// WriteTimeout is the max time a log waits when the buffer is full, the log
+	// is discarded immediately by default.
+	WriteTimeout xtime.Duration `yaml:"writetimeout"`
+	// Compress gzip the rotated files in background.
+	Compress bool `yaml:"compress"`
+	// MaxAge removes the rotated files older than it, 0 meaning unlimit.
+	MaxAge xtime.Duration `yaml:"maxage"`
+	// MaxTotalSize removes the oldest rotated files when the total size of a
+	// log exceeds it, 0 meaning unlimit.
+	MaxTotalSize int64 `yaml:"maxtotalsize"`
+	// Symlink is the symlink name in Dir point to the file being written, e.g.
+	// current, the level file name is appended if SplitLevelOutput, e.g. current.info.log.
+	Symlink string `yaml:"symlink"`
 	//Format
-	Fmt              string   `yaml:"fmt"`
-	Json             bool     `yaml:"json"`
+	Fmt  string `yaml:"fmt"`
+	Json bool   `yaml:"json"`
+	// Encoder is pattern(default), json, logfmt, otlp or a registered one,
+	// see RegisterEncoder, it takes precedence over Json.
+	Encoder          string   `yaml:"encoder"`
 	SplitLevelOutput bool     `yaml:"mergeout"` //各level分别输出
 	FilterIn         []string `yaml:"filterin"` //filterin和filterout只能二选一
 	FilterOut        []string `yaml:"filterout"`
 }
 
 type StdoutOption struct {
-	Fmt       string   `yaml:"fmt"`
-	Json      bool     `yaml:"json"`
+	Fmt  string `yaml:"fmt"`
+	Json bool   `yaml:"json"`
+	// Encoder is pattern(default), json, logfmt, otlp or a registered one,
+	// see RegisterEncoder, it takes precedence over Json.
+	Encoder   string   `yaml:"encoder"`
 	FilterIn  []string `yaml:"filterin"` //filterin和filterout只能二选一
 	FilterOut []string `yaml:"filterout"`
 }
@@ -53,6 +76,14 @@ type Config struct {
 	Stdout *StdoutOption       `yaml:"stdout"`
 	File   *FileRotateOption   `yaml:"file"`
 	Files  []*FileRotateOption `yaml:"files"`
+	// Syslog sends the logs to syslog in RFC5424.
+	Syslog *SyslogOption `yaml:"syslog"`
+	// Net sends the logs as json lines over tcp or udp.
+	Net *NetOption `yaml:"net"`
+	// HTTP posts the logs as json lines in batches.
+	HTTP *HTTPOption `yaml:"http"`
+	// Level is the min level of the logs, eg: debug(default), info, warn, error.
+	Level string `yaml:"level"`
 	// V Enable V-leveled logging at the specified level.
 	V int32 `yaml:"v"`
 	// Module=""
@@ -64,14 +95,31 @@ type Config struct {
 	//   "dao*" = 2
 	// sets the V level to 2 in all Go files whose names begin "dao".
 	Module map[string]int32 `yaml:"module"`
+	// Async handles the logs in a goroutine by the handlers, they are
+	// flushed by Close and the fatal logs.
+	Async *AsyncOption `yaml:"async"`
 	// Filter tell log handler which field are sensitive message, use * instead.
 	Filter []string `yaml:"filter"`
+	// Redact masks the sensitive values by the key and value patterns, see RedactRule.
+	Redact []*RedactRule `yaml:"redact"`
+	// Sample limits the logs of the same level, source and message template
+	// on the hot paths, see SampleOption.
+	Sample *SampleOption `yaml:"sample"`
+	// RedirectStdLog routes the standard logger of the stdlib log to the
+	// handlers at the info level, it is restored by Close. It is opt-in, the
+	// default config of Init(nil) does not redirect.
+	RedirectStdLog bool `yaml:"redirectStdLog"`
+	// RedirectGRPCLog routes the grpclog to the handlers, it is restored by Close.
+	RedirectGRPCLog bool `yaml:"redirectGRPCLog"`
 }
 
 // Render render log output
 type Render interface {
 	Render(io.Writer, map[string]interface{}) error
 	RenderString(map[string]interface{}) string
+	// RenderFields renders the typed fields directly without converting them
+	// to a map, the extra app and host fields are rendered on demand.
+	RenderFields(io.Writer, []D) error
 }
 
 var (
@@ -85,7 +133,7 @@ func init() {
 		Family: env.AppID,
 		Host:   host,
 	}
-	h = newHandlers([]string{}, _defaultStdout)
+	h = levelHandler{newHandlers([]string{}, _defaultStdout)}
 
 	addFlag(flag.CommandLine)
 }
@@ -155,11 +203,46 @@ func Init(conf *Config) {
 	if conf.File != nil && conf.File.Dir != "" {
 		hs = append(hs, NewFile(conf.File))
 	}
+	if conf.Syslog != nil {
+		hs = append(hs, NewSyslog(conf.Syslog))
+	}
+	if conf.Net != nil && conf.Net.Addr != "" {
+		hs = append(hs, NewNet(conf.Net))
+	}
+	if conf.HTTP != nil && conf.HTTP.URL != "" {
+		hs = append(hs, NewHTTP(conf.HTTP))
+	}
 	if len(hs) == 0 {
 		hs = append(hs, _defaultStdout)
 	}
-	h = newHandlers(conf.Filter, hs...)
+	if conf.Async != nil {
+		for i := range hs {
+			hs[i] = NewAsync(hs[i], conf.Async)
+		}
+	}
+	if conf.Level != "" {
+		if lv, err := ParseLevel(conf.Level); err == nil {
+			SetLevel(lv)
+		}
+	}
+	handlers := newHandlers(conf.Filter, hs...)
+	var redactor *Redactor
+	if len(conf.Redact) > 0 {
+		var err error
+		if redactor, err = NewRedactor(conf.Redact); err != nil {
+			panic(err)
+		}
+		handlers.redactor = redactor
+	}
+	_redactor.Store(redactor)
+	if conf.Sample != nil {
+		handlers.sampler = newSampler(conf.Sample, handlers.emitter())
+	}
+	h = levelHandler{handlers}
 	c = conf
+	SetV(conf.V)
+	SetModule(conf.Module)
+	redirect(conf)
 }
 
 func formatLog(args ...interface{}) string {
@@ -322,8 +405,9 @@ func SetFormat(format string) {
 
 // Close close resource.
 func Close() (err error) {
+	unredirect()
 	err = h.Close()
-	h = _defaultStdout
+	h = levelHandler{_defaultStdout}
 	return
 }
 
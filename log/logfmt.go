@@ -0,0 +1,182 @@
+package log
+
+import (
+	"io"
+	"path"
+
+	"github.com/gisvr/golib/log/internal/core"
+)
+
+const _hexDigits = "0123456789abcdef"
+
+var logfmtPatternMap = map[string]func(*core.Buffer, *renderEntry){
+	"T": longTime2Logfmt,
+	"t": shortTime2Logfmt,
+	"L": keyFactory2Logfmt(_level),
+	"f": keyFactory2Logfmt(_bizName),
+	"P": keyFactory2Logfmt(_svcName),
+	"p": keyFactory2Logfmt(_pid),
+	"u": keyFactory2Logfmt(_userId),
+	"h": keyFactory2Logfmt(_hostName),
+	"S": longSource2Logfmt,
+	"s": shortSource2Logfmt,
+	"M": message2Logfmt,
+}
+
+// newLogfmtRender returns a render of logfmt, the fields are selected by the
+// % pattern of format like the json pattern, eg:
+//
+//	time=2006-01-02T15:04:05.000 level=INFO filename=d.go linenum=23 user=u1 log="order paid"
+func newLogfmtRender(format string) Render {
+	p := &logfmt{}
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 >= len(format) {
+			continue
+		}
+		f, ok := logfmtPatternMap[string(format[i+1])]
+		if !ok {
+			continue
+		}
+		p.funcs = append(p.funcs, f)
+		i++
+	}
+	return p
+}
+
+type logfmt struct {
+	funcs []func(*core.Buffer, *renderEntry)
+}
+
+func (p *logfmt) format(buf *core.Buffer, fields []D) {
+	e := getRenderEntry(fields)
+	for _, f := range p.funcs {
+		f(buf, e)
+	}
+	putRenderEntry(e)
+}
+
+// RenderFields implemet Formater by the typed fields.
+func (p *logfmt) RenderFields(w io.Writer, fields []D) error {
+	buf := core.GetPool()
+	defer buf.Free()
+	p.format(buf, fields)
+	buf.AppendByte('\n')
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Render implemet Formater
+func (p *logfmt) Render(w io.Writer, d map[string]interface{}) error {
+	return p.RenderFields(w, mapFields(d))
+}
+
+// Render implemet Formater as string
+func (p *logfmt) RenderString(d map[string]interface{}) string {
+	buf := core.GetPool()
+	defer buf.Free()
+	p.format(buf, mapFields(d))
+	return buf.String()
+}
+
+// appendLogfmt appends the key=value pair, the key is sanitized and the value
+// is quoted if necessary.
+func appendLogfmt(buf *core.Buffer, key string, val []byte) {
+	if buf.Len() > 0 {
+		buf.AppendByte(' ')
+	}
+	for i := 0; i < len(key); i++ {
+		if c := key[i]; c <= ' ' || c == '=' || c == '"' || c == 0x7f {
+			buf.AppendByte('_')
+		} else {
+			buf.AppendByte(c)
+		}
+	}
+	buf.AppendByte('=')
+	quote := len(val) == 0
+	for _, c := range val {
+		if c <= ' ' || c == '=' || c == '"' || c == 0x7f {
+			quote = true
+			break
+		}
+	}
+	if !quote {
+		buf.Write(val)
+		return
+	}
+	buf.AppendByte('"')
+	for _, c := range val {
+		switch {
+		case c == '"' || c == '\\':
+			buf.AppendByte('\\')
+			buf.AppendByte(c)
+		case c == '\n':
+			buf.AppendString(`\n`)
+		case c == '\r':
+			buf.AppendString(`\r`)
+		case c == '\t':
+			buf.AppendString(`\t`)
+		case c < ' ' || c == 0x7f:
+			buf.AppendString(`\u00`)
+			buf.AppendByte(_hexDigits[c>>4])
+			buf.AppendByte(_hexDigits[c&0xf])
+		default:
+			buf.AppendByte(c)
+		}
+	}
+	buf.AppendByte('"')
+}
+
+func keyFactory2Logfmt(key string) func(*core.Buffer, *renderEntry) {
+	return func(buf *core.Buffer, e *renderEntry) {
+		var val []byte
+		if d, ok := e.lookup(key); ok {
+			val = e.text(d)
+		}
+		appendLogfmt(buf, key, val)
+	}
+}
+
+func source2Logfmt(buf *core.Buffer, e *renderEntry, short bool) {
+	fn, ok := e.lookup(_fileName)
+	switch {
+	case !ok:
+		e.scratch = append(e.scratch[:0], "unknown"...)
+	case short:
+		e.scratch = append(e.scratch[:0], path.Base(fn.StringVal)...)
+	default:
+		e.scratch = append(e.scratch[:0], fn.StringVal...)
+	}
+	appendLogfmt(buf, _fileName, e.scratch)
+	lno, ok := e.lookup(_line)
+	if !ok {
+		lno = KVInt(_line, 0)
+	}
+	appendLogfmt(buf, _line, e.text(lno))
+}
+
+func longSource2Logfmt(buf *core.Buffer, e *renderEntry) {
+	source2Logfmt(buf, e, false)
+}
+
+func shortSource2Logfmt(buf *core.Buffer, e *renderEntry) {
+	source2Logfmt(buf, e, true)
+}
+
+func longTime2Logfmt(buf *core.Buffer, e *renderEntry) {
+	e.scratch = e.time().AppendFormat(e.scratch[:0], _timeFormat)
+	appendLogfmt(buf, _time, e.scratch)
+}
+
+func shortTime2Logfmt(buf *core.Buffer, e *renderEntry) {
+	e.scratch = e.time().AppendFormat(e.scratch[:0], "2006-01-02T15:04:05")
+	appendLogfmt(buf, _time, e.scratch)
+}
+
+func message2Logfmt(buf *core.Buffer, e *renderEntry) {
+	for i, d := range e.fields {
+		if isInternalKey(d.Key) || e.overridden(i) {
+			continue
+		}
+		appendLogfmt(buf, d.Key, e.text(d))
+	}
+}
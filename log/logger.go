@@ -0,0 +1,206 @@
+package log
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gisvr/golib/net/metadata"
+	"github.com/gisvr/golib/net/trace"
+)
+
+const _mid = "mid"
+
+type loggerKey struct{}
+
+// Logger logs with the bound fields and context by the global handlers,
+// it is immutable and safe for concurrent use, eg:
+//
+//	l := log.FromContext(ctx).With(log.KVString("order", id))
+//	l.Infof("order paid")
+//	ctx = log.NewContext(ctx, l)
+type Logger struct {
+	ctx    context.Context
+	fields []D
+	depth  int
+}
+
+var _root = &Logger{ctx: context.Background()}
+
+// NewLogger returns a logger with the fields.
+func NewLogger(fields ...D) *Logger {
+	return _root.With(fields...)
+}
+
+// With returns a child logger with the fields added, the existing fields of
+// the same keys are replaced.
+func (l *Logger) With(fields ...D) *Logger {
+	nl := *l
+	nl.fields = make([]D, len(l.fields), len(l.fields)+len(fields))
+	copy(nl.fields, l.fields)
+	for _, f := range fields {
+		nl.fields = setField(nl.fields, f)
+	}
+	return &nl
+}
+
+// WithContext returns a child logger bound to the ctx, the trace id, caller,
+// color and mid in the ctx are added as fields.
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	fields := make([]D, 0, 4)
+	if t, ok := trace.FromContext(ctx); ok {
+		if id := t.TraceID(); id != "" {
+			fields = append(fields, KVString(_tid, id))
+		}
+	}
+	for _, key := range []string{metadata.Caller, metadata.Color} {
+		if v := metadata.String(ctx, key); v != "" {
+			fields = append(fields, KVString(key, v))
+		}
+	}
+	switch mid := metadata.Value(ctx, metadata.Mid).(type) {
+	case int64:
+		if mid != 0 {
+			fields = append(fields, KVInt64(_mid, mid))
+		}
+	case string:
+		if mid != "" {
+			fields = append(fields, KVString(_mid, mid))
+		}
+	}
+	nl := l.With(fields...)
+	nl.ctx = ctx
+	return nl
+}
+
+// WithDepth returns a child logger which skips depth more callers when
+// reporting the source, it is used by the wrappers of Logger.
+func (l *Logger) WithDepth(depth int) *Logger {
+	nl := *l
+	nl.depth += depth
+	return &nl
+}
+
+// Fields returns the bound fields.
+func (l *Logger) Fields() []D {
+	return append([]D(nil), l.fields...)
+}
+
+func setField(fields []D, f D) []D {
+	for i := range fields {
+		if fields[i].Key == f.Key {
+			fields[i] = f
+			return fields
+		}
+	}
+	return append(fields, f)
+}
+
+// NewContext returns a context which carries the logger.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger carried by the ctx and bound to it, or a
+// logger WithContext(ctx) if there is none.
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*Logger); ok {
+		nl := *l
+		nl.ctx = ctx
+		return &nl
+	}
+	return _root.WithContext(ctx)
+}
+
+func (l *Logger) args(args []D) []D {
+	if len(l.fields) == 0 {
+		return args
+	}
+	ds := make([]D, 0, len(l.fields)+len(args))
+	ds = append(ds, l.fields...)
+	return append(ds, args...)
+}
+
+// Logv logs the fields at the level.
+func (l *Logger) Logv(lv Level, args ...D) {
+	h.Log(l.ctx, l.depth, lv, l.args(args)...)
+}
+
+// Debug logs a message at the debug log level.
+func (l *Logger) Debug(args ...interface{}) {
+	h.Log(l.ctx, l.depth, _debugLevel, l.args([]D{KVString(_log, formatLog(args...))})...)
+}
+
+// Info logs a message at the info log level.
+func (l *Logger) Info(args ...interface{}) {
+	h.Log(l.ctx, l.depth, _infoLevel, l.args([]D{KVString(_log, formatLog(args...))})...)
+}
+
+// Warn logs a message at the warning log level.
+func (l *Logger) Warn(args ...interface{}) {
+	h.Log(l.ctx, l.depth, _warnLevel, l.args([]D{KVString(_log, formatLog(args...))})...)
+}
+
+// Error logs a message at the error log level.
+func (l *Logger) Error(args ...interface{}) {
+	h.Log(l.ctx, l.depth, _errorLevel, l.args([]D{KVString(_log, formatLog(args...))})...)
+}
+
+// Fatal logs a message at the fatal log level.
+func (l *Logger) Fatal(args ...interface{}) {
+	h.Log(l.ctx, l.depth, _fatalLevel, l.args([]D{KVString(_log, formatLog(args...))})...)
+}
+
+// Debugf logs a message at the debug log level.
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	h.Log(l.ctx, l.depth, _debugLevel, l.args([]D{KVString(_log, fmt.Sprintf(format, args...))})...)
+}
+
+// Infof logs a message at the info log level.
+func (l *Logger) Infof(format string, args ...interface{}) {
+	h.Log(l.ctx, l.depth, _infoLevel, l.args([]D{KVString(_log, fmt.Sprintf(format, args...))})...)
+}
+
+// Warnf logs a message at the warning log level.
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	h.Log(l.ctx, l.depth, _warnLevel, l.args([]D{KVString(_log, fmt.Sprintf(format, args...))})...)
+}
+
+// Errorf logs a message at the error log level.
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	h.Log(l.ctx, l.depth, _errorLevel, l.args([]D{KVString(_log, fmt.Sprintf(format, args...))})...)
+}
+
+// Fatalf logs a message at the fatal log level.
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	h.Log(l.ctx, l.depth, _fatalLevel, l.args([]D{KVString(_log, fmt.Sprintf(format, args...))})...)
+}
+
+// Infow logs a message with the key-value pairs at the info log level.
+func (l *Logger) Infow(args ...interface{}) {
+	h.Log(l.ctx, l.depth, _infoLevel, l.args(logw(args))...)
+}
+
+// Warnw logs a message with the key-value pairs at the warning log level.
+func (l *Logger) Warnw(args ...interface{}) {
+	h.Log(l.ctx, l.depth, _warnLevel, l.args(logw(args))...)
+}
+
+// Errorw logs a message with the key-value pairs at the error log level.
+func (l *Logger) Errorw(args ...interface{}) {
+	h.Log(l.ctx, l.depth, _errorLevel, l.args(logw(args))...)
+}
+
+// Infov logs the fields at the info log level.
+func (l *Logger) Infov(args ...D) {
+	h.Log(l.ctx, l.depth, _infoLevel, l.args(args)...)
+}
+
+// Warnv logs the fields at the warning log level.
+func (l *Logger) Warnv(args ...D) {
+	h.Log(l.ctx, l.depth, _warnLevel, l.args(args)...)
+}
+
+// Errorv logs the fields at the error log level.
+func (l *Logger) Errorv(args ...D) {
+	h.Log(l.ctx, l.depth, _errorLevel, l.args(args)...)
+}
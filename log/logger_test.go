@@ -0,0 +1,76 @@
+package log
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/gisvr/golib/net/metadata"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fieldsHandler records the fields of the logs.
+type fieldsHandler struct {
+	mu   sync.Mutex
+	logs []map[string]interface{}
+}
+
+func (h *fieldsHandler) Log(ctx context.Context, depth int, lv Level, args ...D) {
+	h.mu.Lock()
+	h.logs = append(h.logs, toMap(args...))
+	h.mu.Unlock()
+}
+
+func (h *fieldsHandler) SetFormat(string) {}
+
+func (h *fieldsHandler) Close() error { return nil }
+
+func withFieldsHandler(t *testing.T) *fieldsHandler {
+	fh := &fieldsHandler{}
+	old := h
+	h = levelHandler{newHandlers(nil, fh)}
+	t.Cleanup(func() { h = old })
+	return fh
+}
+
+func TestLoggerWith(t *testing.T) {
+	fh := withFieldsHandler(t)
+	l := NewLogger(KVString("a", "1"))
+	child := l.With(KVString("a", "2"), KVInt("b", 3))
+	l.Info("parent")
+	child.Infof("child %d", 1)
+
+	assert.Len(t, fh.logs, 2)
+	assert.Equal(t, "parent", fh.logs[0][_log])
+	assert.Equal(t, "1", fh.logs[0]["a"])
+	assert.Nil(t, fh.logs[0]["b"])
+	assert.Equal(t, "child 1", fh.logs[1][_log])
+	assert.Equal(t, "2", fh.logs[1]["a"])
+	assert.Equal(t, int64(3), fh.logs[1]["b"])
+	assert.Equal(t, "logger_test.go", filepath.Base(fh.logs[1][_fileName].(string)))
+}
+
+func TestLoggerContext(t *testing.T) {
+	fh := withFieldsHandler(t)
+	ctx := metadata.NewContext(context.Background(), metadata.MD{
+		metadata.Caller: "caller",
+		metadata.Color:  "red",
+		metadata.Mid:    int64(42),
+	})
+	FromContext(ctx).Warnw("k", "v")
+
+	ctx = NewContext(ctx, FromContext(ctx).With(KVString("order", "o1")))
+	FromContext(ctx).Errorv(KVString(_log, "paid"))
+
+	assert.Len(t, fh.logs, 2)
+	for _, d := range fh.logs {
+		assert.Equal(t, "caller", d[metadata.Caller])
+		assert.Equal(t, "red", d[metadata.Color])
+		assert.Equal(t, int64(42), d[_mid])
+	}
+	assert.Equal(t, "v", fh.logs[0]["k"])
+	assert.Equal(t, "o1", fh.logs[1]["order"])
+	assert.Equal(t, "paid", fh.logs[1][_log])
+}
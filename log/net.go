@@ -0,0 +1,320 @@
+package log
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gisvr/golib/log/internal/core"
+	"github.com/gisvr/golib/stat/metric"
+	xtime "github.com/gisvr/golib/time"
+)
+
+// reasons of the dropped logs.
+const (
+	_dropFull = "full"
+	_dropDown = "down"
+	_dropSend = "send"
+)
+
+var _metricNetDropped = metric.NewCounterVec(&metric.CounterVecOpts{
+	Namespace: "log",
+	Subsystem: "net",
+	Name:      "dropped_total",
+	Help:      "log network handler dropped logs.",
+	Labels:    []string{"handler", "reason"},
+})
+
+// encodeJSON encodes the log as a json line with the app and host fields,
+// the time is formatted in RFC3339 with nanoseconds.
+func encodeJSON(args []D) []byte {
+	ebuf := core.GetPool()
+	defer ebuf.Free()
+	enc := core.NewJSONEncoder(core.EncoderConfig{
+		EncodeTime:     core.EpochTimeEncoder,
+		EncodeDuration: core.SecondsDurationEncoder,
+	}, ebuf)
+	for _, d := range args {
+		if d.Key == _time && d.Type == core.TimeType {
+			enc.AddString(_time, time.Unix(0, d.Int64Val).Format(time.RFC3339Nano))
+			continue
+		}
+		d.AddTo(enc)
+	}
+	enc.AddString(_svcName, _processName)
+	enc.AddString(_bizName, c.Family)
+	enc.AddString(_hostName, c.Host)
+	enc.AddInt(_pid, _processID)
+	buf := core.GetPool()
+	defer buf.Free()
+	enc.Encode(buf)
+	return append([]byte(nil), buf.Bytes()...)
+}
+
+// _netPattern selects all the fields for the json and logfmt encoders of the
+// network handlers.
+const _netPattern = "%T %L %S %P %f %h %p %M"
+
+// newLineEncoder returns the line encoder of the network handlers, the json
+// line of encodeJSON is used if encoder is empty.
+func newLineEncoder(encoder string) func([]D) []byte {
+	if encoder == "" {
+		return encodeJSON
+	}
+	render := newRender(encoder, false, _netPattern)
+	return func(args []D) []byte {
+		buf := core.GetPool()
+		defer buf.Free()
+		render.RenderFields(buf, args)
+		return append([]byte(nil), buf.Bytes()...)
+	}
+}
+
+// NetOption is the option of the network handler, which sends the logs as
+// newline delimited json.
+type NetOption struct {
+	// Network is tcp(default) or udp.
+	Network string `yaml:"network"`
+	Addr    string `yaml:"addr"`
+	// DialTimeout default 1s, the dial is retried with backoff up to 30s.
+	DialTimeout xtime.Duration `yaml:"dialtimeout"`
+	// WriteTimeout default 1s.
+	WriteTimeout xtime.Duration `yaml:"writetimeout"`
+	// ChanSize is the max pending logs, the logs are dropped if it is full, default 1024.
+	ChanSize int `yaml:"chansize"`
+	// Encoder is the encoder of the lines, json, logfmt or otlp, the json line
+	// with all the fields and the RFC3339 time by default.
+	Encoder string `yaml:"encoder"`
+	// SpoolDir is the dir of the spool file, the logs are appended to it when
+	// the peer is down, and resent after reconnected, even after restart.
+	// The logs are dropped when the peer is down if it is empty.
+	SpoolDir string `yaml:"spooldir"`
+	// SpoolSize is the max size of the spool file, default 64MB.
+	SpoolSize int64 `yaml:"spoolsize"`
+}
+
+// NetHandler sends the logs as json lines over tcp or udp in a goroutine.
+type NetHandler struct {
+	opt    *NetOption
+	encode func([]D) []byte
+
+	mu     sync.RWMutex
+	closed bool
+	ch     chan []byte
+	done   chan struct{}
+
+	// the following are only accessed by the goroutine.
+	conn      net.Conn
+	retry     time.Time
+	backoff   time.Duration
+	spool     *os.File
+	spoolSize int64
+}
+
+// NewNet create a network log handler, it connects lazily.
+func NewNet(opt *NetOption) *NetHandler {
+	if opt.Network == "" {
+		opt.Network = "tcp"
+	}
+	if opt.DialTimeout <= 0 {
+		opt.DialTimeout = xtime.Duration(time.Second)
+	}
+	if opt.WriteTimeout <= 0 {
+		opt.WriteTimeout = xtime.Duration(time.Second)
+	}
+	if opt.ChanSize <= 0 {
+		opt.ChanSize = 1024
+	}
+	if opt.SpoolSize <= 0 {
+		opt.SpoolSize = 64 << 20
+	}
+	h := &NetHandler{
+		opt:    opt,
+		encode: newLineEncoder(opt.Encoder),
+		ch:     make(chan []byte, opt.ChanSize),
+		done:   make(chan struct{}),
+	}
+	if opt.SpoolDir != "" {
+		h.openSpool()
+	}
+	go h.proc()
+	return h
+}
+
+// openSpool opens the spool file, the logs spooled before restart are kept.
+func (h *NetHandler) openSpool() {
+	if err := os.MkdirAll(h.opt.SpoolDir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "log: create spool dir %s error(%v)\n", h.opt.SpoolDir, err)
+		return
+	}
+	name := strings.NewReplacer(":", "_", "/", "_").Replace(processName() + "-" + h.opt.Addr + ".spool")
+	fp, err := os.OpenFile(filepath.Join(h.opt.SpoolDir, name), os.O_CREATE|os.O_APPEND|os.O_RDWR, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "log: open spool file error(%v)\n", err)
+		return
+	}
+	fi, err := fp.Stat()
+	if err != nil {
+		fp.Close()
+		fmt.Fprintf(os.Stderr, "log: stat spool file error(%v)\n", err)
+		return
+	}
+	h.spool, h.spoolSize = fp, fi.Size()
+}
+
+// Log sends the log asynchronously, it is dropped if the buffer is full.
+func (h *NetHandler) Log(ctx context.Context, depth int, lv Level, args ...D) {
+	line := h.encode(args)
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		return
+	}
+	select {
+	case h.ch <- line:
+	default:
+		_metricNetDropped.Inc("net", _dropFull)
+	}
+}
+
+func (h *NetHandler) proc() {
+	defer close(h.done)
+	tk := time.NewTicker(time.Second)
+	defer tk.Stop()
+	for {
+		select {
+		case line, ok := <-h.ch:
+			if !ok {
+				h.closeConn()
+				if h.spool != nil {
+					h.spool.Close()
+				}
+				return
+			}
+			h.send(line)
+		case <-tk.C:
+			// resend the spooled logs when it is idle.
+			if h.spoolSize > 0 && (h.conn != nil || h.dial()) {
+				h.replay()
+			}
+		}
+	}
+}
+
+func (h *NetHandler) send(line []byte) {
+	if h.conn == nil && !h.dial() {
+		h.toSpool(line)
+		return
+	}
+	// keep the order, the spooled logs are sent first.
+	if h.spoolSize > 0 && !h.replay() {
+		h.toSpool(line)
+		return
+	}
+	if err := h.write(line); err != nil {
+		fmt.Fprintf(os.Stderr, "log: write %s error(%v)\n", h.opt.Addr, err)
+		h.closeConn()
+		h.toSpool(line)
+	}
+}
+
+// dial connects the peer, it is retried with backoff after failed.
+func (h *NetHandler) dial() bool {
+	if time.Now().Before(h.retry) {
+		return false
+	}
+	conn, err := net.DialTimeout(h.opt.Network, h.opt.Addr, time.Duration(h.opt.DialTimeout))
+	if err != nil {
+		if h.backoff == 0 {
+			fmt.Fprintf(os.Stderr, "log: dial %s error(%v)\n", h.opt.Addr, err)
+			h.backoff = 100 * time.Millisecond
+		} else if h.backoff *= 2; h.backoff > 30*time.Second {
+			h.backoff = 30 * time.Second
+		}
+		h.retry = time.Now().Add(h.backoff)
+		return false
+	}
+	h.conn, h.backoff = conn, 0
+	return true
+}
+
+func (h *NetHandler) closeConn() {
+	if h.conn != nil {
+		h.conn.Close()
+		h.conn = nil
+	}
+}
+
+func (h *NetHandler) write(b []byte) error {
+	h.conn.SetWriteDeadline(time.Now().Add(time.Duration(h.opt.WriteTimeout)))
+	_, err := h.conn.Write(b)
+	return err
+}
+
+// toSpool appends the log to the spool file, it is dropped if there is no
+// spool file or it is full.
+func (h *NetHandler) toSpool(line []byte) {
+	if h.spool == nil || h.spoolSize+int64(len(line)) > h.opt.SpoolSize {
+		_metricNetDropped.Inc("net", _dropDown)
+		return
+	}
+	n, err := h.spool.Write(line)
+	h.spoolSize += int64(n)
+	if err != nil {
+		_metricNetDropped.Inc("net", _dropDown)
+	}
+}
+
+// replay resends the spooled logs line by line and truncates the spool file,
+// the logs may be sent twice if it is interrupted.
+func (h *NetHandler) replay() bool {
+	if _, err := h.spool.Seek(0, io.SeekStart); err != nil {
+		return false
+	}
+	r := bufio.NewReader(h.spool)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			if werr := h.write(line); werr != nil {
+				fmt.Fprintf(os.Stderr, "log: resend spooled logs to %s error(%v)\n", h.opt.Addr, werr)
+				h.closeConn()
+				return false
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return false
+		}
+	}
+	if err := h.spool.Truncate(0); err != nil {
+		return false
+	}
+	h.spoolSize = 0
+	return true
+}
+
+// Close sends or spools the pending logs and closes the connection.
+func (h *NetHandler) Close() error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil
+	}
+	h.closed = true
+	close(h.ch)
+	h.mu.Unlock()
+	<-h.done
+	return nil
+}
+
+// SetFormat is ignored, the lines are encoded by the Encoder option.
+func (h *NetHandler) SetFormat(string) {}
@@ -0,0 +1,194 @@
+package log
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyslogUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+	h := NewSyslog(&SyslogOption{Addr: pc.LocalAddr().String(), Tag: "my app", Fmt: "%M"})
+	defer h.Close()
+	h.Log(context.Background(), 0, WarnLevel, KVMessage("hello"))
+
+	buf := make([]byte, 1024)
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(buf[:n])
+	// user facility 1*8 + warning severity 4.
+	assert.True(t, strings.HasPrefix(msg, "<12>1 "), msg)
+	assert.Contains(t, msg, " my_app ")
+	assert.True(t, strings.HasSuffix(msg, " - - hello"), msg)
+}
+
+func TestSyslogTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	h := NewSyslog(&SyslogOption{Network: "tcp", Addr: l.Addr().String(), Facility: "local0", Fmt: "%M"})
+	defer h.Close()
+	go h.Log(context.Background(), 0, ErrorLevel, KVMessage("hello"))
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	r := bufio.NewReader(conn)
+	size, err := r.ReadString(' ')
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(size))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, n)
+	_, err = io.ReadFull(r, buf)
+	assert.NoError(t, err)
+	// local0 facility 16*8 + error severity 3.
+	assert.True(t, strings.HasPrefix(string(buf), "<131>1 "), string(buf))
+	assert.True(t, strings.HasSuffix(string(buf), "hello"), string(buf))
+}
+
+func TestSyslogBackoff(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	h := NewSyslog(&SyslogOption{Network: "tcp", Addr: addr, Fmt: "%M"})
+	defer h.Close()
+
+	// the second log is dropped without dialing during the backoff.
+	h.Log(context.Background(), 0, ErrorLevel, KVMessage("hello"))
+	retry := h.retry
+	h.Log(context.Background(), 0, ErrorLevel, KVMessage("hello"))
+	assert.Equal(t, retry, h.retry)
+	assert.Equal(t, 100*time.Millisecond, h.backoff)
+
+	// the render is replaced while logging.
+	done := make(chan struct{})
+	go func() {
+		h.SetFormat("%L %M")
+		close(done)
+	}()
+	h.Log(context.Background(), 0, ErrorLevel, KVMessage("hello"))
+	<-done
+}
+
+func TestNetSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log-spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	// the peer is down.
+	l.Close()
+
+	h := NewNet(&NetOption{Addr: addr, SpoolDir: dir})
+	for _, msg := range []string{"a", "b"} {
+		h.Log(context.Background(), 0, InfoLevel, KVMessage(msg))
+	}
+	time.Sleep(50 * time.Millisecond)
+	if l, err = net.Listen("tcp", addr); err != nil {
+		t.Skipf("listen %s again error(%v)", addr, err)
+	}
+	defer l.Close()
+	h.Log(context.Background(), 0, InfoLevel, KVMessage("c"))
+
+	var msgs []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+		s := bufio.NewScanner(conn)
+		for len(msgs) < 3 && s.Scan() {
+			m := make(map[string]interface{})
+			if err := json.Unmarshal(s.Bytes(), &m); err != nil {
+				t.Error(err)
+				return
+			}
+			msgs = append(msgs, m[_log].(string))
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+	h.Close()
+	assert.Equal(t, []string{"a", "b", "c"}, msgs)
+}
+
+func TestHTTPBulk(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls int
+		lines []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if calls++; calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		body, _ := ioutil.ReadAll(r.Body)
+		lines = append(lines, strings.Split(strings.TrimSpace(string(body)), "\n")...)
+	}))
+	defer srv.Close()
+
+	h := NewHTTP(&HTTPOption{
+		URL:          srv.URL,
+		Header:       map[string]string{"Authorization": "Bearer token"},
+		BatchSize:    2,
+		RetryBackoff: 1,
+	})
+	for _, msg := range []string{"a", "b", "c"} {
+		h.Log(context.Background(), 0, InfoLevel, KVMessage(msg))
+	}
+	h.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 3, calls)
+	assert.Len(t, lines, 3)
+}
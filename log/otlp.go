@@ -0,0 +1,220 @@
+package log
+
+import (
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gisvr/golib/log/internal/core"
+)
+
+const _otlpScope = "github.com/gisvr/golib/log"
+
+// _otlpSeverities are the severity numbers of the OpenTelemetry log data model.
+var _otlpSeverities = [...]int64{
+	_debugLevel: 5,
+	_infoLevel:  9,
+	_warnLevel:  13,
+	_errorLevel: 17,
+	_fatalLevel: 21,
+}
+
+// newOTLPRender returns a render of the OTLP/JSON logs, each log is an
+// ExportLogsServiceRequest in a line which the collector can ingest directly:
+//
+//	{"resourceLogs":[{"resource":{"attributes":[...]},"scopeLogs":[{"scope":{"name":"..."},
+//	"logRecords":[{"timeUnixNano":"...","severityNumber":9,"severityText":"INFO",
+//	"body":{"stringValue":"..."},"attributes":[...],"traceId":"...","spanId":"..."}]}]}]}
+//
+// The app and host are the resource attributes, the source is the code.filepath
+// and code.lineno attributes, and format is ignored.
+func newOTLPRender(string) Render {
+	return &otlp{}
+}
+
+type otlp struct{}
+
+func (p *otlp) format(buf *core.Buffer, fields []D) {
+	e := getRenderEntry(fields)
+	defer putRenderEntry(e)
+
+	service := c.Family
+	if service == "" {
+		service = _processName
+	}
+	buf.AppendString(`{"resourceLogs":[{"resource":{"attributes":[`)
+	appendOTLPAttr(buf, e, KVString("service.name", service))
+	appendOTLPAttr(buf, e, KVString("process.executable.name", _processName))
+	appendOTLPAttr(buf, e, KVInt("process.pid", _processID))
+	if c.Host != "" {
+		appendOTLPAttr(buf, e, KVString("host.name", c.Host))
+	}
+	buf.AppendString(`]},"scopeLogs":[{"scope":{"name":"` + _otlpScope + `"},"logRecords":[{`)
+
+	now := time.Now().UnixNano()
+	ts := now
+	if d, ok := e.lookup(_time); ok && d.Type == core.TimeType {
+		ts = d.Int64Val
+	}
+	buf.AppendString(`"timeUnixNano":"`)
+	buf.AppendInt(ts)
+	buf.AppendString(`","observedTimeUnixNano":"`)
+	buf.AppendInt(now)
+	buf.AppendByte('"')
+
+	lv := _infoLevel
+	if d, ok := e.lookup(_levelValue); ok {
+		lv = Level(d.Int64Val)
+	}
+	if int(lv) >= 0 && int(lv) < len(_otlpSeverities) {
+		buf.AppendString(`,"severityNumber":`)
+		buf.AppendInt(_otlpSeverities[lv])
+	}
+	buf.AppendString(`,"severityText":`)
+	core.AppendJSONString(buf, lv.String())
+
+	if d, ok := e.lookup(_log); ok {
+		buf.AppendString(`,"body":`)
+		appendOTLPValue(buf, e, d)
+	}
+
+	buf.AppendString(`,"attributes":[`)
+	if fn, ok := e.lookup(_fileName); ok {
+		appendOTLPAttr(buf, e, KVString("code.filepath", fn.StringVal))
+	}
+	if lno, ok := e.lookup(_line); ok {
+		lno.Key = "code.lineno"
+		appendOTLPAttr(buf, e, lno)
+	}
+	for i, d := range e.fields {
+		if d.Key == _log || d.Key == _tid || isInternalKey(d.Key) || e.overridden(i) {
+			continue
+		}
+		appendOTLPAttr(buf, e, d)
+	}
+	buf.AppendByte(']')
+
+	// the trace id is {TraceID}:{SpanID}:{ParentID}:{flags}:[extend...] in
+	// base16, the high 64 bits of a 128 bits trace id is the h- extension.
+	if d, ok := e.lookup(_tid); ok && d.Type == core.StringType && d.StringVal != "" {
+		parts := strings.Split(d.StringVal, ":")
+		var high string
+		for i := 4; i < len(parts); i++ {
+			if strings.HasPrefix(parts[i], "h-") {
+				high = parts[i][2:]
+			}
+		}
+		buf.AppendString(`,"traceId":"`)
+		appendHexID(buf, high, 16)
+		appendHexID(buf, parts[0], 16)
+		buf.AppendByte('"')
+		if len(parts) > 1 {
+			buf.AppendString(`,"spanId":"`)
+			appendHexID(buf, parts[1], 16)
+			buf.AppendByte('"')
+		}
+	}
+	buf.AppendString("}]}]}]}")
+}
+
+// appendHexID appends the hex id left padded with 0 to n digits.
+func appendHexID(buf *core.Buffer, id string, n int) {
+	for i := len(id); i < n; i++ {
+		buf.AppendByte('0')
+	}
+	if len(id) > n {
+		id = id[len(id)-n:]
+	}
+	buf.AppendString(strings.ToLower(id))
+}
+
+// appendOTLPAttr appends the KeyValue of the field, a comma is added if it is
+// not the first attribute.
+func appendOTLPAttr(buf *core.Buffer, e *renderEntry, d D) {
+	if b := buf.Bytes(); b[len(b)-1] != '[' {
+		buf.AppendByte(',')
+	}
+	buf.AppendString(`{"key":`)
+	core.AppendJSONString(buf, d.Key)
+	buf.AppendString(`,"value":`)
+	appendOTLPValue(buf, e, d)
+	buf.AppendByte('}')
+}
+
+// appendOTLPValue appends the AnyValue of the field, the 64 bits integers are
+// strings as the proto3 json mapping.
+func appendOTLPValue(buf *core.Buffer, e *renderEntry, d D) {
+	switch d.Type {
+	case core.StringType:
+		buf.AppendString(`{"stringValue":`)
+		core.AppendJSONString(buf, d.StringVal)
+	case core.IntTpye, core.Int64Type, core.UintType, core.Uint64Type:
+		buf.AppendString(`{"intValue":"`)
+		buf.Write(e.text(d))
+		buf.AppendByte('"')
+	case core.Float32Type:
+		appendOTLPDouble(buf, float64(math.Float32frombits(uint32(d.Int64Val))))
+	case core.Float64Type:
+		appendOTLPDouble(buf, math.Float64frombits(uint64(d.Int64Val)))
+	case core.DurationType:
+		appendOTLPDouble(buf, time.Duration(d.Int64Val).Seconds())
+	default:
+		switch v := d.Value.(type) {
+		case bool:
+			buf.AppendString(`{"boolValue":`)
+			buf.AppendBool(v)
+		case int, int64, int32, uint, uint64, uint32:
+			buf.AppendString(`{"intValue":"`)
+			buf.Write(e.text(d))
+			buf.AppendByte('"')
+		case float64:
+			appendOTLPDouble(buf, v)
+		case time.Duration:
+			appendOTLPDouble(buf, v.Seconds())
+		default:
+			buf.AppendString(`{"stringValue":`)
+			core.AppendJSONByteString(buf, e.text(d))
+		}
+	}
+	buf.AppendByte('}')
+}
+
+// appendOTLPDouble appends the doubleValue without the closing brace, NaN and
+// Infinity are strings as the proto3 json mapping.
+func appendOTLPDouble(buf *core.Buffer, f float64) {
+	buf.AppendString(`{"doubleValue":`)
+	switch {
+	case math.IsNaN(f):
+		buf.AppendString(`"NaN"`)
+	case math.IsInf(f, 1):
+		buf.AppendString(`"Infinity"`)
+	case math.IsInf(f, -1):
+		buf.AppendString(`"-Infinity"`)
+	default:
+		buf.AppendFloat(f, 64)
+	}
+}
+
+// RenderFields implemet Formater by the typed fields.
+func (p *otlp) RenderFields(w io.Writer, fields []D) error {
+	buf := core.GetPool()
+	defer buf.Free()
+	p.format(buf, fields)
+	buf.AppendByte('\n')
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Render implemet Formater
+func (p *otlp) Render(w io.Writer, d map[string]interface{}) error {
+	return p.RenderFields(w, mapFields(d))
+}
+
+// Render implemet Formater as string
+func (p *otlp) RenderString(d map[string]interface{}) string {
+	buf := core.GetPool()
+	defer buf.Free()
+	p.format(buf, mapFields(d))
+	return buf.String()
+}
@@ -1,17 +1,13 @@
 package log
 
 import (
-	"bytes"
-	"fmt"
 	"io"
 	"path"
-	"strconv"
-	"strings"
-	"sync"
-	"time"
+
+	"github.com/gisvr/golib/log/internal/core"
 )
 
-var patternMap = map[string]func(map[string]interface{}) string{
+var patternMap = map[string]func(*core.Buffer, *renderEntry){
 	"T": longTime,
 	"t": shortTime,
 	"L": keyFactory(_level),
@@ -27,9 +23,7 @@ var patternMap = map[string]func(map[string]interface{}) string{
 
 // newPatternRender new pattern render
 func newPatternRender(format string) Render {
-	p := &pattern{
-		bufPool: sync.Pool{New: func() interface{} { return &bytes.Buffer{} }},
-	}
+	p := &pattern{}
 	b := make([]byte, 0, len(format))
 	for i := 0; i < len(format); i++ {
 		if format[i] != '%' {
@@ -59,95 +53,96 @@ func newPatternRender(format string) Render {
 }
 
 type pattern struct {
-	funcs   []func(map[string]interface{}) string
-	bufPool sync.Pool
+	funcs []func(*core.Buffer, *renderEntry)
 }
 
-// Render implemet Formater
-func (p *pattern) Render(w io.Writer, d map[string]interface{}) error {
-	buf := p.bufPool.Get().(*bytes.Buffer)
-	defer func() {
-		buf.Reset()
-		p.bufPool.Put(buf)
-	}()
+func (p *pattern) format(buf *core.Buffer, fields []D) {
+	e := getRenderEntry(fields)
 	for _, f := range p.funcs {
-		buf.WriteString(f(d))
+		f(buf, e)
 	}
-	buf.WriteString("\n")
-	_, err := buf.WriteTo(w)
+	putRenderEntry(e)
+}
+
+// RenderFields implemet Formater by the typed fields.
+func (p *pattern) RenderFields(w io.Writer, fields []D) error {
+	buf := core.GetPool()
+	defer buf.Free()
+	p.format(buf, fields)
+	buf.AppendByte('\n')
+	_, err := w.Write(buf.Bytes())
 	return err
 }
 
+// Render implemet Formater
+func (p *pattern) Render(w io.Writer, d map[string]interface{}) error {
+	return p.RenderFields(w, mapFields(d))
+}
+
 // Render implemet Formater as string
 func (p *pattern) RenderString(d map[string]interface{}) string {
-	// TODO strings.Builder
-	buf := p.bufPool.Get().(*bytes.Buffer)
-	defer func() {
-		buf.Reset()
-		p.bufPool.Put(buf)
-	}()
-	for _, f := range p.funcs {
-		buf.WriteString(f(d))
-	}
-
+	buf := core.GetPool()
+	defer buf.Free()
+	p.format(buf, mapFields(d))
 	return buf.String()
 }
 
-func textFactory(text string) func(map[string]interface{}) string {
-	return func(map[string]interface{}) string {
-		return text
+func textFactory(text string) func(*core.Buffer, *renderEntry) {
+	return func(buf *core.Buffer, _ *renderEntry) {
+		buf.AppendString(text)
 	}
 }
-func keyFactory(key string) func(map[string]interface{}) string {
-	return func(d map[string]interface{}) string {
-		if v, ok := d[key]; ok {
-			if s, ok := v.(string); ok {
-				return s
-			}
-			return fmt.Sprint(v)
+
+func keyFactory(key string) func(*core.Buffer, *renderEntry) {
+	return func(buf *core.Buffer, e *renderEntry) {
+		if d, ok := e.lookup(key); ok {
+			buf.Write(e.text(d))
 		}
-		return ""
 	}
 }
 
-func longSource(d map[string]interface{}) string {
-	lno, ok := d[_line]
+func source(buf *core.Buffer, e *renderEntry, short bool) {
+	lno, ok := e.lookup(_line)
 	if !ok {
-		return "unknown:0"
+		buf.AppendString("unknown:0")
+		return
 	}
-	fn, ok := d[_fileName]
+	fn, ok := e.lookup(_fileName)
 	if !ok {
-		return "unknown:0"
+		buf.AppendString("unknown:0")
+		return
+	}
+	if short {
+		buf.AppendString(path.Base(fn.StringVal))
+	} else {
+		buf.AppendString(fn.StringVal)
 	}
-	return fn.(string) + ":" + strconv.FormatInt(lno.(int64), 10)
+	buf.AppendByte(':')
+	buf.Write(e.text(lno))
 }
 
-func shortSource(d map[string]interface{}) string {
-	lno, ok := d[_line]
-	if !ok {
-		return "unknown:0"
-	}
-	fn, ok := d[_fileName]
-	if !ok {
-		return "unknown:0"
-	}
-	return path.Base(fn.(string)) + ":" + strconv.FormatInt(lno.(int64), 10)
+func longSource(buf *core.Buffer, e *renderEntry) {
+	source(buf, e, false)
+}
+
+func shortSource(buf *core.Buffer, e *renderEntry) {
+	source(buf, e, true)
 }
 
-func longTime(map[string]interface{}) string {
-	return time.Now().Format(_timeFormat)
+func longTime(buf *core.Buffer, e *renderEntry) {
+	buf.AppendTime(e.time(), _timeFormat)
 }
 
-func shortTime(map[string]interface{}) string {
-	return time.Now().Format("2006-01-02T15:04:05")
+func shortTime(buf *core.Buffer, e *renderEntry) {
+	buf.AppendTime(e.time(), "2006-01-02T15:04:05")
 }
 
-func longDate(map[string]interface{}) string {
-	return time.Now().Format("2006-01-02")
+func longDate(buf *core.Buffer, e *renderEntry) {
+	buf.AppendTime(e.time(), "2006-01-02")
 }
 
-func shortDate(map[string]interface{}) string {
-	return time.Now().Format("01/02")
+func shortDate(buf *core.Buffer, e *renderEntry) {
+	buf.AppendTime(e.time(), "01/02")
 }
 
 func isInternalKey(k string) bool {
@@ -163,19 +158,26 @@ func isInternalKey(k string) bool {
 	return false
 }
 
-func message(d map[string]interface{}) string {
-	var m string
-	var s []string
-	for k, v := range d {
-		if k == _log {
-			m = fmt.Sprint(v)
+// message renders the fields as key=value in order, followed by the log message.
+func message(buf *core.Buffer, e *renderEntry) {
+	var msg D
+	for i, d := range e.fields {
+		if e.overridden(i) {
 			continue
 		}
-		if isInternalKey(k) {
+		if d.Key == _log {
+			msg = d
 			continue
 		}
-		s = append(s, fmt.Sprintf("%s=%v", k, v))
+		if isInternalKey(d.Key) {
+			continue
+		}
+		buf.AppendString(d.Key)
+		buf.AppendByte('=')
+		buf.Write(e.text(d))
+		buf.AppendByte(' ')
+	}
+	if msg.Key != "" {
+		buf.Write(e.text(msg))
 	}
-	s = append(s, m)
-	return strings.Join(s, " ")
 }
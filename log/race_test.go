@@ -0,0 +1,6 @@
+// +build race
+
+package log
+
+// raceEnabled skips the allocation tests, the race detector allocates.
+const raceEnabled = true
@@ -0,0 +1,322 @@
+package log
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gisvr/golib/log/internal/core"
+
+	"github.com/pkg/errors"
+)
+
+// mask strategies of the redaction.
+const (
+	// MaskFull replaces the value with ***.
+	MaskFull = "full"
+	// MaskLast4 keeps the last 4 characters, eg: ***1234.
+	MaskLast4 = "last4"
+	// MaskHash replaces the value with its sha256 prefix, so the same values
+	// are still correlated, eg: sha256:9f86d081884c7d65.
+	MaskHash = "hash"
+)
+
+const _masked = "***"
+
+// _detectors are the builtin value detectors.
+var _detectors = map[string]*regexp.Regexp{
+	"phone": regexp.MustCompile(`(?:\+?\d{1,3}[- ]?)?(?:\b1[3-9]\d{9}\b|\(?\b\d{3}\)?[- ]?\d{3}[- ]\d{4}\b)`),
+	"email": regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	"card":  regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+	"token": regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]+=*|\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+|\b(?:sk|pk|ghp|xox[abp])[-_][A-Za-z0-9_-]{16,}`),
+}
+
+// RedactRule is a redaction rule, the values of the fields whose keys match
+// Key are masked if Value is empty, otherwise the parts of the string values
+// matched by Value are masked. eg:
+//
+//	[[log.redact]]
+//		key = "*password*"
+//	[[log.redact]]
+//		value = "phone"
+//		mask = "last4"
+//	[[log.redact]]
+//		key = "/^x-.*-token$/"
+//		mask = "hash"
+type RedactRule struct {
+	// Key is a case insensitive glob of the field keys, or a regexp wrapped
+	// by "/", all keys are matched if it is empty.
+	Key string `yaml:"key"`
+	// Value is a detector of phone, email, card and token, or a regexp
+	// wrapped by "/".
+	Value string `yaml:"value"`
+	// Mask is full(default), last4 or hash.
+	Mask string `yaml:"mask"`
+}
+
+type redactRule struct {
+	glob  string
+	key   *regexp.Regexp
+	value *regexp.Regexp
+	luhn  bool
+	mask  func(string) string
+}
+
+// Redactor masks the sensitive values of the log fields, including the ones
+// in the nested maps and structs.
+type Redactor struct {
+	rules []*redactRule
+}
+
+// NewRedactor returns a redactor of the rules.
+func NewRedactor(rules []*RedactRule) (*Redactor, error) {
+	r := &Redactor{}
+	for _, rule := range rules {
+		if rule.Key == "" && rule.Value == "" {
+			return nil, errors.New("log: redact rule requires key or value")
+		}
+		rr := &redactRule{}
+		if isRegexp(rule.Key) {
+			re, err := regexp.Compile(rule.Key[1 : len(rule.Key)-1])
+			if err != nil {
+				return nil, errors.Wrapf(err, "log: invalid redact key: %s", rule.Key)
+			}
+			rr.key = re
+		} else if rule.Key != "" {
+			if _, err := path.Match(rule.Key, ""); err != nil {
+				return nil, errors.Wrapf(err, "log: invalid redact key: %s", rule.Key)
+			}
+			rr.glob = strings.ToLower(rule.Key)
+		}
+		if isRegexp(rule.Value) {
+			re, err := regexp.Compile(rule.Value[1 : len(rule.Value)-1])
+			if err != nil {
+				return nil, errors.Wrapf(err, "log: invalid redact value: %s", rule.Value)
+			}
+			rr.value = re
+		} else if rule.Value != "" {
+			re, ok := _detectors[rule.Value]
+			if !ok {
+				return nil, errors.Errorf("log: unknown redact detector: %s", rule.Value)
+			}
+			rr.value, rr.luhn = re, rule.Value == "card"
+		}
+		switch rule.Mask {
+		case "", MaskFull:
+			rr.mask = maskFull
+		case MaskLast4:
+			rr.mask = maskLast4
+		case MaskHash:
+			rr.mask = maskHash
+		default:
+			return nil, errors.Errorf("log: unknown redact mask: %s", rule.Mask)
+		}
+		r.rules = append(r.rules, rr)
+	}
+	return r, nil
+}
+
+func isRegexp(s string) bool {
+	return len(s) >= 2 && s[0] == '/' && s[len(s)-1] == '/'
+}
+
+func maskFull(string) string {
+	return _masked
+}
+
+func maskLast4(s string) string {
+	r := []rune(s)
+	if len(r) <= 4 {
+		return _masked
+	}
+	return _masked + string(r[len(r)-4:])
+}
+
+func maskHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+// luhn reports whether the digits in s pass the luhn check of card numbers.
+func luhn(s string) bool {
+	var sum, n int
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n > 0 && sum%10 == 0
+}
+
+func (rr *redactRule) matchKey(key string) bool {
+	switch {
+	case rr.key != nil:
+		return rr.key.MatchString(key)
+	case rr.glob != "":
+		ok, _ := path.Match(rr.glob, strings.ToLower(key))
+		return ok
+	}
+	return true
+}
+
+// keyRule returns the rule which masks the whole value of the key.
+func (r *Redactor) keyRule(key string) *redactRule {
+	for _, rr := range r.rules {
+		if rr.value == nil && rr.matchKey(key) {
+			return rr
+		}
+	}
+	return nil
+}
+
+// text masks the parts of s matched by the value rules of the key.
+func (r *Redactor) text(key, s string) string {
+	for _, rr := range r.rules {
+		if rr.value == nil || !rr.matchKey(key) {
+			continue
+		}
+		s = rr.value.ReplaceAllStringFunc(s, func(m string) string {
+			if rr.luhn && !luhn(m) {
+				return m
+			}
+			return rr.mask(m)
+		})
+	}
+	return s
+}
+
+// Text masks the sensitive parts of a text by the value rules, eg: a body dump.
+func (r *Redactor) Text(s string) string {
+	return r.text("", s)
+}
+
+// Field returns the redacted field.
+func (r *Redactor) Field(d D) D {
+	if rr := r.keyRule(d.Key); rr != nil {
+		return KVString(d.Key, rr.mask(fieldString(d)))
+	}
+	switch d.Type {
+	case core.StringType:
+		d.StringVal = r.text(d.Key, d.StringVal)
+	case core.UnknownType:
+		d.Value = r.value(d.Key, d.Value)
+	}
+	return d
+}
+
+// Value returns the redacted value, the maps, structs and slices are
+// converted to the json objects and arrays.
+func (r *Redactor) Value(v interface{}) interface{} {
+	return r.value("", v)
+}
+
+func (r *Redactor) value(key string, v interface{}) interface{} {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return r.text(key, val)
+	case error:
+		return r.text(key, val.Error())
+	case time.Time, time.Duration, []byte:
+		return v
+	}
+	switch reflect.Indirect(reflect.ValueOf(v)).Kind() {
+	case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
+	default:
+		if s, ok := v.(fmt.Stringer); ok {
+			return r.text(key, s.String())
+		}
+		return v
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	// the numbers are kept as json.Number, or the large int64 lose precision as float64.
+	var obj interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err = dec.Decode(&obj); err != nil {
+		return v
+	}
+	return r.walk(key, obj)
+}
+
+// walk redacts the json value in place.
+func (r *Redactor) walk(key string, v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, e := range val {
+			if rr := r.keyRule(k); rr != nil {
+				val[k] = rr.mask(fmt.Sprint(e))
+				continue
+			}
+			val[k] = r.walk(k, e)
+		}
+	case []interface{}:
+		for i, e := range val {
+			val[i] = r.walk(key, e)
+		}
+	case string:
+		return r.text(key, val)
+	}
+	return v
+}
+
+func fieldString(d D) string {
+	switch d.Type {
+	case core.StringType:
+		return d.StringVal
+	case core.UintType, core.Uint64Type:
+		return fmt.Sprint(uint64(d.Int64Val))
+	case core.IntTpye, core.Int64Type:
+		return fmt.Sprint(d.Int64Val)
+	case core.Float32Type:
+		return fmt.Sprint(math.Float32frombits(uint32(d.Int64Val)))
+	case core.Float64Type:
+		return fmt.Sprint(math.Float64frombits(uint64(d.Int64Val)))
+	case core.DurationType:
+		return time.Duration(d.Int64Val).String()
+	case core.TimeType:
+		return time.Unix(0, d.Int64Val).String()
+	}
+	return fmt.Sprint(d.Value)
+}
+
+var _redactor atomic.Value
+
+// RedactValue redacts the value by the rules of Config.Redact, it returns v
+// if there is no rule, eg: for the request body dumps.
+func RedactValue(v interface{}) interface{} {
+	if r, ok := _redactor.Load().(*Redactor); ok && r != nil {
+		return r.Value(v)
+	}
+	return v
+}
+
+// RedactText redacts the text by the rules of Config.Redact.
+func RedactText(s string) string {
+	if r, ok := _redactor.Load().(*Redactor); ok && r != nil {
+		return r.Text(s)
+	}
+	return s
+}
@@ -0,0 +1,72 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor(t *testing.T) {
+	r, err := NewRedactor([]*RedactRule{
+		{Key: "*password*"},
+		{Key: "/^x-.*-token$/", Mask: MaskHash},
+		{Value: "phone", Mask: MaskLast4},
+		{Value: "email"},
+		{Value: "card", Mask: MaskLast4},
+		{Value: "token"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, KVString("DB_Password", "***"), r.Field(KVString("DB_Password", "secret")))
+	assert.Equal(t, KVString("user_password", "***"), r.Field(KVInt("user_password", 123456)))
+	assert.Equal(t, maskHash("abc"), r.Field(KVString("x-auth-token", "abc")).StringVal)
+	assert.Equal(t, "call ***5678 or mail ***", r.Field(KVString(_log, "call 13912345678 or mail a.b@example.com")).StringVal)
+	assert.Equal(t, "card ***1111 order 1234567890123", r.Text("card 4111 1111 1111 1111 order 1234567890123"))
+	assert.Equal(t, "Authorization: ***", r.Text("Authorization: Bearer abc.def"))
+	assert.Equal(t, KVInt("count", 13912345678), r.Field(KVInt("count", 13912345678)))
+
+	type user struct {
+		Name     string            `json:"name"`
+		Password string            `json:"password"`
+		Phones   []string          `json:"phones"`
+		Extra    map[string]string `json:"extra"`
+	}
+	v := r.Value(&user{
+		Name:     "bob",
+		Password: "secret",
+		Phones:   []string{"13912345678"},
+		Extra:    map[string]string{"old_password": "x", "email": "bob@example.com"},
+	})
+	assert.Equal(t, map[string]interface{}{
+		"name":     "bob",
+		"password": "***",
+		"phones":   []interface{}{"***5678"},
+		"extra":    map[string]interface{}{"old_password": "***", "email": "***"},
+	}, v)
+
+	// the int64 beyond the float64 precision is kept.
+	v = r.Value(map[string]interface{}{"id": int64(1<<62 + 1), "password": 1})
+	assert.Equal(t, map[string]interface{}{"id": json.Number("4611686018427387905"), "password": "***"}, v)
+
+	_, err = NewRedactor([]*RedactRule{{Value: "unknown"}})
+	assert.Error(t, err)
+	_, err = NewRedactor([]*RedactRule{{Key: "/(/"}})
+	assert.Error(t, err)
+}
+
+func TestHandlersRedact(t *testing.T) {
+	fh := withFieldsHandler(t)
+	r, err := NewRedactor([]*RedactRule{{Key: "token"}, {Value: "email"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.(levelHandler).Handler.(*Handlers).redactor = r
+	Infov(context.Background(), KVString("token", "abc"), KVString(_log, "from a@b.com"))
+
+	assert.Equal(t, "***", fh.logs[0]["token"])
+	assert.Equal(t, "from ***", fh.logs[0][_log])
+}
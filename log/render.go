@@ -0,0 +1,177 @@
+package log
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gisvr/golib/log/internal/core"
+)
+
+var (
+	_processName = processName()
+	_processID   = os.Getpid()
+)
+
+// renderEntry is the typed fields of a log being rendered, it is pooled with
+// the scratch buffer so the fields are rendered without allocation.
+type renderEntry struct {
+	fields  []D
+	json    *jsonpattern
+	scratch []byte
+}
+
+var _renderEntryPool = sync.Pool{New: func() interface{} {
+	return &renderEntry{scratch: make([]byte, 0, 64)}
+}}
+
+func getRenderEntry(fields []D) *renderEntry {
+	e := _renderEntryPool.Get().(*renderEntry)
+	e.fields = fields
+	return e
+}
+
+func putRenderEntry(e *renderEntry) {
+	e.fields, e.json = nil, nil
+	e.scratch = e.scratch[:0]
+	_renderEntryPool.Put(e)
+}
+
+// extraField returns the app and host fields which are added by the handlers.
+func extraField(key string) (D, bool) {
+	switch key {
+	case _svcName:
+		return KVString(key, _processName), true
+	case _bizName:
+		return KVString(key, c.Family), true
+	case _pid:
+		return KVInt(key, _processID), true
+	case _hostName:
+		return KVString(key, c.Host), true
+	}
+	return D{}, false
+}
+
+// lookup returns the field of the key, the later one wins if the key is duplicated.
+func (e *renderEntry) lookup(key string) (D, bool) {
+	if d, ok := extraField(key); ok {
+		return d, true
+	}
+	for i := len(e.fields) - 1; i >= 0; i-- {
+		if e.fields[i].Key == key {
+			return e.fields[i], true
+		}
+	}
+	return D{}, false
+}
+
+// time returns the time of the log captured by the handlers, which is
+// rendered instead of the current time as the rendering may be delayed by
+// the async handler.
+func (e *renderEntry) time() time.Time {
+	return logTime(e.fields)
+}
+
+// logTime returns the time field of the log, or the current time if absent.
+func logTime(fields []D) time.Time {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i].Key == _time && fields[i].Type == core.TimeType {
+			return time.Unix(0, fields[i].Int64Val)
+		}
+	}
+	return time.Now()
+}
+
+// overridden reports whether the i-th field is overridden by a later one of the same key.
+func (e *renderEntry) overridden(i int) bool {
+	for j := i + 1; j < len(e.fields); j++ {
+		if e.fields[j].Key == e.fields[i].Key {
+			return true
+		}
+	}
+	return false
+}
+
+// text returns the text of the field value in the scratch buffer, it is only
+// valid until the next call.
+func (e *renderEntry) text(d D) []byte {
+	e.scratch = appendValue(e.scratch[:0], d)
+	return e.scratch
+}
+
+// appendValue appends the text of the field value to b, which is the same as
+// fmt.Sprint except the times.
+func appendValue(b []byte, d D) []byte {
+	switch d.Type {
+	case core.StringType:
+		return append(b, d.StringVal...)
+	case core.IntTpye, core.Int64Type:
+		return strconv.AppendInt(b, d.Int64Val, 10)
+	case core.UintType, core.Uint64Type:
+		return strconv.AppendUint(b, uint64(d.Int64Val), 10)
+	case core.Float32Type:
+		return strconv.AppendFloat(b, float64(math.Float32frombits(uint32(d.Int64Val))), 'g', -1, 32)
+	case core.Float64Type:
+		return strconv.AppendFloat(b, math.Float64frombits(uint64(d.Int64Val)), 'g', -1, 64)
+	case core.DurationType:
+		return append(b, time.Duration(d.Int64Val).String()...)
+	case core.TimeType:
+		return time.Unix(0, d.Int64Val).AppendFormat(b, _timeFormat)
+	}
+	switch v := d.Value.(type) {
+	case string:
+		return append(b, v...)
+	case []byte:
+		return append(b, v...)
+	case bool:
+		return strconv.AppendBool(b, v)
+	case int:
+		return strconv.AppendInt(b, int64(v), 10)
+	case int64:
+		return strconv.AppendInt(b, v, 10)
+	case int32:
+		return strconv.AppendInt(b, int64(v), 10)
+	case uint:
+		return strconv.AppendUint(b, uint64(v), 10)
+	case uint64:
+		return strconv.AppendUint(b, v, 10)
+	case uint32:
+		return strconv.AppendUint(b, uint64(v), 10)
+	case float64:
+		return strconv.AppendFloat(b, v, 'g', -1, 64)
+	case time.Time:
+		return v.AppendFormat(b, _timeFormat)
+	case error:
+		return append(b, v.Error()...)
+	case fmt.Stringer:
+		return append(b, v.String()...)
+	}
+	return append(b, fmt.Sprint(d.Value)...)
+}
+
+// mapFields converts the map to the fields for the legacy Render.
+func mapFields(d map[string]interface{}) []D {
+	fields := make([]D, 0, len(d))
+	for k, v := range d {
+		switch val := v.(type) {
+		case string:
+			fields = append(fields, KVString(k, val))
+		case int:
+			fields = append(fields, KVInt(k, val))
+		case int64:
+			fields = append(fields, KVInt64(k, val))
+		case float64:
+			fields = append(fields, KVFloat64(k, val))
+		case time.Duration:
+			fields = append(fields, KVDuration(k, val))
+		case time.Time:
+			fields = append(fields, KVTime(k, val))
+		default:
+			fields = append(fields, KV(k, v))
+		}
+	}
+	return fields
+}
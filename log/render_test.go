@@ -0,0 +1,144 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testFields() []D {
+	return []D{
+		KVString(_log, "hello"),
+		KVString("user", "u1"),
+		KVInt("n", 1),
+		KVFloat64("f", 0.5),
+		KVDuration("d", time.Second),
+		KVString("user", "u2"),
+		KVString(_fileName, "/a/b/c.go"),
+		KVInt(_line, 12),
+		KVTime(_time, time.Now()),
+		KVInt64(_levelValue, int64(_infoLevel)),
+		KVString(_level, _infoLevel.String()),
+	}
+}
+
+func TestPatternRenderFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	p := newPatternRender("%L %s %S %P %M")
+	assert.NoError(t, p.RenderFields(buf, testFields()))
+	assert.Equal(t, "INFO c.go:12 /a/b/c.go:12 "+_processName+" n=1 f=0.5 d=1s user=u2 hello\n", buf.String())
+}
+
+func TestJSONPatternRenderFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	p := newJsonPatternRender("%T %L %s %p %M")
+	assert.NoError(t, p.RenderFields(buf, testFields()))
+
+	m := make(map[string]interface{})
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &m), buf.String())
+	assert.Equal(t, "INFO", m[_level])
+	assert.Equal(t, "c.go", m[_fileName])
+	assert.Equal(t, float64(12), m[_line])
+	assert.NotEmpty(t, m[_time])
+	assert.NotEmpty(t, m[_pid])
+	assert.Equal(t, "hello", m[_log])
+	assert.Equal(t, "u2", m["user"])
+	assert.Equal(t, float64(1), m["n"])
+	assert.Equal(t, float64(1), m["d"])
+}
+
+func TestRenderLogTime(t *testing.T) {
+	// the time captured by the handlers is rendered, not the rendering time.
+	ts := time.Date(2019, 1, 2, 3, 4, 5, 6e6, time.Local)
+	fields := []D{KVString(_log, "hello"), KVTime(_time, ts)}
+	for _, c := range []struct {
+		render Render
+		expect string
+	}{
+		{newPatternRender("%T|%t"), "2019-01-02T03:04:05.006|2019-01-02T03:04:05"},
+		{newJsonPatternRender("%T"), `"time":"2019-01-02T03:04:05.006"`},
+		{newRender(EncoderLogfmt, false, "%t"), "time=2019-01-02T03:04:05"},
+	} {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, c.render.RenderFields(buf, fields))
+		assert.Contains(t, buf.String(), c.expect)
+	}
+}
+
+func TestRenderMap(t *testing.T) {
+	p := newPatternRender("%L %s %M")
+	s := p.RenderString(map[string]interface{}{_level: "WARN", _fileName: "/a/b.go", _line: int64(3), _log: "hi"})
+	assert.Equal(t, "WARN b.go:3 hi", s)
+}
+
+func TestRenderFieldsAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector allocates")
+	}
+	fields := testFields()
+	for _, p := range []Render{newPatternRender(defaultFilePattern), newJsonPatternRender(defaultFilePattern)} {
+		p.RenderFields(ioutil.Discard, fields)
+		allocs := testing.AllocsPerRun(100, func() {
+			p.RenderFields(ioutil.Discard, fields[1:5])
+		})
+		assert.Equal(t, float64(0), allocs)
+	}
+}
+
+func benchmarkRender(b *testing.B, p Render) {
+	fields := testFields()
+	b.Run("fields", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			p.RenderFields(ioutil.Discard, fields)
+		}
+	})
+	b.Run("map", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			p.Render(ioutil.Discard, toMap(fields...))
+		}
+	})
+}
+
+func BenchmarkPatternRender(b *testing.B) {
+	benchmarkRender(b, newPatternRender(defaultFilePattern))
+}
+
+func BenchmarkJSONPatternRender(b *testing.B) {
+	benchmarkRender(b, newJsonPatternRender(defaultFilePattern))
+}
+
+// discardHandler renders the logs to ioutil.Discard like the file handler.
+type discardHandler struct {
+	render Render
+}
+
+func (h *discardHandler) Log(ctx context.Context, depth int, lv Level, args ...D) {
+	h.render.RenderFields(ioutil.Discard, args)
+}
+
+func (h *discardHandler) SetFormat(string) {}
+
+func (h *discardHandler) Close() error { return nil }
+
+func BenchmarkLogCall(b *testing.B) {
+	for name, render := range map[string]Render{
+		"pattern": newPatternRender(defaultFilePattern),
+		"json":    newJsonPatternRender(defaultFilePattern),
+	} {
+		hs := newHandlers(nil, &discardHandler{render: render})
+		b.Run(name, func(b *testing.B) {
+			ctx := context.Background()
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				hs.Log(ctx, 0, _infoLevel, KVString(_log, "hello"), KVString("user", "u1"), KVInt("n", i))
+			}
+		})
+	}
+}
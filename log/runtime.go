@@ -0,0 +1,168 @@
+package log
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	xtime "github.com/gisvr/golib/time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+)
+
+// _defaultRevert is the default duration after which an override is reverted.
+const _defaultRevert = 10 * time.Minute
+
+// verbosity is the V level and the module V levels.
+type verbosity struct {
+	v      int32
+	module map[string]int32
+}
+
+var (
+	_verbose atomic.Value // *verbosity
+
+	_overrideMu  sync.Mutex
+	_baseline    *Runtime    // the settings before the override, nil if there is no override.
+	_revertAt    time.Time   // when the override is reverted.
+	_revert      *time.Timer // the revert timer of the override.
+	_overrideGen uint64      // the generation of the override, a stale timer is ignored.
+)
+
+func init() {
+	_verbose.Store(&verbosity{})
+}
+
+func loadVerbosity() *verbosity {
+	return _verbose.Load().(*verbosity)
+}
+
+// SetV sets the V level of the global logger.
+func SetV(v int32) {
+	_verbose.Store(&verbosity{v: v, module: loadVerbosity().module})
+}
+
+// SetModule sets the V levels of the modules, see Config.Module.
+func SetModule(module map[string]int32) {
+	m := make(map[string]int32, len(module))
+	for k, v := range module {
+		m[k] = v
+	}
+	_verbose.Store(&verbosity{v: loadVerbosity().v, module: m})
+}
+
+// Runtime is the log settings which can be changed at runtime, eg:
+//
+//	level = "debug"
+//	v = 3
+//	revert = "30m"
+//	[module]
+//	"dao*" = 5
+type Runtime struct {
+	// Level is the min level, the level is unchanged if it is empty.
+	Level string `toml:"level" json:"level"`
+	// V is the V level, it is unchanged if it is nil.
+	V *int32 `toml:"v" json:"v"`
+	// Module is the V levels of the modules, they are unchanged if it is nil.
+	Module map[string]int32 `toml:"module" json:"module,omitempty"`
+	// Revert is the duration after which the override is reverted, default 10m.
+	Revert xtime.Duration `toml:"revert" json:"revert,omitempty"`
+}
+
+// Set implements the paladin.Setter, the override of the text in toml is
+// applied, and the empty text reverts the override, eg:
+// paladin.Watch("log.toml", &log.Runtime{}).
+func (r *Runtime) Set(text string) error {
+	if strings.TrimSpace(text) == "" {
+		Revert()
+		return nil
+	}
+	nr := &Runtime{}
+	if _, err := toml.Decode(text, nr); err != nil {
+		return errors.Wrap(err, "log: decode runtime")
+	}
+	if err := Override(nr); err != nil {
+		return err
+	}
+	*r = *nr
+	return nil
+}
+
+// Current returns the current settings, and when the override is reverted,
+// which is zero if there is no override.
+func Current() (*Runtime, time.Time) {
+	vb := loadVerbosity()
+	v := vb.v
+	r := &Runtime{Level: GetLevel().String(), V: &v, Module: vb.module}
+	_overrideMu.Lock()
+	revertAt := _revertAt
+	_overrideMu.Unlock()
+	return r, revertAt
+}
+
+// Override applies the settings temporarily, they are reverted to the ones
+// before the first override after r.Revert, so that a debug level can not be
+// left on. A later override resets the revert timer.
+func Override(r *Runtime) error {
+	lv := GetLevel()
+	if r.Level != "" {
+		var err error
+		if lv, err = ParseLevel(r.Level); err != nil {
+			return err
+		}
+	}
+	revert := time.Duration(r.Revert)
+	if revert <= 0 {
+		revert = _defaultRevert
+	}
+	_overrideMu.Lock()
+	defer _overrideMu.Unlock()
+	if _baseline == nil {
+		vb := loadVerbosity()
+		v := vb.v
+		_baseline = &Runtime{Level: GetLevel().String(), V: &v, Module: vb.module}
+	}
+	if _revert != nil {
+		_revert.Stop()
+	}
+	_overrideGen++
+	gen := _overrideGen
+	_revertAt = time.Now().Add(revert)
+	_revert = time.AfterFunc(revert, func() { revertGen(gen) })
+
+	SetLevel(lv)
+	if r.V != nil {
+		SetV(*r.V)
+	}
+	if r.Module != nil {
+		SetModule(r.Module)
+	}
+	vb := loadVerbosity()
+	Warnf("log: override level(%s) v(%d) module(%v), revert after %s", lv, vb.v, vb.module, revert)
+	return nil
+}
+
+// Revert reverts the override immediately.
+func Revert() {
+	revertGen(0)
+}
+
+// revertGen reverts the override of the generation, or any override if gen is 0.
+func revertGen(gen uint64) {
+	_overrideMu.Lock()
+	defer _overrideMu.Unlock()
+	if _baseline == nil || (gen != 0 && gen != _overrideGen) {
+		return
+	}
+	if _revert != nil {
+		_revert.Stop()
+	}
+	lv, _ := ParseLevel(_baseline.Level)
+	SetLevel(lv)
+	SetV(*_baseline.V)
+	SetModule(_baseline.Module)
+	_baseline, _revert, _revertAt = nil, nil, time.Time{}
+	Warnf("log: override is reverted to level(%s) v(%d)", lv, loadVerbosity().v)
+}
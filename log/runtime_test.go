@@ -0,0 +1,44 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	xtime "github.com/gisvr/golib/time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverride(t *testing.T) {
+	defer Revert()
+	SetV(1)
+	assert.False(t, bool(V(2)))
+
+	v := int32(2)
+	assert.NoError(t, Override(&Runtime{Level: "error", V: &v, Revert: xtime.Duration(50 * time.Millisecond)}))
+	assert.Equal(t, ErrorLevel, GetLevel())
+	assert.True(t, bool(V(2)))
+	_, revertAt := Current()
+	assert.False(t, revertAt.IsZero())
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, DebugLevel, GetLevel(), "the override is reverted")
+	assert.False(t, bool(V(2)))
+	assert.Error(t, Override(&Runtime{Level: "none"}))
+
+	// the level only override keeps the V level.
+	assert.NoError(t, Override(&Runtime{Level: "warn"}))
+	assert.Equal(t, WarnLevel, GetLevel())
+	assert.True(t, bool(V(1)))
+}
+
+func TestRuntimeSet(t *testing.T) {
+	defer Revert()
+	r := &Runtime{}
+	assert.NoError(t, r.Set("level = \"warn\"\nv = 3\n[module]\n\"runtime*\" = 5\n"))
+	assert.Equal(t, WarnLevel, GetLevel())
+	assert.True(t, bool(V(5)), "the module is matched")
+	assert.NoError(t, r.Set(""))
+	assert.Equal(t, DebugLevel, GetLevel())
+	assert.Error(t, r.Set("level = "))
+}
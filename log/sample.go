@@ -0,0 +1,193 @@
+package log
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	xtime "github.com/gisvr/golib/time"
+)
+
+// _maxSampleTemplate is the max length of the message template in the sample key.
+const _maxSampleTemplate = 128
+
+// SampleOption is the log sampling option, the logs of the same level, source
+// line and message template are logged First times in every Interval, then
+// every Thereafter-th, and the suppressed counts are logged every Summary.
+// The fatal logs are never sampled.
+type SampleOption struct {
+	// Interval default 1s.
+	Interval xtime.Duration `yaml:"interval"`
+	// First default 100.
+	First int `yaml:"first"`
+	// Thereafter is the sample rate after the first logs, 0 drops all of them.
+	Thereafter int `yaml:"thereafter"`
+	// Summary is the interval of the suppressed counts summary, default 1m.
+	Summary xtime.Duration `yaml:"summary"`
+}
+
+type sampleKey struct {
+	lv   Level
+	file string
+	line int
+	tmpl string
+}
+
+type sampleCounter struct {
+	start      time.Time
+	n          int
+	suppressed int64
+	active     bool
+}
+
+// sampler samples the logs before they are fanned out to the handlers.
+type sampler struct {
+	opt      SampleOption
+	emit     func(lv Level, d ...D)
+	mu       sync.Mutex
+	counters map[sampleKey]*sampleCounter
+	done     chan struct{}
+	exited   chan struct{}
+	once     sync.Once
+}
+
+func newSampler(opt *SampleOption, emit func(lv Level, d ...D)) *sampler {
+	s := &sampler{
+		opt:      *opt,
+		emit:     emit,
+		counters: make(map[sampleKey]*sampleCounter),
+		done:     make(chan struct{}),
+		exited:   make(chan struct{}),
+	}
+	if s.opt.Interval <= 0 {
+		s.opt.Interval = xtime.Duration(time.Second)
+	}
+	if s.opt.First <= 0 {
+		s.opt.First = 100
+	}
+	if s.opt.Summary <= 0 {
+		s.opt.Summary = xtime.Duration(time.Minute)
+	}
+	go s.proc()
+	return s
+}
+
+// template returns the message with the digits replaced by #, so that the
+// messages formatted by the same template share the key.
+func template(d []D) string {
+	var msg string
+	for i := range d {
+		if d[i].Key == _log {
+			msg = d[i].StringVal
+			break
+		}
+	}
+	if len(msg) > _maxSampleTemplate {
+		msg = msg[:_maxSampleTemplate]
+	}
+	b := make([]byte, 0, len(msg))
+	for i := 0; i < len(msg); i++ {
+		if c := msg[i]; c >= '0' && c <= '9' {
+			if len(b) == 0 || b[len(b)-1] != '#' {
+				b = append(b, '#')
+			}
+			continue
+		}
+		b = append(b, msg[i])
+	}
+	return string(b)
+}
+
+// allow reports whether the log is sampled.
+func (s *sampler) allow(lv Level, file string, line int, d []D, now time.Time) bool {
+	if lv >= _fatalLevel {
+		return true
+	}
+	key := sampleKey{lv: lv, file: file, line: line, tmpl: template(d)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.counters[key]
+	if !ok {
+		c = &sampleCounter{start: now}
+		s.counters[key] = c
+	}
+	c.active = true
+	if now.Sub(c.start) >= time.Duration(s.opt.Interval) {
+		c.start, c.n = now, 0
+	}
+	c.n++
+	if c.n <= s.opt.First || (s.opt.Thereafter > 0 && (c.n-s.opt.First)%s.opt.Thereafter == 0) {
+		return true
+	}
+	c.suppressed++
+	return false
+}
+
+// summary logs the suppressed counts and removes the idle counters.
+func (s *sampler) summary() {
+	type suppressed struct {
+		key sampleKey
+		n   int64
+	}
+	var ss []suppressed
+	s.mu.Lock()
+	for key, c := range s.counters {
+		if c.suppressed > 0 {
+			ss = append(ss, suppressed{key: key, n: c.suppressed})
+			c.suppressed = 0
+		}
+		if !c.active {
+			delete(s.counters, key)
+		}
+		c.active = false
+	}
+	s.mu.Unlock()
+	for _, e := range ss {
+		s.emit(_warnLevel,
+			KVString(_log, "log: sampling suppressed "+strconv.FormatInt(e.n, 10)+" logs"),
+			KVString("sampled_level", e.key.lv.String()),
+			KVString("sampled_source", e.key.file+":"+strconv.Itoa(e.key.line)),
+			KVString("sampled_template", e.key.tmpl),
+			KVInt64("suppressed", e.n),
+		)
+	}
+}
+
+func (s *sampler) proc() {
+	defer close(s.exited)
+	tk := time.NewTicker(time.Duration(s.opt.Summary))
+	defer tk.Stop()
+	for {
+		select {
+		case <-tk.C:
+			s.summary()
+		case <-s.done:
+			s.summary()
+			return
+		}
+	}
+}
+
+// close logs the last summary and stops the sampler, it returns after the
+// summary is logged, so that the handlers can be closed then.
+func (s *sampler) close() {
+	s.once.Do(func() { close(s.done) })
+	<-s.exited
+}
+
+// emitter returns the function which logs the summary by the handlers
+// without sampling.
+func (hs *Handlers) emitter() func(lv Level, d ...D) {
+	return func(lv Level, d ...D) {
+		if hs.redactor != nil {
+			for i := range d {
+				d[i] = hs.redactor.Field(d[i])
+			}
+		}
+		d = append(d, KVTime(_time, time.Now()), KVInt64(_levelValue, int64(lv)), KVString(_level, lv.String()))
+		for _, h := range hs.handlers {
+			h.Log(context.Background(), 0, lv, d...)
+		}
+	}
+}
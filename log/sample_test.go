@@ -0,0 +1,82 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	xtime "github.com/gisvr/golib/time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampleTemplate(t *testing.T) {
+	assert.Equal(t, "order # paid #.#", template([]D{KVString(_log, "order 123 paid 9.99")}))
+	assert.Equal(t, "", template([]D{KVString("k", "v")}))
+}
+
+func TestSamplerAllow(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		sums []map[string]interface{}
+	)
+	s := newSampler(&SampleOption{Interval: xtime.Duration(time.Second), First: 2, Thereafter: 3}, func(lv Level, d ...D) {
+		mu.Lock()
+		sums = append(sums, toMap(d...))
+		mu.Unlock()
+	})
+	now := time.Now()
+	var allowed []int
+	for i := 1; i <= 8; i++ {
+		if s.allow(_infoLevel, "a.go", 1, []D{KVString(_log, "req "+string(rune('0'+i)))}, now) {
+			allowed = append(allowed, i)
+		}
+	}
+	// first 2, then every 3rd.
+	assert.Equal(t, []int{1, 2, 5, 8}, allowed)
+	// another source line is not affected.
+	assert.True(t, s.allow(_infoLevel, "a.go", 2, []D{KVString(_log, "req 1")}, now))
+	// the fatal logs are never sampled.
+	for i := 0; i < 10; i++ {
+		assert.True(t, s.allow(_fatalLevel, "a.go", 1, []D{KVString(_log, "req 1")}, now))
+	}
+	// the next interval.
+	assert.True(t, s.allow(_infoLevel, "a.go", 1, []D{KVString(_log, "req 1")}, now.Add(time.Second)))
+
+	// the summary is logged before close returns.
+	s.close()
+	s.close()
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, sums, 1)
+	assert.Equal(t, int64(4), sums[0]["suppressed"])
+	assert.Equal(t, "a.go:1", sums[0]["sampled_source"])
+	assert.Equal(t, "req #", sums[0]["sampled_template"])
+}
+
+func TestSamplerDropAll(t *testing.T) {
+	s := newSampler(&SampleOption{First: 1}, func(Level, ...D) {})
+	defer s.close()
+	now := time.Now()
+	assert.True(t, s.allow(_warnLevel, "a.go", 1, nil, now))
+	assert.False(t, s.allow(_warnLevel, "a.go", 1, nil, now))
+	assert.False(t, s.allow(_warnLevel, "a.go", 1, nil, now))
+}
+
+func TestHandlersSample(t *testing.T) {
+	fh := &fieldsHandler{}
+	hs := newHandlers(nil, fh)
+	hs.sampler = newSampler(&SampleOption{First: 3}, hs.emitter())
+	for i := 0; i < 10; i++ {
+		hs.Log(context.Background(), 0, _infoLevel, KVString(_log, "hot path"))
+	}
+	hs.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	assert.Len(t, fh.logs, 4)
+	assert.Equal(t, int64(7), fh.logs[3]["suppressed"])
+	assert.Equal(t, "WARN", fh.logs[3][_level])
+}
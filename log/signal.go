@@ -0,0 +1,37 @@
+// +build !windows
+
+package log
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	xtime "github.com/gisvr/golib/time"
+)
+
+// WatchSignals changes the settings by the signals until stop is closed:
+// SIGUSR1 overrides the level to debug and raises V by 1 each time, and
+// SIGUSR2 reverts the override. The override is reverted after revert
+// anyway, default 10m.
+func WatchSignals(revert time.Duration, stop <-chan struct{}) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case sig := <-ch:
+				if sig == syscall.SIGUSR2 {
+					Revert()
+					continue
+				}
+				v := loadVerbosity().v + 1
+				Override(&Runtime{Level: _debugLevel.String(), V: &v, Revert: xtime.Duration(revert)})
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,6 @@
+package log
+
+import "time"
+
+// WatchSignals is a no-op on windows, which has no SIGUSR1 and SIGUSR2.
+func WatchSignals(revert time.Duration, stop <-chan struct{}) {}
@@ -3,7 +3,6 @@ package log
 import (
 	"context"
 	"os"
-	"time"
 )
 
 const defaultStdoutPattern = "%T %L %s\t] %M"
@@ -22,11 +21,7 @@ func NewStdout(opt *StdoutOption) *StdoutHandler {
 		opt.Fmt = defaultStdoutPattern
 	}
 	h := &StdoutHandler{opt: opt}
-	if opt.Json {
-		h.render = newJsonPatternRender(opt.Fmt)
-	} else {
-		h.render = newPatternRender(opt.Fmt)
-	}
+	h.render = newRender(opt.Encoder, opt.Json, opt.Fmt)
 	return h
 }
 
@@ -75,12 +70,7 @@ func (h *StdoutHandler) Log(ctx context.Context, depth int, lv Level, args ...D)
 		//日志内容都被过滤了
 		return
 	}
-	d := toMap(args...)
-	// add extra fields
-	addExtraField(ctx, d)
-	d[_time] = time.Now().Format(_timeFormat)
-
-	h.render.Render(os.Stderr, d)
+	h.render.RenderFields(os.Stderr, args)
 }
 
 // Close stdout loging
@@ -102,5 +92,5 @@ func (h *StdoutHandler) Close() error {
 // %s final file name element and line number: d.go:23
 // %M log message and additional fields: key=value this is log message
 func (h *StdoutHandler) SetFormat(format string) {
-	h.render = newPatternRender(format)
+	h.render = newRender(h.opt.Encoder, h.opt.Json, format)
 }
@@ -0,0 +1,193 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gisvr/golib/log/internal/core"
+	xtime "github.com/gisvr/golib/time"
+)
+
+const (
+	defaultSyslogPattern = "%s %M"
+	_syslogTimeFormat    = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+var _syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3,
+	"auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+var _syslogSeverities = [...]int{
+	_debugLevel: 7,
+	_infoLevel:  6,
+	_warnLevel:  4,
+	_errorLevel: 3,
+	_fatalLevel: 2,
+}
+
+// SyslogOption is the option of the RFC5424 syslog handler.
+type SyslogOption struct {
+	// Network is udp(default), tcp or unix, the messages over tcp and unix
+	// stream are framed by the octet counting of RFC6587.
+	Network string `yaml:"network"`
+	// Addr is the host:port, or the socket path of unix, eg: /dev/log.
+	Addr string `yaml:"addr"`
+	// Facility is kern, user(default), mail, daemon ... local0 to local7.
+	Facility string `yaml:"facility"`
+	// Tag is the APP-NAME, default the process name.
+	Tag string `yaml:"tag"`
+	// Fmt is the pattern of the MSG, default "%s %M".
+	Fmt string `yaml:"fmt"`
+	// Encoder is the encoder of the MSG, pattern(default), json, logfmt or otlp.
+	Encoder string `yaml:"encoder"`
+	// WriteTimeout default 1s.
+	WriteTimeout xtime.Duration `yaml:"writetimeout"`
+}
+
+// SyslogHandler sends the logs to syslog synchronously, wrap it by
+// NewAsync to send in a goroutine.
+type SyslogHandler struct {
+	opt      *SyslogOption
+	facility int
+	render   Render
+
+	mu      sync.Mutex
+	conn    net.Conn
+	stream  bool
+	retry   time.Time
+	backoff time.Duration
+}
+
+// NewSyslog create a syslog handler, it connects lazily.
+func NewSyslog(opt *SyslogOption) *SyslogHandler {
+	if opt.Network == "" {
+		opt.Network = "udp"
+	}
+	if opt.Facility == "" {
+		opt.Facility = "user"
+	}
+	facility, ok := _syslogFacilities[opt.Facility]
+	if !ok {
+		panic(fmt.Sprintf("log: invalid syslog facility: %s", opt.Facility))
+	}
+	if opt.Tag == "" {
+		opt.Tag = processName()
+	}
+	// APP-NAME is at most 48 printable characters.
+	opt.Tag = strings.Replace(opt.Tag, " ", "_", -1)
+	if len(opt.Tag) > 48 {
+		opt.Tag = opt.Tag[:48]
+	}
+	if opt.Fmt == "" {
+		opt.Fmt = defaultSyslogPattern
+	}
+	if opt.WriteTimeout <= 0 {
+		opt.WriteTimeout = xtime.Duration(time.Second)
+	}
+	return &SyslogHandler{opt: opt, facility: facility, render: newRender(opt.Encoder, false, opt.Fmt)}
+}
+
+// format formats the RFC5424 message:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func (h *SyslogHandler) format(lv Level, ts time.Time, msg string) []byte {
+	severity := _syslogSeverities[_infoLevel]
+	if int(lv) >= 0 && int(lv) < len(_syslogSeverities) {
+		severity = _syslogSeverities[lv]
+	}
+	host := c.Host
+	if host == "" {
+		host = "-"
+	}
+	b := fmt.Sprintf("<%d>1 %s %s %s %d - - %s", h.facility*8+severity,
+		ts.Format(_syslogTimeFormat), host, h.opt.Tag, os.Getpid(), msg)
+	if h.stream {
+		b = fmt.Sprintf("%d %s", len(b), b)
+	}
+	return []byte(b)
+}
+
+// Log sends the log to syslog, it is retried once after reconnected.
+func (h *SyslogHandler) Log(ctx context.Context, depth int, lv Level, args ...D) {
+	buf := core.GetPool()
+	defer buf.Free()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.render.RenderFields(buf, args)
+	msg := strings.TrimRight(buf.String(), "\n")
+	for i := 0; i < 2; i++ {
+		if h.conn == nil && !h.dial() {
+			_metricNetDropped.Inc("syslog", _dropDown)
+			return
+		}
+		h.conn.SetWriteDeadline(time.Now().Add(time.Duration(h.opt.WriteTimeout)))
+		if _, err := h.conn.Write(h.format(lv, logTime(args), msg)); err == nil {
+			return
+		}
+		h.conn.Close()
+		h.conn = nil
+	}
+	_metricNetDropped.Inc("syslog", _dropSend)
+}
+
+// dial connects syslog, it is retried with backoff up to 30s after failed
+// as NetHandler.
+func (h *SyslogHandler) dial() bool {
+	if time.Now().Before(h.retry) {
+		return false
+	}
+	conn, stream, err := h.dialSyslog()
+	if err != nil {
+		if h.backoff == 0 {
+			fmt.Fprintf(os.Stderr, "log: dial syslog %s error(%v)\n", h.opt.Addr, err)
+			h.backoff = 100 * time.Millisecond
+		} else if h.backoff *= 2; h.backoff > 30*time.Second {
+			h.backoff = 30 * time.Second
+		}
+		h.retry = time.Now().Add(h.backoff)
+		return false
+	}
+	h.conn, h.stream, h.backoff = conn, stream, 0
+	return true
+}
+
+func (h *SyslogHandler) dialSyslog() (conn net.Conn, stream bool, err error) {
+	if h.opt.Network != "unix" {
+		conn, err = net.DialTimeout(h.opt.Network, h.opt.Addr, time.Second)
+		return conn, !strings.HasPrefix(h.opt.Network, "udp"), err
+	}
+	// the local syslog daemon is usually listening on the unix datagram socket.
+	if conn, err = net.DialTimeout("unixgram", h.opt.Addr, time.Second); err == nil {
+		return conn, false, nil
+	}
+	conn, err = net.DialTimeout("unix", h.opt.Addr, time.Second)
+	return conn, true, err
+}
+
+// Close closes the connection.
+func (h *SyslogHandler) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.conn == nil {
+		return nil
+	}
+	err := h.conn.Close()
+	h.conn = nil
+	return err
+}
+
+// SetFormat set the MSG pattern, see StdoutHandler.SetFormat for detail.
+func (h *SyslogHandler) SetFormat(format string) {
+	h.mu.Lock()
+	h.render = newRender(h.opt.Encoder, false, format)
+	h.mu.Unlock()
+}
@@ -1,7 +1,6 @@
 package log
 
 import (
-	"context"
 	"math"
 	"os"
 	"path/filepath"
@@ -12,13 +11,6 @@ import (
 	"github.com/gisvr/golib/log/internal/core"
 )
 
-func addExtraField(ctx context.Context, fields map[string]interface{}) {
-	fields[_svcName] = processName()
-	fields[_bizName] = c.Family
-	fields[_pid] = os.Getpid()
-	fields[_hostName] = c.Host
-}
-
 func processName() string {
 	app := os.Args[0]
 	app = filepath.Base(app)
@@ -49,6 +41,8 @@ func toMap(args ...D) map[string]interface{} {
 			d[arg.Key] = math.Float64frombits(uint64(arg.Int64Val))
 		case core.DurationType:
 			d[arg.Key] = time.Duration(arg.Int64Val)
+		case core.TimeType:
+			d[arg.Key] = time.Unix(0, arg.Int64Val)
 		default:
 			d[arg.Key] = arg.Value
 		}
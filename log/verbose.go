@@ -24,15 +24,22 @@ import (
 // call, the V call will log.
 // v must be more than 0.
 func V(v int32) Verbose {
+	return VDepth(1, v)
+}
+
+// VDepth is V which matches the module of the depth caller, it is used by
+// the wrappers of V.
+func VDepth(depth int, v int32) Verbose {
 	var (
 		file string
 	)
+	vb := loadVerbosity()
 	if v < 0 {
 		return Verbose(false)
-	} else if c.V >= v {
+	} else if vb.v >= v {
 		return Verbose(true)
 	}
-	if pc, _, _, ok := runtime.Caller(1); ok {
+	if pc, _, _, ok := runtime.Caller(1 + depth); ok {
 		file, _ = runtime.FuncForPC(pc).FileLine(pc)
 	}
 	if strings.HasSuffix(file, ".go") {
@@ -41,7 +48,7 @@ func V(v int32) Verbose {
 	if slash := strings.LastIndex(file, "/"); slash >= 0 {
 		file = file[slash+1:]
 	}
-	for filter, lvl := range c.Module {
+	for filter, lvl := range vb.module {
 		var match bool
 		if match = filter == file; !match {
 			match, _ = filepath.Match(filter, file)
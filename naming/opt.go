@@ -0,0 +1,157 @@
+package naming
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"net/url"
+	"sort"
+
+	"github.com/gisvr/golib/conf/env"
+)
+
+// BuildOptions build options.
+type BuildOptions struct {
+	Filter     func(map[string][]*Instance) map[string][]*Instance
+	Subset     func([]*Instance, int) []*Instance
+	SubsetSize int
+	ClientZone string
+	Scheduler  func(*InstancesInfo) []*Instance
+}
+
+// BuildOpt build option interface.
+type BuildOpt interface {
+	Apply(*BuildOptions)
+}
+
+type funcOpt struct {
+	f func(*BuildOptions)
+}
+
+func (f *funcOpt) Apply(opt *BuildOptions) {
+	f.f(opt)
+}
+
+// Filter filters the instances which have an address of the scheme, and
+// belong to the clusters if clusters is not empty.
+func Filter(scheme string, clusters map[string]struct{}) BuildOpt {
+	return &funcOpt{f: func(opt *BuildOptions) {
+		opt.Filter = func(inss map[string][]*Instance) map[string][]*Instance {
+			newInss := make(map[string][]*Instance)
+			for zone := range inss {
+				var instances []*Instance
+				for _, ins := range inss[zone] {
+					if len(clusters) > 0 {
+						if _, ok := clusters[ins.Metadata[MetaCluster]]; !ok {
+							continue
+						}
+					}
+					var addr string
+					for _, a := range ins.Addrs {
+						u, err := url.Parse(a)
+						if err == nil && u.Scheme == scheme {
+							addr = u.Host
+						}
+					}
+					if addr == "" {
+						continue
+					}
+					instances = append(instances, ins)
+				}
+				newInss[zone] = instances
+			}
+			return newInss
+		}
+	}}
+}
+
+// defaultSubset selects size instances stably by the hostname of the client,
+// so that the clients are spread evenly over the instances.
+func defaultSubset(inss []*Instance, size int) []*Instance {
+	backends := inss
+	if len(backends) <= size {
+		return backends
+	}
+	sort.Slice(backends, func(i, j int) bool {
+		return backends[i].Hostname < backends[j].Hostname
+	})
+	count := len(backends) / size
+	h := fnv.New64a()
+	h.Write([]byte(env.Hostname))
+	id := h.Sum64()
+	// the clients of the same round shuffle the instances in the same order.
+	round := int64(id / uint64(count))
+	ra := rand.New(rand.NewSource(round))
+	ra.Shuffle(len(backends), func(i, j int) {
+		backends[i], backends[j] = backends[j], backends[i]
+	})
+	start := (id % uint64(count)) * uint64(size)
+	return backends[int(start) : int(start)+size]
+}
+
+// Subset selects at most size instances.
+func Subset(size int) BuildOpt {
+	return &funcOpt{f: func(opt *BuildOptions) {
+		opt.SubsetSize = size
+		opt.Subset = defaultSubset
+	}}
+}
+
+// ScheduleNode schedules the instances by the zone strategies of the client
+// zone, the instances of the client zone are preferred if no strategy.
+func ScheduleNode(clientZone string) BuildOpt {
+	return &funcOpt{f: func(opt *BuildOptions) {
+		opt.ClientZone = clientZone
+		opt.Scheduler = func(app *InstancesInfo) (instances []*Instance) {
+			type zone struct {
+				inss   []*Instance
+				weight int64
+				score  float64
+			}
+			var zones []*zone
+			if app.Scheduler != nil {
+				if strategy, ok := app.Scheduler.Clients[clientZone]; ok {
+					var min *zone
+					for name, s := range strategy.Zones {
+						inss := app.Instances[name]
+						if len(inss) == 0 || s.Weight <= 0 {
+							continue
+						}
+						z := &zone{inss: inss, weight: s.Weight, score: float64(len(inss)) / float64(s.Weight)}
+						if min == nil || z.score < min.score {
+							min = z
+						}
+						zones = append(zones, z)
+					}
+					if min != nil && opt.SubsetSize != 0 && len(min.inss) > opt.SubsetSize {
+						min.score = float64(opt.SubsetSize) / float64(min.weight)
+					}
+					for _, z := range zones {
+						nums := int(min.score * float64(z.weight))
+						if nums == 0 {
+							nums = 1
+						}
+						if nums < len(z.inss) {
+							if opt.Subset != nil {
+								z.inss = opt.Subset(z.inss, nums)
+							} else {
+								z.inss = defaultSubset(z.inss, nums)
+							}
+						}
+					}
+				}
+			}
+			for _, z := range zones {
+				instances = append(instances, z.inss...)
+			}
+			if len(instances) == 0 {
+				instances = app.Instances[clientZone]
+				if len(instances) == 0 {
+					for _, value := range app.Instances {
+						instances = append(instances, value...)
+					}
+				}
+			}
+			return
+		}
+	}}
+}
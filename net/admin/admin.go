@@ -0,0 +1,242 @@
+// Package admin serves the admin and debug endpoints on a separate listener,
+// which is authenticated by a token or mutual tls, eg:
+//
+//	admin.Init(&admin.Config{Addr: "127.0.0.1:2333", Token: "secret"})
+//
+// The endpoints are:
+//
+//	/debug/pprof/	the pprof profiles.
+//	/metrics	the prometheus metrics.
+//	/config		the registered configs in json, the secrets are masked.
+//	/stats		the registered stats in json, eg: the breakers and bbr limiters.
+//	/log/level	GET returns the log settings, PUT or POST ?level=debug&v=3&revert=30m
+//			overrides them temporarily, DELETE reverts the override.
+//
+// and the handlers registered by Handle, eg: /metadata of blademaster.
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gisvr/golib/log"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Config is the admin server config, Token or TLS.ClientCAFile is required.
+type Config struct {
+	// Addr is the listen address, default 127.0.0.1:2333.
+	Addr string `yaml:"addr"`
+	// Token is the bearer token of the Authorization header.
+	Token string `yaml:"token"`
+	// TLS serves https, the client certificates are required and verified by
+	// the ClientCAFile if it is set.
+	TLS *TLSConfig `yaml:"tls"`
+	// MaskKeys are the extra config keys which are masked, the keys contain
+	// password, secret, token, key or credential are always masked.
+	MaskKeys []string `yaml:"maskKeys"`
+}
+
+// TLSConfig is the tls config of the admin server.
+type TLSConfig struct {
+	CertFile     string `yaml:"certFile"`
+	KeyFile      string `yaml:"keyFile"`
+	ClientCAFile string `yaml:"clientCAFile"`
+}
+
+// Server is an admin server.
+type Server struct {
+	conf   *Config
+	mux    *http.ServeMux
+	server *http.Server
+}
+
+var (
+	_mutex    sync.RWMutex
+	_handlers = make(map[string]http.Handler)
+	_configs  = make(map[string]func() interface{})
+	_stats    = make(map[string]func() interface{})
+
+	_default *Server
+)
+
+// Handle registers the handler of the pattern to all the admin servers, the
+// existing one of the pattern is replaced.
+func Handle(pattern string, h http.Handler) {
+	_mutex.Lock()
+	_handlers[pattern] = h
+	_mutex.Unlock()
+}
+
+// RegisterConfig registers a config which is dumped by /config, the name is
+// suffixed by a sequence if it exists. It returns a function which
+// unregisters the config, it should be called when the owner is closed.
+func RegisterConfig(name string, fn func() interface{}) (unregister func()) {
+	return register(_configs, name, fn)
+}
+
+// RegisterStats registers the stats which are dumped by /stats, the name is
+// suffixed by a sequence if it exists. It returns a function which
+// unregisters the stats, it should be called when the owner is closed.
+func RegisterStats(name string, fn func() interface{}) (unregister func()) {
+	return register(_stats, name, fn)
+}
+
+func register(m map[string]func() interface{}, name string, fn func() interface{}) func() {
+	_mutex.Lock()
+	name = uniqueName(m, name)
+	m[name] = fn
+	_mutex.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			_mutex.Lock()
+			delete(m, name)
+			_mutex.Unlock()
+		})
+	}
+}
+
+func uniqueName(m map[string]func() interface{}, name string) string {
+	n := name
+	for i := 1; ; i++ {
+		if _, ok := m[n]; !ok {
+			return n
+		}
+		n = name + "." + strconv.Itoa(i)
+	}
+}
+
+// New returns an admin server, it is not started.
+func New(c *Config) (*Server, error) {
+	if c == nil || (c.Token == "" && (c.TLS == nil || c.TLS.ClientCAFile == "")) {
+		return nil, errors.New("admin: token or tls client ca is required")
+	}
+	if c.Addr == "" {
+		c.Addr = "127.0.0.1:2333"
+	}
+	s := &Server{conf: c, mux: http.NewServeMux()}
+	s.mux.HandleFunc("/debug/pprof/", pprof.Index)
+	s.mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	s.mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	s.mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	s.mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	s.mux.Handle("/metrics", promhttp.Handler())
+	s.mux.HandleFunc("/config", s.config)
+	s.mux.HandleFunc("/stats", stats)
+	s.mux.HandleFunc("/log/level", logLevel)
+	s.mux.HandleFunc("/", registered)
+
+	s.server = &http.Server{Handler: s, ReadHeaderTimeout: 5 * time.Second}
+	if c.TLS != nil {
+		tlsConf := &tls.Config{MinVersion: tls.VersionTLS12}
+		if c.TLS.ClientCAFile != "" {
+			pem, err := ioutil.ReadFile(c.TLS.ClientCAFile)
+			if err != nil {
+				return nil, errors.Wrapf(err, "admin: read client ca: %s", c.TLS.ClientCAFile)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, errors.Errorf("admin: no certificate in client ca: %s", c.TLS.ClientCAFile)
+			}
+			tlsConf.ClientCAs = pool
+			tlsConf.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+		cert, err := tls.LoadX509KeyPair(c.TLS.CertFile, c.TLS.KeyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "admin: load certificate: %s", c.TLS.CertFile)
+		}
+		tlsConf.Certificates = []tls.Certificate{cert}
+		s.server.TLSConfig = tlsConf
+	}
+	return s, nil
+}
+
+// ServeHTTP authenticates the request by the token.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.conf.Token != "" {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.conf.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// Start listens and serves in a goroutine.
+func (s *Server) Start() error {
+	l, err := net.Listen("tcp", s.conf.Addr)
+	if err != nil {
+		return errors.Wrapf(err, "admin: listen tcp: %s", s.conf.Addr)
+	}
+	log.Infof("admin: start http listen addr: %s", l.Addr())
+	go func() {
+		var err error
+		if s.server.TLSConfig != nil {
+			err = s.server.ServeTLS(l, "", "")
+		} else {
+			err = s.server.Serve(l)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Errorf("admin: serve %s error(%v)", s.conf.Addr, err)
+		}
+	}()
+	return nil
+}
+
+// Shutdown stops the server gracefully.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+// Init starts the default admin server, it can be called only once.
+func Init(c *Config) error {
+	_mutex.Lock()
+	defer _mutex.Unlock()
+	if _default != nil {
+		return errors.New("admin: already initialized")
+	}
+	s, err := New(c)
+	if err != nil {
+		return err
+	}
+	if err = s.Start(); err != nil {
+		return err
+	}
+	_default = s
+	return nil
+}
+
+// Enabled reports whether the default admin server is started, the debug
+// endpoints should not be served elsewhere then.
+func Enabled() bool {
+	_mutex.RLock()
+	defer _mutex.RUnlock()
+	return _default != nil
+}
+
+// registered serves the handlers registered by Handle.
+func registered(w http.ResponseWriter, r *http.Request) {
+	_mutex.RLock()
+	h, ok := _handlers[r.URL.Path]
+	_mutex.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	h.ServeHTTP(w, r)
+}
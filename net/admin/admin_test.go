@@ -0,0 +1,94 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gisvr/golib/log"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(s *Server, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	return w
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(&Config{})
+	assert.Error(t, err, "the auth is required")
+	_, err = New(&Config{Token: "t", TLS: &TLSConfig{CertFile: "none.pem", KeyFile: "none.key"}})
+	assert.Error(t, err)
+}
+
+func TestAuth(t *testing.T) {
+	s, err := New(&Config{Token: "secret"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, serve(s, "GET", "/metrics", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(s, "GET", "/metrics", "other").Code)
+	assert.Equal(t, http.StatusOK, serve(s, "GET", "/metrics", "secret").Code)
+	assert.Equal(t, http.StatusOK, serve(s, "GET", "/debug/pprof/", "secret").Code)
+	assert.Equal(t, http.StatusNotFound, serve(s, "GET", "/none", "secret").Code)
+
+	Handle("/hello", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("hello")) }))
+	assert.Equal(t, "hello", serve(s, "GET", "/hello", "secret").Body.String())
+}
+
+func TestConfigStats(t *testing.T) {
+	type db struct {
+		DSN      string
+		Password string `json:"password"`
+	}
+	defer RegisterConfig("db", func() interface{} { return &db{DSN: "mysql", Password: "p"} })()
+	defer RegisterConfig("db", func() interface{} {
+		return map[string]interface{}{"apiKey": "k", "list": []interface{}{map[string]string{"token": "t"}}}
+	})()
+	unregister := RegisterStats("counter", func() interface{} { return 1 })
+	s, _ := New(&Config{Token: "secret", MaskKeys: []string{"dsn"}})
+
+	var v map[string]interface{}
+	assert.NoError(t, json.Unmarshal(serve(s, "GET", "/config", "secret").Body.Bytes(), &v))
+	assert.Equal(t, map[string]interface{}{"DSN": _masked, "password": _masked}, v["db"])
+	assert.Equal(t, map[string]interface{}{"apiKey": _masked, "list": []interface{}{map[string]interface{}{"token": _masked}}}, v["db.1"])
+
+	assert.NoError(t, json.Unmarshal(serve(s, "GET", "/stats", "secret").Body.Bytes(), &v))
+	assert.Equal(t, float64(1), v["counter"])
+
+	unregister()
+	unregister()
+	v = nil
+	assert.NoError(t, json.Unmarshal(serve(s, "GET", "/stats", "secret").Body.Bytes(), &v))
+	assert.NotContains(t, v, "counter")
+}
+
+func TestLogLevel(t *testing.T) {
+	defer log.Revert()
+	s, _ := New(&Config{Token: "secret"})
+	assert.Equal(t, http.StatusBadRequest, serve(s, "PUT", "/log/level?level=none", "secret").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(s, "PUT", "/log/level?module=dao", "secret").Code)
+	w := serve(s, "PUT", "/log/level?level=warn&v=3&module=dao*=5&revert=1m", "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var v map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &v))
+	assert.Equal(t, "WARN", v["level"])
+	assert.Equal(t, float64(3), v["v"])
+	assert.Equal(t, map[string]interface{}{"dao*": float64(5)}, v["module"])
+	assert.NotEmpty(t, v["revertAt"])
+	assert.Equal(t, log.WarnLevel, log.GetLevel())
+
+	// the level only override keeps the V level.
+	v = nil
+	assert.NoError(t, json.Unmarshal(serve(s, "PUT", "/log/level?level=error", "secret").Body.Bytes(), &v))
+	assert.Equal(t, float64(3), v["v"])
+
+	w = serve(s, "DELETE", "/log/level", "secret")
+	assert.JSONEq(t, `{"level":"DEBUG","v":0,"module":{}}`, w.Body.String())
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(s, "PATCH", "/log/level", "secret").Code)
+}
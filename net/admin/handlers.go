@@ -0,0 +1,150 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gisvr/golib/log"
+
+	"github.com/pkg/errors"
+)
+
+const _masked = "***"
+
+var _maskKeys = []string{"password", "passwd", "secret", "token", "key", "credential"}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// dump calls the registered funcs.
+func dump(fns map[string]func() interface{}) map[string]interface{} {
+	_mutex.RLock()
+	defer _mutex.RUnlock()
+	res := make(map[string]interface{}, len(fns))
+	for name, fn := range fns {
+		res[name] = fn()
+	}
+	return res
+}
+
+// config dumps the registered configs, the values of the secret keys are masked.
+func (s *Server) config(w http.ResponseWriter, r *http.Request) {
+	bs, err := json.Marshal(dump(_configs))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var v interface{}
+	if err = json.Unmarshal(bs, &v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	keys := append(_maskKeys[:len(_maskKeys):len(_maskKeys)], s.conf.MaskKeys...)
+	writeJSON(w, mask(v, keys))
+}
+
+// mask replaces the values of the keys which contain any of keys.
+func mask(v interface{}, keys []string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if secret(k, keys) && e != nil {
+				v[k] = _masked
+				continue
+			}
+			v[k] = mask(e, keys)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = mask(e, keys)
+		}
+	}
+	return v
+}
+
+func secret(name string, keys []string) bool {
+	name = strings.ToLower(name)
+	for _, k := range keys {
+		if strings.Contains(name, strings.ToLower(k)) {
+			return true
+		}
+	}
+	return false
+}
+
+// stats dumps the registered stats.
+func stats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, dump(_stats))
+}
+
+// logLevel gets, overrides or reverts the log settings, eg:
+// PUT /log/level?level=debug&v=3&module=dao*=5,service=2&revert=30m
+// the override is reverted after the revert duration, default 10m.
+func logLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		rt, err := parseRuntime(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err = log.Override(rt); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Warnf("admin: log is overridden by %s", r.RemoteAddr)
+	case http.MethodDelete:
+		log.Revert()
+		log.Warnf("admin: log is reverted by %s", r.RemoteAddr)
+	default:
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	rt, revertAt := log.Current()
+	res := map[string]interface{}{"level": rt.Level, "v": rt.V, "module": rt.Module}
+	if !revertAt.IsZero() {
+		res["revertAt"] = revertAt
+	}
+	writeJSON(w, res)
+}
+
+func parseRuntime(r *http.Request) (*log.Runtime, error) {
+	rt := &log.Runtime{Level: r.FormValue("level")}
+	if v := r.FormValue("v"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return nil, errors.Errorf("admin: invalid v: %s", v)
+		}
+		nv := int32(n)
+		rt.V = &nv
+	}
+	if m := r.FormValue("module"); m != "" {
+		rt.Module = make(map[string]int32)
+		for _, kv := range strings.Split(m, ",") {
+			i := strings.LastIndexByte(kv, '=')
+			if i <= 0 {
+				return nil, errors.Errorf("admin: invalid module: %s", kv)
+			}
+			n, err := strconv.ParseInt(kv[i+1:], 10, 32)
+			if err != nil {
+				return nil, errors.Errorf("admin: invalid module: %s", kv)
+			}
+			rt.Module[strings.TrimSpace(kv[:i])] = int32(n)
+		}
+	}
+	if d := r.FormValue("revert"); d != "" {
+		if err := rt.Revert.UnmarshalText([]byte(d)); err != nil {
+			return nil, errors.Errorf("admin: invalid revert: %s", d)
+		}
+	}
+	return rt, nil
+}
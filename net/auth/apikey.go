@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// APIKey is the identity and grants of an api key.
+type APIKey struct {
+	ID          string
+	Mid         int64
+	Roles       []string
+	Permissions []string
+}
+
+// APIKeyConfig is the api key verifier config.
+type APIKeyConfig struct {
+	// Header carries the api key, "X-Api-Key" by default.
+	Header string
+	// Query carries the api key if the header is empty, it is disabled by default
+	// because the query string is usually logged.
+	Query string
+	// Keys is the map of the hex encoded sha256 of the api key to its identity,
+	// so the plain keys are never kept in the config.
+	Keys map[string]*APIKey
+}
+
+// APIKeys verifies the api key of the request.
+type APIKeys struct {
+	conf *APIKeyConfig
+}
+
+var _ Verifier = &APIKeys{}
+
+// NewAPIKeys returns an api key verifier.
+func NewAPIKeys(c *APIKeyConfig) *APIKeys {
+	if c.Header == "" {
+		c.Header = "X-Api-Key"
+	}
+	return &APIKeys{conf: c}
+}
+
+// HashAPIKey returns the hex encoded sha256 of the api key used by APIKeyConfig.Keys.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Scheme returns apikey.
+func (a *APIKeys) Scheme() string {
+	return "apikey"
+}
+
+// Verify looks up the api key of the request.
+func (a *APIKeys) Verify(ctx context.Context, req Request) (*Principal, error) {
+	key := req.Header(a.conf.Header)
+	if key == "" && a.conf.Query != "" {
+		key = req.Params().Get(a.conf.Query)
+	}
+	if key == "" {
+		return nil, ErrNoCredential
+	}
+	k, ok := a.conf.Keys[HashAPIKey(key)]
+	if !ok {
+		return nil, unauthorized("auth: invalid api key")
+	}
+	return &Principal{ID: k.ID, Mid: k.Mid, Roles: k.Roles, Permissions: k.Permissions}, nil
+}
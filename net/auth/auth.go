@@ -0,0 +1,139 @@
+// Package auth provides request authentication and authorization shared by
+// the blademaster and warden servers.
+package auth
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/gisvr/golib/ecode"
+	"github.com/gisvr/golib/net/metadata"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNoCredential is returned by a Verifier when the request does not carry
+// the credential of its kind, then the next verifier will be tried.
+var ErrNoCredential = errors.New("auth: no credential")
+
+// Principal is the authenticated identity of a request.
+type Principal struct {
+	// Scheme is the scheme of the verifier which authenticated the principal, eg: hmac, jwt, apikey.
+	Scheme string
+	// ID is the identity of the principal, eg: appkey, jwt subject or api key id.
+	ID string
+	// Mid is the member id of the principal if it is a user.
+	Mid int64
+	// Roles and Permissions are checked by the Policy.
+	Roles       []string
+	Permissions []string
+	// Claims is the raw claims of a jwt.
+	Claims map[string]interface{}
+}
+
+// HasRole reports whether the principal has the role.
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission reports whether the principal has the permission.
+// A granted permission ending with "*" matches all the permissions with the prefix,
+// eg: "order.*" matches "order.read" and "order.write".
+func (p *Principal) HasPermission(perm string) bool {
+	for _, g := range p.Permissions {
+		if g == perm || g == "*" {
+			return true
+		}
+		if strings.HasSuffix(g, "*") && strings.HasPrefix(perm, g[:len(g)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Request is the transport independent view of a request being authenticated.
+type Request interface {
+	// Method is the http method, it is always POST for grpc.
+	Method() string
+	// Path is the http path or the grpc full method.
+	Path() string
+	// Header returns the http header or the grpc metadata value of the key.
+	Header(key string) string
+	// Params returns the http query and form params, it is nil for grpc.
+	Params() url.Values
+}
+
+// BodyRequest is a Request whose body is not carried by Params, e.g. a json
+// body, the HMAC verifier checks it against the signed content-sha256 param.
+type BodyRequest interface {
+	Request
+	// Body returns the raw body, it is nil if the body is empty or carried by Params.
+	Body() ([]byte, error)
+}
+
+// Verifier verifies the credential carried by a request.
+type Verifier interface {
+	// Scheme returns the name of the verifier.
+	Scheme() string
+	// Verify returns ErrNoCredential if the request has no credential of
+	// this scheme, or ecode.Unauthorized if the credential is invalid.
+	Verify(ctx context.Context, req Request) (*Principal, error)
+}
+
+// Authenticator authenticates requests by the verifiers in order.
+type Authenticator struct {
+	verifiers []Verifier
+}
+
+// New returns an Authenticator with the verifiers.
+func New(verifiers ...Verifier) *Authenticator {
+	return &Authenticator{verifiers: verifiers}
+}
+
+// Authenticate returns the principal of the first verifier which the request
+// has a credential for. ErrNoCredential is returned if the request carries no credential.
+func (a *Authenticator) Authenticate(ctx context.Context, req Request) (*Principal, error) {
+	for _, v := range a.verifiers {
+		p, err := v.Verify(ctx, req)
+		if err == ErrNoCredential {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.Scheme = v.Scheme()
+		return p, nil
+	}
+	return nil, ErrNoCredential
+}
+
+// NewContext returns a copy of ctx with the principal and its mid stored in the metadata.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	md, ok := metadata.FromContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	md[metadata.Principal] = p
+	if p.Mid > 0 {
+		md[metadata.Mid] = p.Mid
+	}
+	return metadata.NewContext(ctx, md)
+}
+
+// FromContext returns the principal stored in the metadata of ctx.
+func FromContext(ctx context.Context) (p *Principal, ok bool) {
+	p, ok = metadata.Value(ctx, metadata.Principal).(*Principal)
+	return
+}
+
+func unauthorized(format string, args ...interface{}) error {
+	return ecode.Errorf(ecode.Unauthorized, format, args...)
+}
@@ -0,0 +1,349 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gisvr/golib/ecode"
+	"github.com/gisvr/golib/net/metadata"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testRequest struct {
+	method, path string
+	header       http.Header
+	params       url.Values
+}
+
+func (r *testRequest) Method() string           { return r.method }
+func (r *testRequest) Path() string             { return r.path }
+func (r *testRequest) Header(key string) string { return r.header.Get(key) }
+func (r *testRequest) Params() url.Values       { return r.params }
+
+type testBodyRequest struct {
+	*testRequest
+	body []byte
+}
+
+func (r *testBodyRequest) Body() ([]byte, error) { return r.body, nil }
+
+func newTestRequest() *testRequest {
+	return &testRequest{method: "GET", path: "/x/user", header: http.Header{}, params: url.Values{}}
+}
+
+func TestPolicy(t *testing.T) {
+	admin := &Principal{Scheme: "jwt", Roles: []string{"admin"}, Permissions: []string{"order.*"}}
+	user := &Principal{Scheme: "hmac", Roles: []string{"user"}, Permissions: []string{"user.read"}}
+	tests := []struct {
+		policy       string
+		anon         bool
+		admin, user  bool
+		anonErr      error
+		principalErr error
+	}{
+		{"anonymous", true, true, true, nil, nil},
+		{"authenticated", false, true, true, ecode.Unauthorized, nil},
+		{"role:admin", false, true, false, ecode.Unauthorized, ecode.AccessDenied},
+		{"perm:order.write || perm:user.read", false, true, true, ecode.Unauthorized, nil},
+		{"authenticated && !scheme:hmac", false, true, false, ecode.Unauthorized, ecode.AccessDenied},
+		{"(role:user || role:admin) && perm:order.read", false, true, false, ecode.Unauthorized, ecode.AccessDenied},
+	}
+	for _, test := range tests {
+		p, err := ParsePolicy(test.policy)
+		assert.NoError(t, err, test.policy)
+		assert.Equal(t, test.anon, p.Allow(nil), test.policy)
+		assert.Equal(t, test.admin, p.Allow(admin), test.policy)
+		assert.Equal(t, test.user, p.Allow(user), test.policy)
+		assert.Equal(t, test.anonErr, p.Check(nil), test.policy)
+		assert.Equal(t, test.principalErr, p.Check(user), test.policy)
+	}
+	for _, bad := range []string{"", "role:", "admin", "role:a &&", "(role:a", "role:a role:b", "role:a & role:b"} {
+		_, err := ParsePolicy(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestHMAC(t *testing.T) {
+	h := NewHMAC(&HMACConfig{Apps: map[string]*App{"app": {Secret: "secret", Roles: []string{"internal"}}}})
+	ctx := context.Background()
+
+	req := newTestRequest()
+	_, err := h.Verify(ctx, req)
+	assert.Equal(t, ErrNoCredential, err)
+
+	req.params.Set("mid", "1")
+	req.params.Set(ParamAppKey, "app")
+	req.params.Set(ParamTs, strconv.FormatInt(time.Now().Unix(), 10))
+	req.params.Set(ParamSign, Sign("secret", req.method, req.path, req.params))
+	_, err = h.Verify(ctx, req)
+	assert.Error(t, err, "nonce required")
+
+	req.params.Set(ParamNonce, "n1")
+	req.params.Set(ParamSign, Sign("secret", req.method, req.path, req.params))
+	p, err := h.Verify(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, "app", p.ID)
+	assert.True(t, p.HasRole("internal"))
+
+	req.params.Set("mid", "2")
+	_, err = h.Verify(ctx, req)
+	assert.Equal(t, ecode.Unauthorized.Code(), ecode.Cause(err).Code())
+
+	// the sign params in headers.
+	req = newTestRequest()
+	req.header.Set(HeaderAppKey, "app")
+	req.header.Set(HeaderNonce, "n2")
+	req.header.Set(HeaderTs, strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+	req.header.Set(HeaderSign, Sign("secret", req.method, req.path, SignParams(req)))
+	_, err = h.Verify(ctx, req)
+	assert.Error(t, err, "ts expired")
+
+	req.header.Set(HeaderTs, strconv.FormatInt(time.Now().Unix(), 10))
+	req.header.Set(HeaderSign, Sign("secret", req.method, req.path, SignParams(req)))
+	_, err = h.Verify(ctx, req)
+	assert.NoError(t, err)
+	_, err = h.Verify(ctx, req)
+	assert.Error(t, err, "replayed request")
+
+	// the nonce is optional if disabled.
+	h2 := NewHMAC(&HMACConfig{Apps: h.conf.Apps, DisableNonce: true})
+	req = newTestRequest()
+	req.params.Set(ParamAppKey, "app")
+	req.params.Set(ParamTs, strconv.FormatInt(time.Now().Unix(), 10))
+	req.params.Set(ParamSign, Sign("secret", req.method, req.path, req.params))
+	_, err = h2.Verify(ctx, req)
+	assert.NoError(t, err)
+}
+
+func TestHMACBody(t *testing.T) {
+	h := NewHMAC(&HMACConfig{Apps: map[string]*App{"app": {Secret: "secret"}}})
+	ctx := context.Background()
+	sign := func(req *testBodyRequest, nonce string, hash string) {
+		req.header.Set(HeaderAppKey, "app")
+		req.header.Set(HeaderTs, strconv.FormatInt(time.Now().Unix(), 10))
+		req.header.Set(HeaderNonce, nonce)
+		if hash != "" {
+			req.header.Set(HeaderContentSHA256, hash)
+		}
+		req.header.Set(HeaderSign, Sign("secret", req.method, req.path, SignParams(req)))
+	}
+
+	body := []byte(`{"mid":1}`)
+	req := &testBodyRequest{testRequest: newTestRequest(), body: body}
+	req.method = "POST"
+	sign(req, "n1", ContentSHA256(body))
+	_, err := h.Verify(ctx, req)
+	assert.NoError(t, err)
+
+	// the body is tampered.
+	req.body = []byte(`{"mid":2}`)
+	sign(req, "n2", ContentSHA256(body))
+	_, err = h.Verify(ctx, req)
+	assert.Error(t, err, "content-sha256 mismatch")
+
+	// the body is not signed.
+	req = &testBodyRequest{testRequest: newTestRequest(), body: body}
+	req.method = "POST"
+	sign(req, "n3", "")
+	_, err = h.Verify(ctx, req)
+	assert.Error(t, err, "content-sha256 required")
+
+	// the streamed body is not verified.
+	req = &testBodyRequest{testRequest: newTestRequest(), body: body}
+	req.method = "POST"
+	sign(req, "n4", UnsignedPayload)
+	_, err = h.Verify(ctx, req)
+	assert.NoError(t, err)
+
+	hash, err := ContentSHA256Reader(bytes.NewReader(body))
+	assert.NoError(t, err)
+	assert.Equal(t, ContentSHA256(body), hash)
+}
+
+func TestAPIKeys(t *testing.T) {
+	a := NewAPIKeys(&APIKeyConfig{Query: "api_key", Keys: map[string]*APIKey{
+		HashAPIKey("key1"): {ID: "ops", Mid: 10, Roles: []string{"ops"}},
+	}})
+	ctx := context.Background()
+
+	req := newTestRequest()
+	_, err := a.Verify(ctx, req)
+	assert.Equal(t, ErrNoCredential, err)
+
+	req.header.Set("X-Api-Key", "key1")
+	p, err := a.Verify(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), p.Mid)
+
+	req = newTestRequest()
+	req.params.Set("api_key", "key2")
+	_, err = a.Verify(ctx, req)
+	assert.Error(t, err)
+}
+
+func encodeSegment(v interface{}) string {
+	bs, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(bs)
+}
+
+func signJWT(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+	signed := encodeSegment(map[string]string{"alg": alg, "typ": "JWT", "kid": kid}) + "." + encodeSegment(claims)
+	hash := _jwtHashes[alg[2:]]
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(hash.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		h := hash.New()
+		h.Write([]byte(signed))
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.Hash(hash), h.Sum(nil)); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		h := hash.New()
+		h.Write([]byte(signed))
+		r, s, err := ecdsa.Sign(rand.Reader, k, h.Sum(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		sig = make([]byte, 2*size)
+		r.FillBytes(sig[:size])
+		s.FillBytes(sig[size:])
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func b64(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func TestJWT(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	jwks := map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa1", "n": b64(rsaKey.N), "e": b64(big.NewInt(int64(rsaKey.E)))},
+		{"kty": "EC", "kid": "ec1", "crv": "P-256", "x": b64(ecKey.X), "y": b64(ecKey.Y)},
+	}}
+	f, err := ioutil.TempFile("", "jwks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	json.NewEncoder(f).Encode(jwks)
+	f.Close()
+
+	j, err := NewJWT(&JWTConfig{Secret: "hs-secret", JWKSFile: f.Name(), Issuer: "kratos", Audience: "api"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	claims := map[string]interface{}{
+		"sub":   "user-1",
+		"mid":   int64(9007199254740993),
+		"iss":   "kratos",
+		"aud":   []string{"api", "web"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{"admin"},
+		"perms": "order.read order.write",
+	}
+	ctx := context.Background()
+	for _, test := range []struct {
+		alg, kid string
+		key      interface{}
+	}{
+		{"HS256", "", []byte("hs-secret")},
+		{"RS256", "rsa1", rsaKey},
+		{"RS512", "", rsaKey},
+		{"ES256", "ec1", ecKey},
+	} {
+		req := newTestRequest()
+		req.header.Set("Authorization", "Bearer "+signJWT(t, test.alg, test.kid, test.key, claims))
+		p, err := j.Verify(ctx, req)
+		if !assert.NoError(t, err, test.alg) {
+			continue
+		}
+		assert.Equal(t, "user-1", p.ID)
+		assert.Equal(t, int64(9007199254740993), p.Mid)
+		assert.True(t, p.HasRole("admin"))
+		assert.True(t, p.HasPermission("order.write"))
+	}
+
+	req := newTestRequest()
+	_, err = j.Verify(ctx, req)
+	assert.Equal(t, ErrNoCredential, err)
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	req.header.Set("Authorization", "Bearer "+signJWT(t, "RS256", "rsa1", otherKey, claims))
+	_, err = j.Verify(ctx, req)
+	assert.Error(t, err, "signature mismatch")
+
+	claims["exp"] = time.Now().Add(-time.Hour).Unix()
+	req.header.Set("Authorization", "Bearer "+signJWT(t, "HS256", "", []byte("hs-secret"), claims))
+	_, err = j.Verify(ctx, req)
+	assert.Error(t, err, "expired")
+
+	delete(claims, "exp")
+	claims["aud"] = "web"
+	req.header.Set("Authorization", "Bearer "+signJWT(t, "HS256", "", []byte("hs-secret"), claims))
+	_, err = j.Verify(ctx, req)
+	assert.Error(t, err, "audience mismatch")
+}
+
+func TestAuthenticator(t *testing.T) {
+	a := New(
+		NewAPIKeys(&APIKeyConfig{Keys: map[string]*APIKey{HashAPIKey("key"): {ID: "ops", Mid: 7}}}),
+		NewHMAC(&HMACConfig{}),
+	)
+	ctx := metadata.NewContext(context.Background(), metadata.MD{metadata.RemoteIP: "127.0.0.1"})
+
+	req := newTestRequest()
+	_, err := a.Authenticate(ctx, req)
+	assert.Equal(t, ErrNoCredential, err)
+
+	req.header.Set("X-Api-Key", "key")
+	p, err := a.Authenticate(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, "apikey", p.Scheme)
+
+	nctx := NewContext(ctx, p)
+	pp, ok := FromContext(nctx)
+	assert.True(t, ok)
+	assert.Equal(t, p, pp)
+	assert.Equal(t, int64(7), metadata.Int64(nctx, metadata.Mid))
+	assert.Equal(t, "127.0.0.1", metadata.String(nctx, metadata.RemoteIP))
+	_, ok = FromContext(ctx)
+	assert.False(t, ok)
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	s := NewMemoryNonceStore()
+	ctx := context.Background()
+	seen, err := s.Seen(ctx, "app:1", 20*time.Millisecond)
+	assert.NoError(t, err)
+	assert.False(t, seen)
+	seen, _ = s.Seen(ctx, "app:1", 20*time.Millisecond)
+	assert.True(t, seen)
+	time.Sleep(30 * time.Millisecond)
+	seen, _ = s.Seen(ctx, "app:1", 20*time.Millisecond)
+	assert.False(t, seen)
+}
@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	xtime "github.com/gisvr/golib/time"
+
+	"github.com/pkg/errors"
+)
+
+// the params of a signed request, they can also be carried by the headers.
+const (
+	ParamAppKey = "appkey"
+	ParamTs     = "ts"
+	ParamNonce  = "nonce"
+	ParamSign   = "sign"
+	// ParamContentSHA256 is the hex encoded sha256 of the body which is not
+	// carried by the params, e.g. a json body.
+	ParamContentSHA256 = "content-sha256"
+
+	HeaderAppKey        = "X-Auth-Appkey"
+	HeaderTs            = "X-Auth-Ts"
+	HeaderNonce         = "X-Auth-Nonce"
+	HeaderSign          = "X-Auth-Sign"
+	HeaderContentSHA256 = "X-Auth-Content-Sha256"
+)
+
+// UnsignedPayload is the content-sha256 of a streamed body which is not
+// hashed by the signer, the body of such a request is not verified.
+const UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+const _defaultSkew = xtime.Duration(5 * time.Minute)
+
+var _signHeaders = map[string]string{
+	ParamAppKey: HeaderAppKey,
+	ParamTs:     HeaderTs,
+	ParamNonce:  HeaderNonce,
+	ParamSign:   HeaderSign,
+
+	ParamContentSHA256: HeaderContentSHA256,
+}
+
+// App is a caller which signs its requests by the secret.
+type App struct {
+	Secret      string
+	Roles       []string
+	Permissions []string
+}
+
+// HMACConfig is the hmac signed request verifier config.
+type HMACConfig struct {
+	// Apps is the appkey to app map.
+	Apps map[string]*App
+	// Skew is the max clock skew between the caller and the server, 5m by default.
+	Skew xtime.Duration
+	// DisableNonce accepts the requests without a nonce, which can be replayed
+	// within the skew. A nonce is always checked against replays if it is present.
+	DisableNonce bool
+}
+
+// HMAC verifies the requests signed by Sign.
+type HMAC struct {
+	conf   *HMACConfig
+	nonces NonceStore
+}
+
+var _ Verifier = &HMAC{}
+
+// NewHMAC returns a hmac signed request verifier.
+func NewHMAC(c *HMACConfig) *HMAC {
+	if c.Skew <= 0 {
+		c.Skew = _defaultSkew
+	}
+	return &HMAC{conf: c, nonces: NewMemoryNonceStore()}
+}
+
+// SetNonceStore sets the store of the nonces, an in process store is used by default.
+func (h *HMAC) SetNonceStore(s NonceStore) {
+	h.nonces = s
+}
+
+// Scheme returns hmac.
+func (h *HMAC) Scheme() string {
+	return "hmac"
+}
+
+// Verify verifies the signature, the timestamp and the nonce of the request,
+// and the body hash if it is a BodyRequest.
+func (h *HMAC) Verify(ctx context.Context, req Request) (*Principal, error) {
+	params := SignParams(req)
+	appkey, sign := params.Get(ParamAppKey), params.Get(ParamSign)
+	if appkey == "" || sign == "" {
+		return nil, ErrNoCredential
+	}
+	app, ok := h.conf.Apps[appkey]
+	if !ok {
+		return nil, unauthorized("auth: unknown appkey %s", appkey)
+	}
+	ts, err := strconv.ParseInt(params.Get(ParamTs), 10, 64)
+	if err != nil {
+		return nil, unauthorized("auth: invalid ts")
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > time.Duration(h.conf.Skew) || -skew > time.Duration(h.conf.Skew) {
+		return nil, unauthorized("auth: ts expired")
+	}
+	expect := Sign(app.Secret, req.Method(), req.Path(), params)
+	if !hmac.Equal([]byte(expect), []byte(sign)) {
+		return nil, unauthorized("auth: sign mismatch")
+	}
+	hash := params.Get(ParamContentSHA256)
+	if br, ok := req.(BodyRequest); ok && hash != UnsignedPayload {
+		body, err := br.Body()
+		if err != nil {
+			return nil, unauthorized("auth: read body error: %v", err)
+		}
+		if len(body) > 0 && hash == "" {
+			return nil, unauthorized("auth: content-sha256 required")
+		}
+		if hash != "" && !hmac.Equal([]byte(ContentSHA256(body)), []byte(strings.ToLower(hash))) {
+			return nil, unauthorized("auth: content-sha256 mismatch")
+		}
+	}
+	nonce := params.Get(ParamNonce)
+	if nonce == "" && !h.conf.DisableNonce {
+		return nil, unauthorized("auth: nonce required")
+	}
+	if nonce != "" {
+		// a replayed request must be within the skew window on both sides of now.
+		seen, err := h.nonces.Seen(ctx, appkey+":"+nonce, 2*time.Duration(h.conf.Skew))
+		if err != nil {
+			return nil, err
+		}
+		if seen {
+			return nil, unauthorized("auth: replayed request")
+		}
+	}
+	return &Principal{ID: appkey, Roles: app.Roles, Permissions: app.Permissions}, nil
+}
+
+// SignParams returns the params of the request with the sign params
+// filled from the headers if they are not in the params.
+func SignParams(req Request) url.Values {
+	params := url.Values{}
+	for k, v := range req.Params() {
+		params[k] = v
+	}
+	for p, h := range _signHeaders {
+		if params.Get(p) != "" {
+			continue
+		}
+		if v := req.Header(h); v != "" {
+			params.Set(p, v)
+		}
+	}
+	return params
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of the string:
+//
+//	METHOD + "\n" + path + "\n" + sorted url encoded params without sign
+//
+// The params should include appkey, ts, nonce and content-sha256 if the body
+// is not carried by the params.
+func Sign(secret, method, path string, params url.Values) string {
+	sorted := make(url.Values, len(params))
+	for k, v := range params {
+		if k != ParamSign {
+			sorted[k] = v
+		}
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.ToUpper(method)))
+	mac.Write([]byte("\n"))
+	mac.Write([]byte(path))
+	mac.Write([]byte("\n"))
+	// NOTE Encode sorts the params by key.
+	mac.Write([]byte(sorted.Encode()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ContentSHA256 returns the hex encoded sha256 of the body for the
+// content-sha256 param.
+func ContentSHA256(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// ContentSHA256Reader returns the hex encoded sha256 of the body read from r.
+func ContentSHA256Reader(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", errors.WithStack(err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
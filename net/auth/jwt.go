@@ -0,0 +1,324 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256" // register the hash functions of the algs
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	xtime "github.com/gisvr/golib/time"
+
+	"github.com/pkg/errors"
+)
+
+var _jwtHashes = map[string]crypto.Hash{
+	"256": crypto.SHA256,
+	"384": crypto.SHA384,
+	"512": crypto.SHA512,
+}
+
+// JWTConfig is the jwt verifier config.
+type JWTConfig struct {
+	// Secret is the key of the HS256, HS384 and HS512 tokens.
+	Secret string
+	// JWKSFile is the local JSON Web Key Set file of the RSA and EC public keys,
+	// which verifies the RS256, RS384, RS512, ES256, ES384 and ES512 tokens.
+	JWKSFile string
+	// Issuer and Audience are checked if they are not empty.
+	Issuer   string
+	Audience string
+	// Leeway is the tolerance of the exp and nbf claims.
+	Leeway xtime.Duration
+	// MidClaim, RolesClaim and PermissionsClaim are the claim names of
+	// the principal, "mid", "roles" and "perms" by default.
+	MidClaim         string
+	RolesClaim       string
+	PermissionsClaim string
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type publicKey struct {
+	kid string
+	alg string
+	key interface{} // *rsa.PublicKey or *ecdsa.PublicKey
+}
+
+// JWT verifies the bearer token of the request.
+type JWT struct {
+	conf *JWTConfig
+
+	mutex sync.RWMutex
+	keys  []*publicKey
+}
+
+var _ Verifier = &JWT{}
+
+// NewJWT returns a jwt verifier, the JWKSFile is loaded if it is set.
+func NewJWT(c *JWTConfig) (*JWT, error) {
+	if c.MidClaim == "" {
+		c.MidClaim = "mid"
+	}
+	if c.RolesClaim == "" {
+		c.RolesClaim = "roles"
+	}
+	if c.PermissionsClaim == "" {
+		c.PermissionsClaim = "perms"
+	}
+	j := &JWT{conf: c}
+	if c.JWKSFile != "" {
+		if err := j.Reload(); err != nil {
+			return nil, err
+		}
+	}
+	return j, nil
+}
+
+// Reload reloads the keys from the JWKSFile.
+func (j *JWT) Reload() error {
+	bs, err := ioutil.ReadFile(j.conf.JWKSFile)
+	if err != nil {
+		return errors.Wrapf(err, "auth: read jwks file: %s", j.conf.JWKSFile)
+	}
+	keys, err := parseJWKS(bs)
+	if err != nil {
+		return errors.Wrapf(err, "auth: parse jwks file: %s", j.conf.JWKSFile)
+	}
+	j.mutex.Lock()
+	j.keys = keys
+	j.mutex.Unlock()
+	return nil
+}
+
+func parseJWKS(bs []byte) (keys []*publicKey, err error) {
+	var set struct {
+		Keys []*jsonWebKey `json:"keys"`
+	}
+	if err = json.Unmarshal(bs, &set); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, k := range set.Keys {
+		pk := &publicKey{kid: k.Kid, alg: k.Alg}
+		switch k.Kty {
+		case "RSA":
+			n, e := decodeBigInt(k.N), decodeBigInt(k.E)
+			if n == nil || e == nil {
+				return nil, errors.Errorf("invalid rsa key: %s", k.Kid)
+			}
+			pk.key = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch k.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				return nil, errors.Errorf("unsupported curve: %s", k.Crv)
+			}
+			x, y := decodeBigInt(k.X), decodeBigInt(k.Y)
+			if x == nil || y == nil || !curve.IsOnCurve(x, y) {
+				return nil, errors.Errorf("invalid ec key: %s", k.Kid)
+			}
+			pk.key = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		default:
+			// the unsupported keys are ignored.
+			continue
+		}
+		keys = append(keys, pk)
+	}
+	return
+}
+
+func decodeBigInt(s string) *big.Int {
+	bs, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(bs) == 0 {
+		return nil
+	}
+	return new(big.Int).SetBytes(bs)
+}
+
+// Scheme returns jwt.
+func (j *JWT) Scheme() string {
+	return "jwt"
+}
+
+// Verify verifies the signature and the claims of the bearer token.
+func (j *JWT) Verify(ctx context.Context, req Request) (*Principal, error) {
+	const bearer = "Bearer "
+	h := req.Header("Authorization")
+	if len(h) <= len(bearer) || !strings.EqualFold(h[:len(bearer)], bearer) {
+		return nil, ErrNoCredential
+	}
+	parts := strings.Split(strings.TrimSpace(h[len(bearer):]), ".")
+	if len(parts) != 3 {
+		return nil, unauthorized("auth: malformed jwt")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, unauthorized("auth: malformed jwt header")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, unauthorized("auth: malformed jwt signature")
+	}
+	if err = j.verifySignature(header.Alg, header.Kid, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+	claims := make(map[string]interface{})
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return nil, unauthorized("auth: malformed jwt claims")
+	}
+	if err = j.verifyClaims(claims); err != nil {
+		return nil, err
+	}
+	p := &Principal{
+		Roles:       claimStrings(claims[j.conf.RolesClaim]),
+		Permissions: claimStrings(claims[j.conf.PermissionsClaim]),
+		Claims:      claims,
+	}
+	p.ID, _ = claims["sub"].(string)
+	if mid, ok := claims[j.conf.MidClaim].(json.Number); ok {
+		p.Mid, _ = mid.Int64()
+	}
+	return p, nil
+}
+
+func (j *JWT) verifySignature(alg, kid, signed string, sig []byte) error {
+	if len(alg) != 5 {
+		return unauthorized("auth: unsupported jwt alg %s", alg)
+	}
+	hash, ok := _jwtHashes[alg[2:]]
+	if !ok {
+		return unauthorized("auth: unsupported jwt alg %s", alg)
+	}
+	if alg[:2] == "HS" {
+		if j.conf.Secret == "" {
+			return unauthorized("auth: unsupported jwt alg %s", alg)
+		}
+		mac := hmac.New(hash.New, []byte(j.conf.Secret))
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), sig) {
+			return unauthorized("auth: jwt signature mismatch")
+		}
+		return nil
+	}
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+	j.mutex.RLock()
+	keys := j.keys
+	j.mutex.RUnlock()
+	for _, k := range keys {
+		if (kid != "" && k.kid != "" && k.kid != kid) || (k.alg != "" && k.alg != alg) {
+			continue
+		}
+		switch key := k.key.(type) {
+		case *rsa.PublicKey:
+			if alg[:2] == "RS" && rsa.VerifyPKCS1v15(key, hash, digest, sig) == nil {
+				return nil
+			}
+		case *ecdsa.PublicKey:
+			size := (key.Curve.Params().BitSize + 7) / 8
+			if alg[:2] == "ES" && len(sig) == 2*size {
+				r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+				if ecdsa.Verify(key, digest, r, s) {
+					return nil
+				}
+			}
+		}
+	}
+	return unauthorized("auth: jwt signature mismatch")
+}
+
+func (j *JWT) verifyClaims(claims map[string]interface{}) error {
+	now := time.Now()
+	leeway := time.Duration(j.conf.Leeway)
+	if exp, ok := claimTime(claims["exp"]); ok && now.After(exp.Add(leeway)) {
+		return unauthorized("auth: jwt expired")
+	}
+	if nbf, ok := claimTime(claims["nbf"]); ok && now.Add(leeway).Before(nbf) {
+		return unauthorized("auth: jwt not valid yet")
+	}
+	if j.conf.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != j.conf.Issuer {
+			return unauthorized("auth: jwt issuer mismatch")
+		}
+	}
+	if j.conf.Audience != "" {
+		found := false
+		for _, aud := range claimStrings(claims["aud"]) {
+			if aud == j.conf.Audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return unauthorized("auth: jwt audience mismatch")
+		}
+	}
+	return nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	bs, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func claimTime(v interface{}) (time.Time, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(f), 0), true
+}
+
+// claimStrings accepts a string array or a space separated string such as the scope claim.
+func claimStrings(v interface{}) (ss []string) {
+	switch vv := v.(type) {
+	case string:
+		ss = strings.Fields(vv)
+	case []interface{}:
+		for _, e := range vv {
+			if s, ok := e.(string); ok {
+				ss = append(ss, s)
+			}
+		}
+	}
+	return
+}
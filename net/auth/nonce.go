@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gisvr/golib/cache"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+// NonceStore records the nonces of the signed requests to detect replays.
+type NonceStore interface {
+	// Seen records the key for ttl and reports whether it has been recorded before.
+	Seen(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
+
+// memoryNonceStore is an in process NonceStore.
+type memoryNonceStore struct {
+	mutex  sync.Mutex
+	nonces map[string]time.Time
+	swept  time.Time
+}
+
+// NewMemoryNonceStore returns an in process NonceStore, it only detects the
+// replays against the same instance.
+func NewMemoryNonceStore() NonceStore {
+	return &memoryNonceStore{nonces: make(map[string]time.Time), swept: time.Now()}
+}
+
+func (s *memoryNonceStore) Seen(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if now.Sub(s.swept) > ttl {
+		for k, expire := range s.nonces {
+			if now.After(expire) {
+				delete(s.nonces, k)
+			}
+		}
+		s.swept = now
+	}
+	if expire, ok := s.nonces[key]; ok && now.Before(expire) {
+		return true, nil
+	}
+	s.nonces[key] = now.Add(ttl)
+	return false, nil
+}
+
+// redisNonceStore is a NonceStore shared by all the instances by redis.
+type redisNonceStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisNonceStore returns a NonceStore backed by redis, the default
+// client of the cache package is used if client is nil.
+func NewRedisNonceStore(client *redis.Client, prefix string) NonceStore {
+	if client == nil {
+		client = cache.Get()
+	}
+	if prefix == "" {
+		prefix = "auth_nonce_"
+	}
+	return &redisNonceStore{client: client, prefix: prefix}
+}
+
+func (s *redisNonceStore) Seen(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	ok, err := s.client.WithContext(ctx).SetNX(s.prefix+key, 1, ttl).Result()
+	if err != nil {
+		return false, errors.Wrapf(err, "auth: redis setnx %s", key)
+	}
+	return !ok, nil
+}
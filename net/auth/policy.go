@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/gisvr/golib/ecode"
+
+	"github.com/pkg/errors"
+)
+
+// Policy is an authorization rule parsed from the policy DSL.
+//
+// The DSL is a boolean expression of the terms below, combined by "&&",
+// "||", "!" and parentheses:
+//
+//	anonymous      any request, authenticated or not
+//	authenticated  any authenticated request
+//	role:NAME      the principal has the role NAME
+//	perm:NAME      the principal has the permission NAME
+//	scheme:NAME    the principal is authenticated by the verifier NAME
+//
+// eg: "role:admin || (perm:order.read && scheme:jwt)".
+type Policy struct {
+	raw  string
+	root expr
+}
+
+// ParsePolicy parses the policy DSL.
+func ParsePolicy(s string) (*Policy, error) {
+	p := &parser{tokens: tokenize(s)}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, errors.Wrapf(err, "auth: invalid policy %q", s)
+	}
+	if p.pos < len(p.tokens) {
+		return nil, errors.Errorf("auth: invalid policy %q: unexpected %q", s, p.tokens[p.pos])
+	}
+	return &Policy{raw: s, root: root}, nil
+}
+
+// MustPolicy is like ParsePolicy but panics if the policy is invalid.
+func MustPolicy(s string) *Policy {
+	p, err := ParsePolicy(s)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func (p *Policy) String() string {
+	return p.raw
+}
+
+// Allow reports whether the principal is allowed by the policy, the principal is nil for anonymous requests.
+func (p *Policy) Allow(pr *Principal) bool {
+	return p.root.eval(pr)
+}
+
+// Check returns ecode.Unauthorized if an anonymous request is not allowed,
+// or ecode.AccessDenied if the principal is not allowed.
+func (p *Policy) Check(pr *Principal) error {
+	if p.Allow(pr) {
+		return nil
+	}
+	if pr == nil {
+		return ecode.Unauthorized
+	}
+	return ecode.AccessDenied
+}
+
+type expr interface {
+	eval(p *Principal) bool
+}
+
+type (
+	orExpr   struct{ l, r expr }
+	andExpr  struct{ l, r expr }
+	notExpr  struct{ e expr }
+	termExpr struct{ kind, name string }
+)
+
+func (e orExpr) eval(p *Principal) bool  { return e.l.eval(p) || e.r.eval(p) }
+func (e andExpr) eval(p *Principal) bool { return e.l.eval(p) && e.r.eval(p) }
+func (e notExpr) eval(p *Principal) bool { return !e.e.eval(p) }
+
+func (e termExpr) eval(p *Principal) bool {
+	switch e.kind {
+	case "anonymous":
+		return true
+	case "authenticated":
+		return p != nil
+	}
+	if p == nil {
+		return false
+	}
+	switch e.kind {
+	case "role":
+		return p.HasRole(e.name)
+	case "perm":
+		return p.HasPermission(e.name)
+	case "scheme":
+		return p.Scheme == e.name
+	}
+	return false
+}
+
+func tokenize(s string) (tokens []string) {
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(' || c == ')' || c == '!':
+			tokens = append(tokens, s[i:i+1])
+			i++
+		case strings.HasPrefix(s[i:], "&&") || strings.HasPrefix(s[i:], "||"):
+			tokens = append(tokens, s[i:i+2])
+			i += 2
+		default:
+			j := i
+			for j < len(s) && isTermChar(rune(s[j])) {
+				j++
+			}
+			if j == i {
+				// an illegal character, leave it to the parser.
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		}
+	}
+	return
+}
+
+func isTermChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(":._-*/", r)
+}
+
+type parser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) parseOr() (expr, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "||" {
+		p.pos++
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = orExpr{l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "&&" {
+		p.pos++
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = andExpr{l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	tok := p.peek()
+	switch tok {
+	case "":
+		return nil, errors.New("unexpected end")
+	case "!":
+		p.pos++
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{e: e}, nil
+	case "(":
+		p.pos++
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, errors.New("missing )")
+		}
+		p.pos++
+		return e, nil
+	}
+	p.pos++
+	return parseTerm(tok)
+}
+
+func parseTerm(tok string) (expr, error) {
+	switch tok {
+	case "anonymous", "authenticated":
+		return termExpr{kind: tok}, nil
+	}
+	kv := strings.SplitN(tok, ":", 2)
+	if len(kv) != 2 || kv[1] == "" {
+		return nil, errors.Errorf("unknown term %q", tok)
+	}
+	switch kv[0] {
+	case "role", "perm", "scheme":
+		return termExpr{kind: kv[0], name: kv[1]}, nil
+	}
+	return nil, errors.Errorf("unknown term %q", tok)
+}
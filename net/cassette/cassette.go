@@ -0,0 +1,221 @@
+// Package cassette records the interactions with the downstream services to
+// a file and replays them in tests.
+package cassette
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Mode is the working mode of the cassette.
+type Mode int
+
+// the cassette modes.
+const (
+	// ModeReplay replays the recorded interactions, the unmatched requests fail.
+	ModeReplay Mode = iota
+	// ModeRecord sends the requests and records the interactions.
+	ModeRecord
+	// ModeAuto replays if the cassette file exists, otherwise records.
+	ModeAuto
+)
+
+// Match is the matching mode of the replayed requests.
+type Match int
+
+// the matching modes.
+const (
+	// MatchStrict replays the interactions in the recorded order, the method,
+	// url and body of the request must be equal to the recorded one, except
+	// the ts, nonce and sign params which differ in every signed request.
+	MatchStrict Match = iota
+	// MatchLenient replays the first unused interaction of the same method and
+	// path in any order, the last matched one is reused if all are used.
+	MatchLenient
+)
+
+// ErrNoInteraction is returned if no recorded interaction matches the request.
+var ErrNoInteraction = errors.New("cassette: no interaction matched")
+
+// Config is the cassette config.
+type Config struct {
+	// Path is the cassette file.
+	Path  string
+	Mode  Mode
+	Match Match
+	// RedactHeaders are the headers whose values are redacted, case insensitive.
+	// The Authorization, Cookie and the auth sign headers are always redacted
+	// unless DisableDefaultRedact is set.
+	RedactHeaders []string
+	// RedactFields are the json fields and form params whose values are
+	// redacted, in both the requests and the responses. The appkey, ts, nonce
+	// and sign params are always redacted unless DisableDefaultRedact is set.
+	RedactFields []string
+	// DisableDefaultRedact only redacts the RedactHeaders and RedactFields.
+	DisableDefaultRedact bool
+}
+
+// Request is the recorded request.
+type Request struct {
+	// Method is the http method or the grpc full method.
+	Method string      `json:"method"`
+	URL    string      `json:"url,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+	// Base64 reports whether the body is binary in base64.
+	Base64 bool `json:"base64,omitempty"`
+}
+
+// Response is the recorded response.
+type Response struct {
+	// Status is the http status or the grpc code.
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+	Base64 bool        `json:"base64,omitempty"`
+	// Error is the grpc status in base64 protobuf.
+	Error string `json:"error,omitempty"`
+}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request  *Request  `json:"request"`
+	Response *Response `json:"response"`
+}
+
+// Cassette records and replays the interactions.
+type Cassette struct {
+	conf     *Config
+	redactor *redactor
+	mode     Mode
+
+	mutex        sync.Mutex
+	interactions []*Interaction
+	used         []bool
+	next         int
+}
+
+// New returns a cassette, the interactions are loaded from the file in the replay mode.
+func New(c *Config) (*Cassette, error) {
+	headers, fields := c.RedactHeaders, c.RedactFields
+	if !c.DisableDefaultRedact {
+		headers = append(append([]string{}, _defaultHeaders...), headers...)
+		fields = append(append([]string{}, _defaultFields...), fields...)
+	}
+	cs := &Cassette{
+		conf:     c,
+		redactor: newRedactor(headers, fields),
+		mode:     c.Mode,
+	}
+	if cs.mode == ModeAuto {
+		cs.mode = ModeRecord
+		if _, err := os.Stat(c.Path); err == nil {
+			cs.mode = ModeReplay
+		}
+	}
+	if cs.mode != ModeReplay {
+		return cs, nil
+	}
+	bs, err := ioutil.ReadFile(c.Path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cassette: read %s", c.Path)
+	}
+	if err = json.Unmarshal(bs, &cs.interactions); err != nil {
+		return nil, errors.Wrapf(err, "cassette: decode %s", c.Path)
+	}
+	cs.used = make([]bool, len(cs.interactions))
+	return cs, nil
+}
+
+// Recording reports whether the cassette is recording.
+func (cs *Cassette) Recording() bool {
+	return cs.mode == ModeRecord
+}
+
+// Redact redacts the request in place, the requests are redacted before they
+// are recorded or matched.
+func (cs *Cassette) Redact(req *Request) {
+	req.Header = cs.redactor.header(req.Header)
+	req.URL = cs.redactor.url(req.URL)
+	if !req.Base64 {
+		req.Body = cs.redactor.body(req.Body)
+	}
+}
+
+// Record appends the interaction, the request and response are redacted.
+func (cs *Cassette) Record(req *Request, resp *Response) {
+	cs.Redact(req)
+	resp.Header = cs.redactor.header(resp.Header)
+	if !resp.Base64 {
+		resp.Body = cs.redactor.body(resp.Body)
+	}
+	cs.mutex.Lock()
+	cs.interactions = append(cs.interactions, &Interaction{Request: req, Response: resp})
+	cs.mutex.Unlock()
+}
+
+// Find returns the recorded response of the request.
+func (cs *Cassette) Find(req *Request) (*Response, error) {
+	cs.Redact(req)
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	if cs.conf.Match == MatchStrict {
+		if cs.next >= len(cs.interactions) {
+			return nil, errors.Wrapf(ErrNoInteraction, "%s %s: all %d interactions are used", req.Method, req.URL, len(cs.interactions))
+		}
+		in := cs.interactions[cs.next]
+		if !strictMatch(in.Request, req) {
+			return nil, errors.Wrapf(ErrNoInteraction, "%s %s: interaction %d is %s %s", req.Method, req.URL, cs.next, in.Request.Method, in.Request.URL)
+		}
+		cs.used[cs.next] = true
+		cs.next++
+		return in.Response, nil
+	}
+	last := -1
+	path := urlPath(req.URL)
+	for i, in := range cs.interactions {
+		if in.Request.Method != req.Method || urlPath(in.Request.URL) != path {
+			continue
+		}
+		if !cs.used[i] {
+			cs.used[i] = true
+			return in.Response, nil
+		}
+		last = i
+	}
+	if last < 0 {
+		return nil, errors.Wrapf(ErrNoInteraction, "%s %s", req.Method, req.URL)
+	}
+	return cs.interactions[last].Response, nil
+}
+
+// strictMatch reports whether the request equals the recorded one, the
+// volatile sign params are ignored.
+func strictMatch(recorded, req *Request) bool {
+	if recorded.Method != req.Method || recorded.Base64 != req.Base64 || stripVolatile(recorded.URL) != stripVolatile(req.URL) {
+		return false
+	}
+	if req.Base64 {
+		return recorded.Body == req.Body
+	}
+	return stripVolatileBody(recorded.Body) == stripVolatileBody(req.Body)
+}
+
+// Save writes the recorded interactions to the file, it is a noop in the replay mode.
+func (cs *Cassette) Save() error {
+	if cs.mode != ModeRecord {
+		return nil
+	}
+	cs.mutex.Lock()
+	bs, err := json.MarshalIndent(cs.interactions, "", "  ")
+	cs.mutex.Unlock()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.Wrapf(ioutil.WriteFile(cs.conf.Path, bs, 0644), "cassette: write %s", cs.conf.Path)
+}
@@ -0,0 +1,137 @@
+package cassette
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor(t *testing.T) {
+	r := newRedactor([]string{"authorization"}, []string{"token", "Sign"})
+	h := r.header(http.Header{"Authorization": {"Bearer x"}, "Accept": {"*/*"}})
+	assert.Equal(t, Redacted, h.Get("Authorization"))
+	assert.Equal(t, "*/*", h.Get("Accept"))
+	assert.Equal(t, "/x?a=1&sign=%5BREDACTED%5D", r.url("/x?sign=abc&a=1"))
+	assert.Equal(t, "/x?a=1", r.url("/x?a=1"))
+	assert.Equal(t, `{"data":[{"token":"[REDACTED]"}],"id":1}`, r.body(`{"id":1,"data":[{"token":"t"}]}`))
+	assert.Equal(t, `{"id": 1}`, r.body(`{"id": 1}`))
+	assert.Equal(t, "a=1&token=%5BREDACTED%5D", r.body("token=t&a=1"))
+}
+
+func TestHTTPCassette(t *testing.T) {
+	var hits int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		bs, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Write([]byte(r.URL.Path + ":" + string(bs) + `:{"token":"secret"}`))
+	}))
+	defer ts.Close()
+	dir, _ := ioutil.TempDir("", "cassette")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "http.json")
+
+	conf := &Config{Path: path, Mode: ModeAuto, RedactHeaders: []string{"Set-Cookie", "Authorization"}, RedactFields: []string{"ts"}}
+	do := func(client *http.Client, uri, body string) (string, error) {
+		req, _ := http.NewRequest("POST", uri, strings.NewReader(body))
+		req.Header.Set("Authorization", "secret")
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		bs, _ := ioutil.ReadAll(resp.Body)
+		return string(bs), nil
+	}
+
+	// record.
+	cs, err := New(conf)
+	assert.NoError(t, err)
+	assert.True(t, cs.Recording())
+	client := &http.Client{Transport: cs.Transport(nil)}
+	for _, uri := range []string{"/a?ts=1", "/b", "/a?ts=2"} {
+		_, err = do(client, ts.URL+uri, "body")
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, cs.Save())
+	bs, _ := ioutil.ReadFile(path)
+	assert.NotContains(t, string(bs), "secret\"", "the headers are redacted")
+	assert.Equal(t, 3, hits)
+
+	// replay strictly.
+	cs, err = New(conf)
+	assert.NoError(t, err)
+	assert.False(t, cs.Recording())
+	client = &http.Client{Transport: cs.Transport(nil)}
+	body, err := do(client, "http://other-host/a?ts=100", "body")
+	assert.NoError(t, err)
+	assert.Equal(t, `/a:body:{"token":"secret"}`, body)
+	_, err = do(client, "http://other-host/a", "body")
+	assert.Error(t, err, "the order is strict")
+	assert.Equal(t, 3, hits)
+
+	// replay leniently.
+	conf.Match = MatchLenient
+	cs, _ = New(conf)
+	client = &http.Client{Transport: cs.Transport(nil)}
+	for _, uri := range []string{"/b?x=1", "/a", "/a", "/a"} {
+		body, err = do(client, "http://other-host"+uri, "other")
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(body, strings.Split(uri, "?")[0]+":"), body)
+	}
+	_, err = do(client, "http://other-host/c", "")
+	assert.Error(t, err)
+}
+
+func TestDefaultRedact(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "cassette")
+	defer os.RemoveAll(dir)
+	cs, err := New(&Config{Path: filepath.Join(dir, "http.json"), Mode: ModeRecord})
+	assert.NoError(t, err)
+	req := &Request{
+		Method: "POST",
+		URL:    "/x?appkey=k&ts=1&a=1",
+		Header: http.Header{"Authorization": {"Bearer x"}, "Cookie": {"s=1"}, "X-Auth-Sign": {"abc"}, "Accept": {"*/*"}},
+		Body:   "nonce=n&sign=abc&b=2",
+	}
+	cs.Redact(req)
+	assert.Equal(t, Redacted, req.Header.Get("Authorization"))
+	assert.Equal(t, Redacted, req.Header.Get("Cookie"))
+	assert.Equal(t, Redacted, req.Header.Get("X-Auth-Sign"))
+	assert.Equal(t, "*/*", req.Header.Get("Accept"))
+	assert.Equal(t, "/x?a=1&appkey=%5BREDACTED%5D&ts=%5BREDACTED%5D", req.URL)
+	assert.Equal(t, "b=2&nonce=%5BREDACTED%5D&sign=%5BREDACTED%5D", req.Body)
+
+	cs, _ = New(&Config{Path: filepath.Join(dir, "http.json"), Mode: ModeRecord, DisableDefaultRedact: true})
+	req = &Request{Method: "GET", URL: "/x?ts=1", Header: http.Header{"Authorization": {"Bearer x"}}}
+	cs.Redact(req)
+	assert.Equal(t, "Bearer x", req.Header.Get("Authorization"))
+	assert.Equal(t, "/x?ts=1", req.URL)
+}
+
+func TestStrictMatchSign(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "cassette")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "http.json")
+	conf := &Config{Path: path, Mode: ModeRecord, DisableDefaultRedact: true}
+	cs, _ := New(conf)
+	cs.Record(&Request{Method: "POST", URL: "/x?a=1&ts=1&sign=s1", Body: "b=2&nonce=n1"}, &Response{Status: 200, Body: "ok"})
+	assert.NoError(t, cs.Save())
+
+	conf.Mode = ModeReplay
+	cs, err := New(conf)
+	assert.NoError(t, err)
+	resp, err := cs.Find(&Request{Method: "POST", URL: "/x?sign=s2&ts=2&a=1", Body: "nonce=n2&b=2"})
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", resp.Body)
+
+	cs, _ = New(conf)
+	_, err = cs.Find(&Request{Method: "POST", URL: "/x?a=2&ts=1&sign=s1", Body: "b=2&nonce=n1"})
+	assert.Error(t, err, "the other params are still matched")
+}
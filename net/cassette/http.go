@@ -0,0 +1,98 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// transport records or replays the http requests.
+type transport struct {
+	cs   *Cassette
+	next http.RoundTripper
+}
+
+// Transport returns a http.RoundTripper which records the requests sent by
+// next, or replays them without sending, eg: client.SetTransport(cs.Transport(nil)).
+// next is http.DefaultTransport if it is nil. The requests are matched by the
+// method, the request uri and the body, the host is ignored.
+func (cs *Cassette) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{cs: cs, next: next}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	creq := &Request{Method: req.Method, URL: req.URL.RequestURI(), Header: req.Header.Clone()}
+	if req.Body != nil && req.Body != http.NoBody {
+		bs, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(bs))
+		creq.Body, creq.Base64 = encodeBody(bs)
+	}
+	if !t.cs.Recording() {
+		resp, err := t.cs.Find(creq)
+		if err != nil {
+			return nil, err
+		}
+		return resp.httpResponse(req)
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	bs, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(bs))
+	cresp := &Response{Status: resp.StatusCode, Header: resp.Header.Clone()}
+	cresp.Body, cresp.Base64 = encodeBody(bs)
+	t.cs.Record(creq, cresp)
+	return resp, nil
+}
+
+// httpResponse returns the replayed http response of the request.
+func (resp *Response) httpResponse(req *http.Request) (*http.Response, error) {
+	bs, err := decodeBody(resp.Body, resp.Base64)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", resp.Status, http.StatusText(resp.Status)),
+		StatusCode:    resp.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        resp.Header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(bs)),
+		ContentLength: int64(len(bs)),
+		Request:       req,
+	}, nil
+}
+
+// encodeBody returns the body as is if it is utf8 text, otherwise in base64.
+func encodeBody(bs []byte) (string, bool) {
+	if utf8.Valid(bs) {
+		return string(bs), false
+	}
+	return base64.StdEncoding.EncodeToString(bs), true
+}
+
+func decodeBody(body string, isBase64 bool) ([]byte, error) {
+	if !isBase64 {
+		return []byte(body), nil
+	}
+	bs, err := base64.StdEncoding.DecodeString(body)
+	return bs, errors.Wrap(err, "cassette: decode body")
+}
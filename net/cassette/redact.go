@@ -0,0 +1,201 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gisvr/golib/net/auth"
+)
+
+// Redacted replaces the redacted values.
+const Redacted = "[REDACTED]"
+
+var (
+	// _defaultHeaders are the credentials which are redacted by default.
+	_defaultHeaders = []string{
+		"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie",
+		auth.HeaderAppKey, auth.HeaderTs, auth.HeaderNonce, auth.HeaderSign,
+	}
+	// _defaultFields are the sign params which are redacted by default.
+	_defaultFields = []string{auth.ParamAppKey, auth.ParamTs, auth.ParamNonce, auth.ParamSign}
+	// _volatileParams differ in every signed request, they are ignored in
+	// the strict matching.
+	_volatileParams = []string{auth.ParamTs, auth.ParamNonce, auth.ParamSign}
+)
+
+// redactor redacts the headers, the params and the json fields by name.
+type redactor struct {
+	headers map[string]struct{}
+	fields  map[string]struct{}
+}
+
+func newRedactor(headers, fields []string) *redactor {
+	r := &redactor{
+		headers: make(map[string]struct{}, len(headers)),
+		fields:  make(map[string]struct{}, len(fields)),
+	}
+	for _, h := range headers {
+		r.headers[http.CanonicalHeaderKey(h)] = struct{}{}
+	}
+	for _, f := range fields {
+		r.fields[strings.ToLower(f)] = struct{}{}
+	}
+	return r
+}
+
+func (r *redactor) redactField(name string) bool {
+	_, ok := r.fields[strings.ToLower(name)]
+	return ok
+}
+
+// header returns a copy of the header with the redacted values.
+func (r *redactor) header(h http.Header) http.Header {
+	if len(h) == 0 {
+		return h
+	}
+	nh := make(http.Header, len(h))
+	for k, v := range h {
+		if _, ok := r.headers[http.CanonicalHeaderKey(k)]; ok {
+			v = []string{Redacted}
+		}
+		nh[k] = v
+	}
+	return nh
+}
+
+// values redacts the params, it reports whether any is redacted.
+func (r *redactor) values(vs url.Values) bool {
+	var redacted bool
+	for k := range vs {
+		if r.redactField(k) {
+			vs[k] = []string{Redacted}
+			redacted = true
+		}
+	}
+	return redacted
+}
+
+// url redacts the query of the url.
+func (r *redactor) url(raw string) string {
+	if len(r.fields) == 0 || !strings.Contains(raw, "?") {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	query := u.Query()
+	if !r.values(query) {
+		return raw
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// body redacts the json fields or the form params of the body, the body is
+// returned as is if nothing is redacted.
+func (r *redactor) body(body string) string {
+	if len(r.fields) == 0 || body == "" {
+		return body
+	}
+	if trimmed := strings.TrimSpace(body); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		var v interface{}
+		dec := json.NewDecoder(strings.NewReader(body))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil || !r.json(v) {
+			return body
+		}
+		buf := new(bytes.Buffer)
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err != nil {
+			return body
+		}
+		return strings.TrimSuffix(buf.String(), "\n")
+	}
+	form, err := url.ParseQuery(body)
+	if err != nil || !r.values(form) {
+		return body
+	}
+	return form.Encode()
+}
+
+// json redacts the decoded json value in place, it reports whether any is redacted.
+func (r *redactor) json(v interface{}) (redacted bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, field := range v {
+			if r.redactField(k) {
+				v[k] = Redacted
+				redacted = true
+				continue
+			}
+			if r.json(field) {
+				redacted = true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if r.json(item) {
+				redacted = true
+			}
+		}
+	}
+	return
+}
+
+// stripVolatile removes the volatile sign params from the query of the url.
+func stripVolatile(raw string) string {
+	if !strings.Contains(raw, "?") {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	query := u.Query()
+	if !stripValues(query) {
+		return raw
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// stripVolatileBody removes the volatile sign params from the form body.
+func stripVolatileBody(body string) string {
+	if trimmed := strings.TrimSpace(body); trimmed == "" || strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		return body
+	}
+	form, err := url.ParseQuery(body)
+	if err != nil || !stripValues(form) {
+		return body
+	}
+	return form.Encode()
+}
+
+// stripValues removes the volatile params, it reports whether any is removed.
+func stripValues(vs url.Values) bool {
+	var stripped bool
+	for k := range vs {
+		for _, p := range _volatileParams {
+			if strings.EqualFold(k, p) {
+				delete(vs, k)
+				stripped = true
+				break
+			}
+		}
+	}
+	return stripped
+}
+
+// urlPath returns the path of the url, which is matched in the lenient mode.
+func urlPath(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return u.Path
+}
@@ -0,0 +1,275 @@
+package blademaster
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gisvr/golib/ecode"
+	"github.com/gisvr/golib/log"
+	"github.com/gisvr/golib/net/metadata"
+	"github.com/gisvr/golib/net/trace"
+	xtime "github.com/gisvr/golib/time"
+)
+
+// access log formats.
+const (
+	AccessLogJSON     = "json"
+	AccessLogCombined = "combined"
+)
+
+// _headerField is the field prefix of a request header, eg: header.User-Agent.
+const _headerField = "header."
+
+var _defaultAccessFields = []string{
+	"method", "ip", "user", "path", "route", "query", "status", "ret",
+	"bytes_in", "bytes_out", "latency", "trace_id", "err",
+}
+
+// AccessLogConfig is the access log config.
+type AccessLogConfig struct {
+	// Fields are the logged fields, the default fields are logged if it is
+	// empty, eg: [method, route, status, header.User-Agent].
+	// The fields are method, ip, user, path, route, query, status, ret, msg,
+	// bytes_in, bytes_out, latency, trace_id, err, and header.<Name>.
+	Fields []string `yaml:"fields"`
+	// Format is json(default), combined for the apache combined log format,
+	// or a text pattern of the fields, eg: "%{ip} %{method} %{path} %{status}".
+	Format string `yaml:"format"`
+	// SampleRate is the ratio of the logged requests in (0, 1], default 1.
+	// The failed and slow requests are always logged.
+	SampleRate float64 `yaml:"sampleRate"`
+	// Slow is the latency of the slow requests which are logged as warning, default 500ms.
+	Slow xtime.Duration `yaml:"slow"`
+	// Routes overrides the config of the routes keyed by the route template, eg: /user/:id.
+	Routes map[string]*AccessLogRoute `yaml:"routes"`
+	// Handler is the dedicated handler of the access log, eg: a separate
+	// log.FileHandler, the global logger is used if it is nil.
+	Handler log.Handler `yaml:"-"`
+}
+
+// AccessLogRoute is the access log config of a route.
+type AccessLogRoute struct {
+	SampleRate float64        `yaml:"sampleRate"`
+	Slow       xtime.Duration `yaml:"slow"`
+	// Disable stops logging the successful requests of the route.
+	Disable bool `yaml:"disable"`
+}
+
+// accessEntry is a finished request.
+type accessEntry struct {
+	c      *Context
+	start  time.Time
+	dt     time.Duration
+	caller string
+	cerr   ecode.Codes
+	body   *countedBody
+	writer *countedWriter
+}
+
+var _accessFields = map[string]func(e *accessEntry) interface{}{
+	"method":    func(e *accessEntry) interface{} { return e.c.Request.Method },
+	"ip":        func(e *accessEntry) interface{} { return metadata.String(e.c, metadata.RemoteIP) },
+	"user":      func(e *accessEntry) interface{} { return e.caller },
+	"path":      func(e *accessEntry) interface{} { return e.c.Request.URL.Path },
+	"route":     func(e *accessEntry) interface{} { return e.c.RoutePath },
+	"query":     func(e *accessEntry) interface{} { return e.c.Request.URL.RawQuery },
+	"status":    func(e *accessEntry) interface{} { return int64(e.writer.status) },
+	"ret":       func(e *accessEntry) interface{} { return int64(e.cerr.Code()) },
+	"msg":       func(e *accessEntry) interface{} { return e.cerr.Message() },
+	"bytes_in":  func(e *accessEntry) interface{} { return e.body.n },
+	"bytes_out": func(e *accessEntry) interface{} { return e.writer.n },
+	"latency":   func(e *accessEntry) interface{} { return e.dt.Seconds() },
+	"trace_id": func(e *accessEntry) interface{} {
+		if t, ok := trace.FromContext(e.c); ok {
+			return t.TraceID()
+		}
+		return ""
+	},
+	"err": func(e *accessEntry) interface{} {
+		if e.c.Error != nil {
+			return e.c.Error.Error()
+		}
+		return ""
+	},
+}
+
+// accessField returns the value getter of the field.
+func accessField(name string) (func(e *accessEntry) interface{}, bool) {
+	if strings.HasPrefix(name, _headerField) {
+		key := name[len(_headerField):]
+		return func(e *accessEntry) interface{} { return e.c.Request.Header.Get(key) }, true
+	}
+	f, ok := _accessFields[name]
+	return f, ok
+}
+
+// accessPattern is the compiled text format, the texts and fields are interleaved.
+type accessPattern struct {
+	texts  []string
+	fields []func(e *accessEntry) interface{}
+}
+
+func newAccessPattern(format string) *accessPattern {
+	p := &accessPattern{}
+	for {
+		i := strings.Index(format, "%{")
+		j := -1
+		if i >= 0 {
+			j = strings.IndexByte(format[i:], '}')
+		}
+		if j < 0 {
+			p.texts = append(p.texts, format)
+			return p
+		}
+		j += i
+		f, ok := accessField(format[i+2 : j])
+		if !ok {
+			panic(fmt.Sprintf("blademaster: unknown access log field: %s", format[i+2:j]))
+		}
+		p.texts = append(p.texts, format[:i])
+		p.fields = append(p.fields, f)
+		format = format[j+1:]
+	}
+}
+
+func (p *accessPattern) render(e *accessEntry) string {
+	buf := new(bytes.Buffer)
+	for i, f := range p.fields {
+		buf.WriteString(p.texts[i])
+		fmt.Fprint(buf, f(e))
+	}
+	buf.WriteString(p.texts[len(p.texts)-1])
+	return buf.String()
+}
+
+// combined renders the apache combined log format.
+func combined(e *accessEntry) string {
+	req := e.c.Request
+	user := e.caller
+	if user == "" {
+		user = "-"
+	}
+	size := "-"
+	if e.writer.n > 0 {
+		size = strconv.FormatInt(e.writer.n, 10)
+	}
+	return fmt.Sprintf("%s - %s [%s] %q %d %s %q %q",
+		metadata.String(e.c, metadata.RemoteIP), user, e.start.Format("02/Jan/2006:15:04:05 -0700"),
+		req.Method+" "+req.URL.RequestURI()+" "+req.Proto, e.writer.status, size, req.Referer(), req.UserAgent())
+}
+
+// AccessLog returns the access log middleware of the config, it also reports
+// the request metrics as Logger, so it replaces Logger rather than follows it.
+func AccessLog(conf *AccessLogConfig) HandlerFunc {
+	if conf == nil {
+		conf = &AccessLogConfig{}
+	}
+	names := conf.Fields
+	if len(names) == 0 {
+		names = _defaultAccessFields
+	}
+	fields := make([]func(e *accessEntry) interface{}, 0, len(names))
+	for _, name := range names {
+		f, ok := accessField(name)
+		if !ok {
+			panic(fmt.Sprintf("blademaster: unknown access log field: %s", name))
+		}
+		fields = append(fields, f)
+	}
+	var render func(e *accessEntry) string
+	switch conf.Format {
+	case "", AccessLogJSON:
+	case AccessLogCombined:
+		render = combined
+	default:
+		render = newAccessPattern(conf.Format).render
+	}
+	var handler log.Handler
+	if conf.Handler != nil {
+		handler = log.NewHandlers(nil, conf.Handler)
+	}
+	slow := time.Duration(conf.Slow)
+	if slow <= 0 {
+		slow = 500 * time.Millisecond
+	}
+	return func(c *Context) {
+		now := time.Now()
+		body := &countedBody{ReadCloser: c.Request.Body}
+		c.Request.Body = body
+		writer := &countedWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		// the access log is rendered by the configured fields rather than
+		// the bound ones, the logger is only for the handlers.
+		bindLogger(c)
+
+		c.Next()
+
+		e := &accessEntry{c: c, start: now, dt: time.Since(now), body: body, writer: writer}
+		e.cerr = ecode.Cause(c.Error)
+		e.caller = metadata.String(c, metadata.Caller)
+		caller := e.caller
+		if caller == "" {
+			caller = "no_user"
+		}
+		reportRequest(c, caller, e.cerr, e.dt)
+
+		lv := log.InfoLevel
+		rate, routeSlow, disable := conf.SampleRate, slow, false
+		if rc, ok := conf.Routes[c.RoutePath]; ok && rc != nil {
+			if rc.SampleRate > 0 {
+				rate = rc.SampleRate
+			}
+			if rc.Slow > 0 {
+				routeSlow = time.Duration(rc.Slow)
+			}
+			disable = rc.Disable
+		}
+		switch {
+		case c.Error != nil && e.cerr.Code() > 0:
+			lv = log.WarnLevel
+		case c.Error != nil || writer.status >= http.StatusInternalServerError:
+			lv = log.ErrorLevel
+		case e.dt >= routeSlow:
+			lv = log.WarnLevel
+		case disable:
+			return
+		case rate > 0 && rate < 1 && rand.Float64() >= rate:
+			return
+		}
+
+		var ds []log.D
+		if render != nil {
+			ds = []log.D{log.KVMessage(render(e))}
+		} else {
+			ds = make([]log.D, 0, len(fields)+1)
+			for i, f := range fields {
+				switch v := f(e).(type) {
+				case string:
+					ds = append(ds, log.KVString(names[i], v))
+				case int64:
+					ds = append(ds, log.KVInt64(names[i], v))
+				case float64:
+					ds = append(ds, log.KVFloat64(names[i], v))
+				}
+			}
+			ds = append(ds, log.KVString("catalog", "http-access-log"))
+		}
+		if handler != nil {
+			handler.Log(c, 0, lv, ds...)
+			return
+		}
+		switch lv {
+		case log.ErrorLevel:
+			log.Errorv(c, ds...)
+		case log.WarnLevel:
+			log.Warnv(c, ds...)
+		default:
+			log.Infov(c, ds...)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package blademaster
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gisvr/golib/ecode"
+	"github.com/gisvr/golib/log"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// captureHandler records the logged fields.
+type captureHandler struct {
+	mutex sync.Mutex
+	logs  []map[string]interface{}
+}
+
+func (h *captureHandler) Log(ctx context.Context, depth int, lv log.Level, args ...log.D) {
+	d := make(map[string]interface{})
+	for _, arg := range args {
+		switch {
+		case arg.StringVal != "":
+			d[arg.Key] = arg.StringVal
+		case arg.Value != nil:
+			d[arg.Key] = arg.Value
+		default:
+			d[arg.Key] = arg.Int64Val
+		}
+	}
+	h.mutex.Lock()
+	h.logs = append(h.logs, d)
+	h.mutex.Unlock()
+}
+
+func (h *captureHandler) SetFormat(string) {}
+func (h *captureHandler) Close() error     { return nil }
+
+func newAccessEngine(conf *AccessLogConfig) (*Engine, *captureHandler) {
+	h := &captureHandler{}
+	conf.Handler = h
+	e := newLimitEngine()
+	e.Use(AccessLog(conf))
+	e.GET("/access/user/:id", func(c *Context) {
+		c.String(200, "hello")
+	})
+	e.GET("/access/fail", func(c *Context) {
+		c.JSON(nil, ecode.ServerErr)
+	})
+	return e, h
+}
+
+func TestAccessLogFields(t *testing.T) {
+	e, h := newAccessEngine(&AccessLogConfig{Fields: []string{"route", "status", "bytes_out", "header.X-Test"}})
+	req := httptest.NewRequest("GET", "/access/user/42?a=1", nil)
+	req.Header.Set("X-Test", "kratos")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	if assert.Len(t, h.logs, 1) {
+		d := h.logs[0]
+		assert.Equal(t, "/access/user/:id", d["route"])
+		assert.EqualValues(t, 200, d["status"])
+		assert.EqualValues(t, len("hello"), d["bytes_out"])
+		assert.Equal(t, "kratos", d["header.X-Test"])
+		assert.Equal(t, "INFO", d["level"])
+	}
+}
+
+func TestAccessLogFormat(t *testing.T) {
+	e, h := newAccessEngine(&AccessLogConfig{Format: "%{method} %{route} %{status}!"})
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/access/user/42", nil))
+	e, h2 := newAccessEngine(&AccessLogConfig{Format: AccessLogCombined})
+	req := httptest.NewRequest("GET", "/access/user/42?a=1", nil)
+	req.Header.Set("User-Agent", "test")
+	e.ServeHTTP(httptest.NewRecorder(), req)
+	if assert.Len(t, h.logs, 1) && assert.Len(t, h2.logs, 1) {
+		assert.Equal(t, "GET /access/user/:id 200!", h.logs[0]["log"])
+		combined := h2.logs[0]["log"].(string)
+		assert.True(t, strings.HasSuffix(combined, `"GET /access/user/42?a=1 HTTP/1.1" 200 5 "" "test"`), combined)
+	}
+	assert.Panics(t, func() { AccessLog(&AccessLogConfig{Format: "%{unknown}"}) })
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	e, h := newAccessEngine(&AccessLogConfig{
+		SampleRate: 0.000001,
+		Routes:     map[string]*AccessLogRoute{"/access/fail": {Disable: true}},
+	})
+	for i := 0; i < 10; i++ {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/access/user/42", nil))
+	}
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/access/fail", nil))
+	if assert.Len(t, h.logs, 1, "the failed requests are always logged") {
+		assert.Equal(t, "/access/fail", h.logs[0]["route"])
+		assert.Equal(t, "ERROR", h.logs[0]["level"])
+	}
+}
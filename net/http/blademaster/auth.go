@@ -0,0 +1,109 @@
+package blademaster
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gisvr/golib/net/auth"
+)
+
+// _maxAuthBody is the max bytes of the body which is read to verify its hash
+// if the route has no MaxBodySize, as it is read before authenticated.
+const _maxAuthBody = 4 << 20
+
+// authRequest adapts the http request to auth.BodyRequest.
+type authRequest struct {
+	c *Context
+}
+
+func (r authRequest) Method() string           { return r.c.Request.Method }
+func (r authRequest) Path() string             { return r.c.Request.URL.Path }
+func (r authRequest) Header(key string) string { return r.c.Request.Header.Get(key) }
+
+// Params returns the query and the url encoded form, the multipart form is
+// excluded because the files can not be signed.
+func (r authRequest) Params() url.Values {
+	req := r.c.Request
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		return req.Form
+	}
+	return req.URL.Query()
+}
+
+// Body returns the body which is not a form, it is nil for the url encoded and
+// the multipart forms as they have been parsed before the middlewares. The
+// body is limited by the MaxBodySize of the route or _maxAuthBody.
+func (r authRequest) Body() ([]byte, error) {
+	req := r.c.Request
+	ctype := req.Header.Get("Content-Type")
+	if req.Body == nil || strings.HasPrefix(ctype, "application/x-www-form-urlencoded") || strings.Contains(ctype, "multipart/form-data") {
+		return nil, nil
+	}
+	limit := int64(_maxAuthBody)
+	if mc := r.c.engine.methodConfig(r.c.RoutePath); mc != nil && mc.MaxBodySize > 0 {
+		limit = mc.MaxBodySize
+	}
+	bs, err := ioutil.ReadAll(http.MaxBytesReader(r.c.Writer, req.Body, limit))
+	req.Body.Close()
+	// the body is restored for the binding of the handlers.
+	req.Body = ioutil.NopCloser(bytes.NewReader(bs))
+	return bs, err
+}
+
+// Auth is the authentication and authorization middleware.
+type Auth struct {
+	authn *auth.Authenticator
+}
+
+// NewAuth returns an auth middleware which authenticates requests by the verifiers in order.
+func NewAuth(verifiers ...auth.Verifier) *Auth {
+	return &Auth{authn: auth.New(verifiers...)}
+}
+
+// Verify authenticates the request and stores the principal into the metadata.
+// Requests without a credential pass through anonymously, those with an
+// invalid credential are aborted with ecode.Unauthorized.
+func (a *Auth) Verify() HandlerFunc {
+	return func(c *Context) {
+		a.authenticate(c)
+	}
+}
+
+// Require returns a middleware which authenticates the request and checks it
+// by the policy, eg: group := engine.Group("/admin", a.Require("role:admin")).
+// It panics if the policy is invalid.
+func (a *Auth) Require(policy string) HandlerFunc {
+	p := auth.MustPolicy(policy)
+	return func(c *Context) {
+		pr, ok := a.authenticate(c)
+		if !ok {
+			return
+		}
+		if err := p.Check(pr); err != nil {
+			c.JSON(nil, err)
+			c.Abort()
+		}
+	}
+}
+
+// authenticate returns the principal of the request, nil for anonymous requests.
+func (a *Auth) authenticate(c *Context) (*auth.Principal, bool) {
+	if pr, ok := auth.FromContext(c); ok {
+		// authenticated by a former middleware.
+		return pr, true
+	}
+	pr, err := a.authn.Authenticate(c, authRequest{c: c})
+	if err == auth.ErrNoCredential {
+		return nil, true
+	}
+	if err != nil {
+		c.JSON(nil, err)
+		c.Abort()
+		return nil, false
+	}
+	c.Context = auth.NewContext(c.Context, pr)
+	return pr, true
+}
@@ -0,0 +1,264 @@
+package blademaster
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gisvr/golib/conf/env"
+	"github.com/gisvr/golib/ecode"
+	"github.com/gisvr/golib/net/metadata"
+	"github.com/gisvr/golib/net/netutil/breaker"
+)
+
+const (
+	// the balancers of the resolved instances.
+	balancerP2C = "p2c"
+	balancerWRR = "wrr"
+
+	// The mean lifetime of the statistics, it reaches its half-life after tau*ln(2).
+	_tau = int64(time.Millisecond * 600)
+	// the penalty of the endpoint without statistics.
+	_penalty = uint64(time.Second * 250)
+	// the endpoint not picked during the gap is forced once to decay its statistics.
+	_forceGap = int64(time.Second * 3)
+)
+
+// endpointStat is the passive health statistics of an instance, which is
+// kept across the instance updates.
+type endpointStat struct {
+	lag      uint64 // the moving average latency in nanoseconds.
+	success  uint64 // the moving average success rate in 1/1000.
+	inflight int64
+	stamp    int64 // the last collected timestamp.
+	pick     int64 // the last picked timestamp.
+}
+
+func newEndpointStat() *endpointStat {
+	return &endpointStat{success: 1000, inflight: 1}
+}
+
+// endpoint is an instance of the target.
+type endpoint struct {
+	addr   string
+	weight int64
+	color  string
+	brk    breaker.Breaker
+	stat   *endpointStat
+}
+
+func (e *endpoint) health() uint64 {
+	return atomic.LoadUint64(&e.stat.success)
+}
+
+func (e *endpoint) valid() bool {
+	return e.health() > 500
+}
+
+func (e *endpoint) load() uint64 {
+	lag := uint64(math.Sqrt(float64(atomic.LoadUint64(&e.stat.lag))) + 1)
+	load := lag * uint64(atomic.LoadInt64(&e.stat.inflight))
+	if load == 0 {
+		load = _penalty
+	}
+	return load
+}
+
+// done returns the callback which collects the result of the request.
+func (e *endpoint) done(start int64) func(error) {
+	atomic.AddInt64(&e.stat.inflight, 1)
+	return func(err error) {
+		st := e.stat
+		atomic.AddInt64(&st.inflight, -1)
+		if err != nil {
+			e.brk.MarkFailed()
+		} else {
+			e.brk.MarkSuccess()
+		}
+		now := time.Now().UnixNano()
+		// get moving average ratio w
+		td := now - atomic.SwapInt64(&st.stamp, now)
+		if td < 0 {
+			td = 0
+		}
+		w := math.Exp(float64(-td) / float64(_tau))
+		lag := now - start
+		if lag < 0 {
+			lag = 0
+		}
+		oldLag := atomic.LoadUint64(&st.lag)
+		if oldLag == 0 {
+			w = 0.0
+		}
+		atomic.StoreUint64(&st.lag, uint64(float64(oldLag)*w+float64(lag)*(1.0-w)))
+		success := uint64(1000)
+		if err != nil {
+			success = 0
+		}
+		oldSuc := atomic.LoadUint64(&st.success)
+		atomic.StoreUint64(&st.success, uint64(float64(oldSuc)*w+float64(success)*(1.0-w)))
+	}
+}
+
+// picker picks an endpoint for a request.
+type picker interface {
+	pick(c context.Context) (*endpoint, func(error), error)
+}
+
+// colorPicker routes the request to the endpoints of its color, the
+// endpoints without color serve the rest.
+type colorPicker struct {
+	normal picker
+	colors map[string]picker
+}
+
+func newPicker(balancer string, endpoints []*endpoint) picker {
+	build := newP2CPicker
+	if balancer == balancerWRR {
+		build = newWRRPicker
+	}
+	var normal []*endpoint
+	colors := make(map[string][]*endpoint)
+	for _, e := range endpoints {
+		if e.color == "" {
+			normal = append(normal, e)
+			continue
+		}
+		colors[e.color] = append(colors[e.color], e)
+	}
+	p := &colorPicker{normal: build(normal), colors: make(map[string]picker, len(colors))}
+	for color, es := range colors {
+		p.colors[color] = build(es)
+	}
+	return p
+}
+
+func (p *colorPicker) pick(c context.Context) (*endpoint, func(error), error) {
+	color := metadata.String(c, metadata.Color)
+	if color == "" {
+		color = env.Color
+	}
+	if color != "" {
+		if cp, ok := p.colors[color]; ok {
+			return cp.pick(c)
+		}
+	}
+	return p.normal.pick(c)
+}
+
+// p2cPicker picks the less loaded one of two random endpoints.
+type p2cPicker struct {
+	endpoints []*endpoint
+	mutex     sync.Mutex
+	r         *rand.Rand
+}
+
+func newP2CPicker(endpoints []*endpoint) picker {
+	return &p2cPicker{endpoints: endpoints, r: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// prePick chooses two distinct endpoints.
+func (p *p2cPicker) prePick() (a, b *endpoint) {
+	for i := 0; i < 3; i++ {
+		p.mutex.Lock()
+		x := p.r.Intn(len(p.endpoints))
+		y := p.r.Intn(len(p.endpoints) - 1)
+		p.mutex.Unlock()
+		if y >= x {
+			y++
+		}
+		a, b = p.endpoints[x], p.endpoints[y]
+		if a.valid() || b.valid() {
+			break
+		}
+	}
+	return
+}
+
+func (p *p2cPicker) pick(c context.Context) (*endpoint, func(error), error) {
+	var pc, upc *endpoint
+	start := time.Now().UnixNano()
+	switch len(p.endpoints) {
+	case 0:
+		return nil, nil, ecode.ServiceUnavailable
+	case 1:
+		pc = p.endpoints[0]
+	default:
+		a, b := p.prePick()
+		if a.load()*b.health()*uint64(b.weight) > b.load()*a.health()*uint64(a.weight) {
+			pc, upc = b, a
+		} else {
+			pc, upc = a, b
+		}
+		// force the unpicked one if it has not been picked during the gap.
+		pick := atomic.LoadInt64(&upc.stat.pick)
+		if start-pick > _forceGap && atomic.CompareAndSwapInt64(&upc.stat.pick, pick, start) {
+			pc, upc = upc, pc
+		}
+	}
+	if err := pc.brk.Allow(); err != nil {
+		if upc == nil || upc.brk.Allow() != nil {
+			return nil, nil, err
+		}
+		pc = upc
+	}
+	atomic.StoreInt64(&pc.stat.pick, start)
+	return pc, pc.done(start), nil
+}
+
+// wrrPicker picks the endpoints by the smooth weighted round robin, the
+// weight is scaled by the success rate.
+type wrrPicker struct {
+	endpoints []*endpoint
+	current   []int64
+	mutex     sync.Mutex
+}
+
+func newWRRPicker(endpoints []*endpoint) picker {
+	return &wrrPicker{endpoints: endpoints, current: make([]int64, len(endpoints))}
+}
+
+func (p *wrrPicker) next(skip map[int]struct{}) int {
+	var total int64
+	best := -1
+	for i, e := range p.endpoints {
+		if _, ok := skip[i]; ok {
+			continue
+		}
+		weight := e.weight * int64(e.health()+1)
+		total += weight
+		p.current[i] += weight
+		if best < 0 || p.current[i] > p.current[best] {
+			best = i
+		}
+	}
+	if best >= 0 {
+		p.current[best] -= total
+	}
+	return best
+}
+
+func (p *wrrPicker) pick(c context.Context) (*endpoint, func(error), error) {
+	var skip map[int]struct{}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	err := error(ecode.ServiceUnavailable)
+	for range p.endpoints {
+		i := p.next(skip)
+		if i < 0 {
+			break
+		}
+		pc := p.endpoints[i]
+		if err = pc.brk.Allow(); err == nil {
+			return pc, pc.done(time.Now().UnixNano()), nil
+		}
+		if skip == nil {
+			skip = make(map[int]struct{})
+		}
+		skip[i] = struct{}{}
+	}
+	return nil, nil, err
+}
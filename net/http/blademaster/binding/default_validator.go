@@ -32,6 +32,7 @@ func (v *defaultValidator) RegisterValidation(key string, fn validator.Func) err
 func (v *defaultValidator) lazyinit() {
 	v.once.Do(func() {
 		v.validate = validator.New()
+		registerFileValidations(v.validate)
 	})
 }
 
@@ -10,7 +10,11 @@ const defaultMemory = 32 * 1024 * 1024
 
 type formBinding struct{}
 type formPostBinding struct{}
-type formMultipartBinding struct{}
+type formMultipartBinding struct {
+	conf *MultipartConfig
+}
+
+var _defaultMultipartConfig = &MultipartConfig{MaxMemory: defaultMemory}
 
 func (f formBinding) Name() string {
 	return "form"
@@ -23,6 +27,11 @@ func (f formBinding) Bind(req *http.Request, obj interface{}) error {
 	if err := mapForm(obj, req.Form); err != nil {
 		return err
 	}
+	if req.MultipartForm != nil {
+		if err := mapFiles(obj, req.MultipartForm.File); err != nil {
+			return err
+		}
+	}
 	return validate(obj)
 }
 
@@ -45,11 +54,18 @@ func (f formMultipartBinding) Name() string {
 }
 
 func (f formMultipartBinding) Bind(req *http.Request, obj interface{}) error {
-	if err := req.ParseMultipartForm(defaultMemory); err != nil {
-		return errors.WithStack(err)
+	conf := f.conf
+	if conf == nil {
+		conf = _defaultMultipartConfig
+	}
+	if err := parseMultipart(req, conf, obj); err != nil {
+		return err
 	}
 	if err := mapForm(obj, req.MultipartForm.Value); err != nil {
 		return err
 	}
+	if err := mapFiles(obj, req.MultipartForm.File); err != nil {
+		return err
+	}
 	return validate(obj)
 }
@@ -46,6 +46,10 @@ func (c *cache) set(obj reflect.Type) (s *sinfo) {
 			fd.hasDefault = true
 			fd.defaultValue = dv
 		}
+		if fd.tp.Type == _fileHeaderType || fd.tp.Type == _fileHeaderSliceType {
+			fd.isFile = true
+			fd.fileType = hasFileType(fd.tp.Tag.Get("validate"))
+		}
 		s.field = append(s.field, fd)
 	}
 	c.mutex.Lock()
@@ -65,6 +69,9 @@ type field struct {
 
 	hasDefault   bool          // if field had default value
 	defaultValue reflect.Value // field default value
+
+	isFile   bool // if field is *multipart.FileHeader or []*multipart.FileHeader
+	fileType bool // if field has the filetype validation tag
 }
 
 func mapForm(ptr interface{}, form map[string][]string) error {
@@ -77,6 +84,10 @@ func mapForm(ptr interface{}, form map[string][]string) error {
 			continue
 		}
 
+		if fd.isFile {
+			// file fields are set by mapFiles.
+			continue
+		}
 		structFieldKind := structField.Kind()
 		inputFieldName := fd.name
 		if inputFieldName == "" {
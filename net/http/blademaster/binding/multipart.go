@@ -0,0 +1,305 @@
+package binding
+
+import (
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+const _sniffLen = 512
+
+var (
+	_fileHeaderType      = reflect.TypeOf((*multipart.FileHeader)(nil))
+	_fileHeaderSliceType = reflect.TypeOf([]*multipart.FileHeader(nil))
+)
+
+// MultipartConfig is the multipart form binding config.
+type MultipartConfig struct {
+	// MaxMemory is the max bytes of the file parts kept in memory,
+	// the remainder are streamed into temporary files on disk.
+	MaxMemory int64
+	// MaxBodySize limits the size of the whole request body, no limit if it is zero.
+	MaxBodySize int64
+	// MaxFileSize limits the size of every single file, no limit if it is zero.
+	MaxFileSize int64
+	// AllowTypes is the allow-list of the sniffed MIME types of the files,
+	// a wildcard subtype such as "image/*" is supported. Any type is allowed if it is empty.
+	AllowTypes []string
+}
+
+// Multipart returns a multipart/form-data binding with the given config.
+func Multipart(conf *MultipartConfig) Binding {
+	return formMultipartBinding{conf: conf}
+}
+
+// the validation tags of the file fields, which apply to every file of the
+// field, eg:
+//
+//	Avatar *multipart.FileHeader `form:"avatar" validate:"required,filesize=2MB,filetype=image/png image/jpeg,fileext=.png .jpg"`
+//
+// The filetype tag overrides the AllowTypes of the MultipartConfig, an
+// optional file field needs the omitempty tag.
+const (
+	_tagFileSize = "filesize"
+	_tagFileType = "filetype"
+	_tagFileExt  = "fileext"
+)
+
+// registerFileValidations registers the file validation tags, a single file
+// is validated as a slice of one file.
+func registerFileValidations(v *validator.Validate) {
+	v.RegisterCustomTypeFunc(fileSlice, multipart.FileHeader{})
+	v.RegisterValidation(_tagFileSize, validateFileSize)
+	v.RegisterValidation(_tagFileType, validateFileType)
+	v.RegisterValidation(_tagFileExt, validateFileExt)
+}
+
+func fileSlice(v reflect.Value) interface{} {
+	if v.CanAddr() {
+		return []*multipart.FileHeader{v.Addr().Interface().(*multipart.FileHeader)}
+	}
+	fh := v.Interface().(multipart.FileHeader)
+	return []*multipart.FileHeader{&fh}
+}
+
+// validateFiles reports whether every file of the field is valid.
+func validateFiles(fl validator.FieldLevel, fn func(fh *multipart.FileHeader) bool) bool {
+	fhs, ok := fl.Field().Interface().([]*multipart.FileHeader)
+	if !ok {
+		return false
+	}
+	for _, fh := range fhs {
+		if fh != nil && !fn(fh) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateFileSize validates the max size of the files, an invalid size
+// fails the validation.
+func validateFileSize(fl validator.FieldLevel) bool {
+	size, err := parseSize(fl.Param())
+	if err != nil {
+		return false
+	}
+	return validateFiles(fl, func(fh *multipart.FileHeader) bool {
+		return fh.Size <= size
+	})
+}
+
+// validateFileType validates the sniffed MIME types of the files.
+func validateFileType(fl validator.FieldLevel) bool {
+	types := strings.Fields(fl.Param())
+	return validateFiles(fl, func(fh *multipart.FileHeader) bool {
+		ctype, err := SniffContentType(fh)
+		return err == nil && matchMIMEs(types, ctype)
+	})
+}
+
+// validateFileExt validates the extensions of the file names, case insensitive.
+func validateFileExt(fl validator.FieldLevel) bool {
+	exts := strings.Fields(strings.ToLower(fl.Param()))
+	return validateFiles(fl, func(fh *multipart.FileHeader) bool {
+		return containsString(exts, strings.ToLower(filepath.Ext(fh.Filename)))
+	})
+}
+
+// hasFileType reports whether the validate tag has the filetype tag.
+func hasFileType(tag string) bool {
+	for _, t := range strings.Split(tag, ",") {
+		if strings.HasPrefix(t, _tagFileType+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSize parses a size such as 1024, 512KB, 2MB or 1GB into bytes.
+func parseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	unit := int64(1)
+	for _, u := range []struct {
+		suffix string
+		n      int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(s, u.suffix) {
+			unit = u.n
+			s = strings.TrimSuffix(s, u.suffix)
+			break
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "binding: invalid size: %q", s)
+	}
+	return n * unit, nil
+}
+
+// parseMultipart parses the multipart form of the request, and checks every
+// file part by the config, whether or not it is bound to a field of obj.
+func parseMultipart(req *http.Request, conf *MultipartConfig, obj interface{}) error {
+	if conf.MaxBodySize > 0 && req.ContentLength > conf.MaxBodySize {
+		return errors.Errorf("binding: request body too large: %d > %d", req.ContentLength, conf.MaxBodySize)
+	}
+	// the form may have been parsed by the server already.
+	if req.MultipartForm == nil {
+		if conf.MaxBodySize > 0 {
+			req.Body = http.MaxBytesReader(nil, req.Body, conf.MaxBodySize)
+		}
+		maxMemory := conf.MaxMemory
+		if maxMemory <= 0 {
+			maxMemory = defaultMemory
+		}
+		if err := req.ParseMultipartForm(maxMemory); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return checkFiles(obj, req.MultipartForm.File, conf)
+}
+
+// checkFiles checks every file part by the config, the AllowTypes is skipped
+// for the parts of the fields which have the filetype tag.
+func checkFiles(obj interface{}, files map[string][]*multipart.FileHeader, conf *MultipartConfig) error {
+	if conf.MaxFileSize <= 0 && len(conf.AllowTypes) == 0 {
+		return nil
+	}
+	typed := make(map[string]bool)
+	fileTypeFields(reflect.TypeOf(obj), typed)
+	for name, fhs := range files {
+		for _, fh := range fhs {
+			if err := checkFile(name, fh, typed[name], conf); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fileTypeFields collects the form names of the file fields of the struct
+// pointer type which have the filetype tag.
+func fileTypeFields(tp reflect.Type, names map[string]bool) {
+	for _, fd := range scache.get(tp).field {
+		if fd.tp.PkgPath != "" {
+			// unexported fields are not bound.
+			continue
+		}
+		if fd.name == "" && fd.tp.Type.Kind() == reflect.Struct {
+			fileTypeFields(reflect.PtrTo(fd.tp.Type), names)
+			continue
+		}
+		if !fd.isFile || !fd.fileType {
+			continue
+		}
+		name := fd.name
+		if name == "" {
+			name = fd.tp.Name
+		}
+		names[name] = true
+	}
+}
+
+// mapFiles sets the *multipart.FileHeader and []*multipart.FileHeader fields of ptr.
+func mapFiles(ptr interface{}, files map[string][]*multipart.FileHeader) error {
+	sinfo := scache.get(reflect.TypeOf(ptr))
+	val := reflect.ValueOf(ptr).Elem()
+	for i, fd := range sinfo.field {
+		structField := val.Field(i)
+		if !structField.CanSet() {
+			continue
+		}
+		if fd.name == "" && structField.Kind() == reflect.Struct {
+			if err := mapFiles(structField.Addr().Interface(), files); err != nil {
+				return err
+			}
+			continue
+		}
+		if !fd.isFile {
+			continue
+		}
+		name := fd.name
+		if name == "" {
+			name = fd.tp.Name
+		}
+		fhs, ok := files[name]
+		if !ok || len(fhs) == 0 {
+			continue
+		}
+		if fd.tp.Type == _fileHeaderType {
+			structField.Set(reflect.ValueOf(fhs[0]))
+			continue
+		}
+		structField.Set(reflect.ValueOf(fhs))
+	}
+	return nil
+}
+
+// checkFile checks the file by the config, the AllowTypes is skipped if the
+// field has the filetype tag.
+func checkFile(name string, fh *multipart.FileHeader, fileType bool, conf *MultipartConfig) (err error) {
+	if conf.MaxFileSize > 0 && fh.Size > conf.MaxFileSize {
+		return errors.Errorf("binding: file %s(%s) too large: %d > %d", name, fh.Filename, fh.Size, conf.MaxFileSize)
+	}
+	if fileType || len(conf.AllowTypes) == 0 {
+		return
+	}
+	ctype, err := SniffContentType(fh)
+	if err != nil {
+		return
+	}
+	if matchMIMEs(conf.AllowTypes, ctype) {
+		return
+	}
+	return errors.Errorf("binding: file %s(%s) type %s not allowed", name, fh.Filename, ctype)
+}
+
+// SniffContentType detects the MIME type of the uploaded file by its content
+// rather than the Content-Type declared by the client.
+func SniffContentType(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer f.Close()
+	buf := make([]byte, _sniffLen)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", errors.WithStack(err)
+	}
+	return stripContentTypeParam(http.DetectContentType(buf[:n])), nil
+}
+
+func matchMIMEs(patterns []string, ctype string) bool {
+	for _, p := range patterns {
+		if matchMIME(p, ctype) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchMIME(pattern, ctype string) bool {
+	if pattern == "*/*" || pattern == ctype {
+		return true
+	}
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(ctype, pattern[:len(pattern)-1])
+	}
+	return false
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package binding
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+var _png = []byte("\x89PNG\x0D\x0A\x1A\x0A\x00\x00\x00\x0DIHDR")
+
+type UploadStruct struct {
+	Name   string                  `form:"name" validate:"required"`
+	Avatar *multipart.FileHeader   `form:"avatar" validate:"required,filesize=1KB,filetype=image/png,fileext=.png"`
+	Photos []*multipart.FileHeader `form:"photos" validate:"max=2"`
+}
+
+type upload struct {
+	field, filename string
+	content         []byte
+}
+
+func createUploadRequest(uploads ...upload) *http.Request {
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	mw.WriteField("name", "kratos")
+	for _, u := range uploads {
+		w, _ := mw.CreateFormFile(u.field, u.filename)
+		w.Write(u.content)
+	}
+	mw.Close()
+	req, _ := http.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.ContentLength = int64(body.Len())
+	return req
+}
+
+func TestBindingMultipartFile(t *testing.T) {
+	req := createUploadRequest(
+		upload{"avatar", "a.png", _png},
+		upload{"photos", "1.txt", []byte("photo1")},
+		upload{"photos", "2.txt", []byte("photo2")},
+	)
+	obj := new(UploadStruct)
+	assert.NoError(t, FormMultipart.Bind(req, obj))
+	assert.Equal(t, "kratos", obj.Name)
+	assert.Equal(t, "a.png", obj.Avatar.Filename)
+	assert.Len(t, obj.Photos, 2)
+
+	ctype, err := SniffContentType(obj.Avatar)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", ctype)
+}
+
+func TestBindingMultipartFileConstraint(t *testing.T) {
+	// the content is not a png.
+	req := createUploadRequest(upload{"avatar", "a.png", []byte("hello")})
+	assert.Error(t, FormMultipart.Bind(req, new(UploadStruct)))
+
+	// the extension is not allowed.
+	req = createUploadRequest(upload{"avatar", "a.gif", _png})
+	assert.Error(t, FormMultipart.Bind(req, new(UploadStruct)))
+
+	// the file is too large.
+	req = createUploadRequest(upload{"avatar", "a.png", append(_png, make([]byte, 1024)...)})
+	assert.Error(t, FormMultipart.Bind(req, new(UploadStruct)))
+
+	// too many files.
+	req = createUploadRequest(
+		upload{"avatar", "a.png", _png},
+		upload{"photos", "1.txt", []byte("1")},
+		upload{"photos", "2.txt", []byte("2")},
+		upload{"photos", "3.txt", []byte("3")},
+	)
+	assert.Error(t, FormMultipart.Bind(req, new(UploadStruct)))
+
+	// required file is missing.
+	req = createUploadRequest()
+	assert.Error(t, FormMultipart.Bind(req, new(UploadStruct)))
+}
+
+func TestBindingMultipartFileValidation(t *testing.T) {
+	// the file constraints are reported by the validator.
+	req := createUploadRequest(upload{"avatar", "a.gif", _png})
+	err := FormMultipart.Bind(req, new(UploadStruct))
+	errs, ok := err.(validator.ValidationErrors)
+	assert.True(t, ok, "%v", err)
+	assert.Equal(t, "fileext", errs[0].Tag())
+
+	type optional struct {
+		Name   string                  `form:"name"`
+		Avatar *multipart.FileHeader   `form:"avatar" validate:"omitempty,filesize=1KB"`
+		Photos []*multipart.FileHeader `form:"photos" validate:"fileext=.TXT"`
+		Bad    *multipart.FileHeader   `form:"bad" validate:"omitempty,filesize=big"`
+	}
+	req = createUploadRequest(upload{"photos", "1.txt", []byte("1")})
+	assert.NoError(t, FormMultipart.Bind(req, new(optional)))
+	req = createUploadRequest(upload{"photos", "1.txt", []byte("1")}, upload{"photos", "2.jpg", []byte("2")})
+	assert.Error(t, FormMultipart.Bind(req, new(optional)))
+
+	// the invalid param fails the validation rather than panics.
+	req = createUploadRequest(upload{"bad", "a.png", _png})
+	assert.Error(t, FormMultipart.Bind(req, new(optional)))
+}
+
+func TestBindingMultipartConfig(t *testing.T) {
+	b := Multipart(&MultipartConfig{MaxMemory: 1, MaxBodySize: 1 << 10})
+	req := createUploadRequest(upload{"avatar", "a.png", _png})
+	obj := new(UploadStruct)
+	assert.NoError(t, b.Bind(req, obj))
+	assert.Equal(t, "a.png", obj.Avatar.Filename)
+
+	req = createUploadRequest(upload{"avatar", "a.png", append(_png, make([]byte, 2048)...)})
+	assert.Error(t, b.Bind(req, new(UploadStruct)))
+
+	b = Multipart(&MultipartConfig{MaxFileSize: 4})
+	req = createUploadRequest(upload{"avatar", "a.png", _png})
+	assert.Error(t, b.Bind(req, new(UploadStruct)))
+
+	b = Multipart(&MultipartConfig{AllowTypes: []string{"text/*"}})
+	req = createUploadRequest(upload{"avatar", "a.png", _png}, upload{"photos", "1.txt", []byte("photo")})
+	assert.NoError(t, b.Bind(req, new(UploadStruct)))
+
+	// the parts which are not bound to any field are checked too.
+	req = createUploadRequest(upload{"avatar", "a.png", _png}, upload{"other", "b.png", _png})
+	assert.Error(t, b.Bind(req, new(UploadStruct)))
+	b = Multipart(&MultipartConfig{MaxFileSize: 32})
+	req = createUploadRequest(upload{"avatar", "a.png", _png}, upload{"other", "b.txt", make([]byte, 64)})
+	assert.Error(t, b.Bind(req, new(UploadStruct)))
+}
+
+func TestParseSize(t *testing.T) {
+	for s, n := range map[string]int64{"1024": 1024, "2KB": 2048, "1mb": 1 << 20, "1GB": 1 << 30, "10B": 10} {
+		v, err := parseSize(s)
+		assert.NoError(t, err)
+		assert.Equal(t, n, v)
+	}
+	_, err := parseSize("big")
+	assert.Error(t, err)
+}
@@ -0,0 +1,47 @@
+package blademaster
+
+import (
+	"github.com/gisvr/golib/ecode"
+	"github.com/gisvr/golib/net/netutil/breaker"
+)
+
+// Breaker is the server side circuit breaker middleware, the requests of a
+// route are rejected with ecode.ServiceUnavailable while its handlers keep
+// failing, which sheds the load of a broken dependency.
+type Breaker struct {
+	group *breaker.Group
+}
+
+// NewBreaker return a breaker middleware, the breakers are grouped by route.
+func NewBreaker(conf *breaker.Config) *Breaker {
+	return &Breaker{group: breaker.NewGroup(conf)}
+}
+
+// Reload reloads the breaker config.
+func (b *Breaker) Reload(conf *breaker.Config) {
+	b.group.Reload(conf)
+}
+
+// Handler return a bm handler func, the routes with DisableBreaker in the
+// method config are not broken.
+func (b *Breaker) Handler() HandlerFunc {
+	return func(c *Context) {
+		if mc := c.engine.methodConfig(c.RoutePath); mc != nil && mc.DisableBreaker {
+			return
+		}
+		brk := b.group.Get(c.RoutePath)
+		if err := brk.Allow(); err != nil {
+			_metricServerLimit.Inc(routeLabel(c), c.method, "breaker")
+			c.JSON(nil, err)
+			c.Abort()
+			return
+		}
+		c.Next()
+		if err := c.Error; err != nil && (ecode.EqualError(ecode.ServerErr, err) ||
+			ecode.EqualError(ecode.ServiceUnavailable, err) || ecode.EqualError(ecode.Deadline, err)) {
+			brk.MarkFailed()
+			return
+		}
+		brk.MarkSuccess()
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"github.com/gisvr/golib/conf/env"
+	"github.com/gisvr/golib/net/admin"
 	"github.com/gisvr/golib/net/metadata"
 	"github.com/gisvr/golib/net/netutil/breaker"
 	xtime "github.com/gisvr/golib/time"
@@ -49,6 +50,15 @@ type ClientConfig struct {
 	Breaker   *breaker.Config          `yaml:"breaker"`
 	URL       map[string]*ClientConfig `yaml:"url"`
 	Host      map[string]*ClientConfig `yaml:"host"`
+	// the options of the resolved appid, which are read from the url or host
+	// config when the appid is resolved first time.
+	Balancer string       `yaml:"balancer"` // p2c(default) or wrr.
+	Subset   int          `yaml:"subset"`   // the max instances to balance, default 50.
+	Cluster  []string     `yaml:"cluster"`  // the clusters of the instances, all by default.
+	Zone     string       `yaml:"zone"`     // the preferred zone, env.Zone by default.
+	Retry    *RetryConfig `yaml:"retry"`
+	// MaxResponseSize is the max bytes of the response body, no limit if it is zero.
+	MaxResponseSize int64 `yaml:"maxResponseSize"`
 }
 
 // Client is http client.
@@ -62,6 +72,10 @@ type Client struct {
 	hostConf map[string]*ClientConfig
 	mutex    sync.RWMutex
 	breaker  *breaker.Group
+	signer   Signer
+	targets  map[string]*target
+
+	unregister func()
 }
 
 // NewClient new a http client.
@@ -85,10 +99,12 @@ func NewClient(c *ClientConfig) *Client {
 	}
 	client.urlConf = make(map[string]*ClientConfig)
 	client.hostConf = make(map[string]*ClientConfig)
+	client.targets = make(map[string]*target)
 	client.breaker = breaker.NewGroup(c.Breaker)
 	if c.Timeout <= 0 {
 		panic("must config http timeout!!!")
 	}
+	client.unregister = admin.RegisterStats("blademaster.client.breaker", func() interface{} { return client.breaker.Stats() })
 	for uri, cfg := range c.URL {
 		client.urlConf[uri] = cfg
 	}
@@ -98,12 +114,30 @@ func NewClient(c *ClientConfig) *Client {
 	return client
 }
 
+// Close closes the resolved targets and unregisters the stats of the client.
+func (client *Client) Close() error {
+	client.mutex.Lock()
+	targets := client.targets
+	client.targets = make(map[string]*target)
+	client.mutex.Unlock()
+	for _, t := range targets {
+		t.close()
+	}
+	client.unregister()
+	return nil
+}
+
 // SetTransport set client transport
 func (client *Client) SetTransport(t xhttp.RoundTripper) {
 	client.transport = t
 	client.client.Transport = t
 }
 
+// SetSigner set the signer which signs every outgoing request.
+func (client *Client) SetSigner(s Signer) {
+	client.signer = s
+}
+
 // SetConfig set client config.
 func (client *Client) SetConfig(c *ClientConfig) {
 	client.mutex.Lock()
@@ -250,16 +284,32 @@ func (client *Client) RESTfulPost(c context.Context, uri, ip string, params url.
 	return client.Do(c, req, res, uri)
 }
 
-// Raw sends an HTTP request and returns bytes response
+// Raw sends an HTTP request and returns bytes response, the request is
+// retried by the retry config of the url or host.
 func (client *Client) Raw(c context.Context, req *xhttp.Request, v ...string) (bs []byte, err error) {
+	resp, config, finish, err := client.send(c, req, v...)
+	if err != nil {
+		return
+	}
+	defer func() {
+		finish(err)
+	}()
+	defer resp.Body.Close()
+	if bs, err = readAll(limitResponse(resp, config.MaxResponseSize), _minRead); err != nil {
+		err = pkgerr.Wrapf(err, "host:%s, url:%s", resp.Request.URL.Host, realURL(resp.Request))
+	}
+	return
+}
+
+// send sends the request with the breaker, the metrics, the timeout and the
+// retries. If err is nil, the caller must close the body of resp and call
+// finish with the error of reading it.
+func (client *Client) send(c context.Context, req *xhttp.Request, v ...string) (resp *xhttp.Response, config *ClientConfig, finish func(error), err error) {
 	var (
-		ok      bool
-		code    string
-		cancel  func()
-		resp    *xhttp.Response
-		config  *ClientConfig
-		timeout time.Duration
-		uri     = fmt.Sprintf("%s://%s%s", req.URL.Scheme, req.Host, req.URL.Path)
+		ok     bool
+		code   string
+		cancel func()
+		uri    = fmt.Sprintf("%s://%s%s", req.URL.Scheme, req.Host, req.URL.Path)
 	)
 	// NOTE fix prom & config uri key.
 	if len(v) == 1 {
@@ -268,18 +318,26 @@ func (client *Client) Raw(c context.Context, req *xhttp.Request, v ...string) (b
 	// breaker
 	brk := client.breaker.Get(uri)
 	if err = brk.Allow(); err != nil {
-		code = "breaker"
-		_metricClientReqCodeTotal.Inc(uri, req.Method, code)
+		_metricClientReqCodeTotal.Inc(uri, req.Method, "breaker")
 		return
 	}
-	defer client.onBreaker(brk, &err)
 	// stat
 	now := time.Now()
-	defer func() {
+	finish = func(err error) {
+		if cancel != nil {
+			cancel()
+		}
+		client.onBreaker(brk, &err)
 		_metricClientReqDur.Observe(int64(time.Since(now)/time.Millisecond), uri, req.Method)
 		if code != "" {
 			_metricClientReqCodeTotal.Inc(uri, req.Method, code)
 		}
+	}
+	defer func() {
+		if err != nil {
+			finish(err)
+			resp, finish = nil, nil
+		}
 	}()
 	// get config
 	// 1.url config 2.host config 3.default
@@ -291,40 +349,118 @@ func (client *Client) Raw(c context.Context, req *xhttp.Request, v ...string) (b
 	}
 	client.mutex.RUnlock()
 	// timeout
-	deliver := true
-	timeout = time.Duration(config.Timeout)
-	if deadline, ok := c.Deadline(); ok {
-		if ctimeout := time.Until(deadline); ctimeout < timeout {
-			// deliver small timeout
-			timeout = ctimeout
-			deliver = false
+	if deadline, ok := c.Deadline(); !ok || time.Until(deadline) > time.Duration(config.Timeout) {
+		var timeoutCancel func()
+		// canceled by finish.
+		c, timeoutCancel = context.WithTimeout(c, time.Duration(config.Timeout))
+		cancel = timeoutCancel
+	}
+	var max int
+	if retry := config.Retry; retry.retryable(req) {
+		if err = rewindable(req); err != nil {
+			code = "failed"
+			return
+		}
+		max = retry.Max
+	}
+	for retries := 0; ; retries++ {
+		var done func(error)
+		if resp, code, done, err = client.do(c, req, config); err == nil {
+			stat := finish
+			finish = func(err error) {
+				done(err)
+				stat(err)
+			}
+			return
 		}
+		if retries >= max {
+			return
+		}
+		var retryAfter time.Duration
+		if resp != nil {
+			if !config.Retry.retryStatus(resp.StatusCode) {
+				return
+			}
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		} else if code != "failed" || c.Err() != nil {
+			return
+		}
+		delay := config.Retry.backoff(retries, retryAfter)
+		if deadline, ok := c.Deadline(); ok && time.Until(deadline) < delay {
+			return
+		}
+		_metricClientReqRetry.Inc(uri, req.Method)
+		select {
+		case <-time.After(delay):
+		case <-c.Done():
+			return
+		}
+	}
+}
+
+// do makes an attempt of the request. If err is nil, done must be called with
+// the error of reading the body of resp, otherwise resp is the response with
+// the closed body if it is received.
+func (client *Client) do(c context.Context, req *xhttp.Request, config *ClientConfig) (resp *xhttp.Response, code string, done func(error), err error) {
+	var (
+		ep    *endpoint
+		epErr error
+	)
+	done = func(error) {}
+	// resolve the appid of the url like discovery://appid/path
+	if t, ok := client.resolve(req.URL.Scheme, req.URL.Host, config); ok {
+		if ep, done, err = t.pick(c); err != nil {
+			err = pkgerr.Wrapf(err, "resolve appid:%s", req.URL.Host)
+			code = "resolve"
+			return
+		}
+		defer func() {
+			if err != nil {
+				done(epErr)
+			}
+		}()
+	}
+	// every attempt is sent by a copy, which is signed again.
+	r := req.Clone(c)
+	if req.GetBody != nil && req.Body != nil && req.Body != xhttp.NoBody {
+		if r.Body, err = req.GetBody(); err != nil {
+			err = pkgerr.Wrapf(err, "host:%s, url:%s", req.URL.Host, req.URL.Path)
+			code = "failed"
+			return
+		}
+	}
+	if ep != nil {
+		r.URL.Scheme, r.URL.Host, r.Host = "http", ep.addr, ep.addr
 	}
-	if deliver {
-		c, cancel = context.WithTimeout(c, timeout)
-		defer cancel()
+	if deadline, ok := c.Deadline(); ok {
+		setTimeout(r, time.Until(deadline))
 	}
-	setTimeout(req, timeout)
-	req = req.WithContext(c)
-	setCaller(req)
+	setCaller(r)
 	metadata.Range(c,
 		func(key string, value interface{}) {
-			setMetadata(req, key, value)
+			setMetadata(r, key, value)
 		},
 		metadata.IsOutgoingKey)
-	if resp, err = client.client.Do(req); err != nil {
-		err = pkgerr.Wrapf(err, "host:%s, url:%s", req.URL.Host, realURL(req))
+	if client.signer != nil {
+		if err = client.signer.Sign(r); err != nil {
+			code = "sign"
+			return
+		}
+	}
+	if resp, err = client.client.Do(r); err != nil {
+		err = pkgerr.Wrapf(err, "host:%s, url:%s", r.URL.Host, realURL(r))
 		code = "failed"
+		epErr = err
+		resp = nil
 		return
 	}
-	defer resp.Body.Close()
 	if resp.StatusCode >= xhttp.StatusBadRequest {
-		err = pkgerr.Errorf("incorrect http status:%d host:%s, url:%s", resp.StatusCode, req.URL.Host, realURL(req))
+		resp.Body.Close()
+		err = pkgerr.Errorf("incorrect http status:%d host:%s, url:%s", resp.StatusCode, r.URL.Host, realURL(r))
 		code = strconv.Itoa(resp.StatusCode)
-		return
-	}
-	if bs, err = readAll(resp.Body, _minRead); err != nil {
-		err = pkgerr.Wrapf(err, "host:%s, url:%s", req.URL.Host, realURL(req))
+		if resp.StatusCode >= xhttp.StatusInternalServerError {
+			epErr = err
+		}
 		return
 	}
 	return
@@ -2,8 +2,11 @@ package blademaster
 
 import (
 	"context"
+	"io"
 	"math"
+	"mime/multipart"
 	"net/http"
+	"os"
 	"strconv"
 	"text/template"
 
@@ -292,6 +295,11 @@ func (c *Context) Bind(obj interface{}) error {
 // See the binding package.
 func (c *Context) mustBindWith(obj interface{}, b binding.Binding) (err error) {
 	if err = b.Bind(c.Request, obj); err != nil {
+		if errors.Cause(err) == errBodyTooLarge {
+			// the body of the unknown length exceeds the MaxBodySize.
+			c.engine.rejectTooLarge(c)
+			return
+		}
 		c.Error = ecode.RequestErr
 		c.Render(http.StatusOK, render.JSON{
 			Code:    ecode.RequestErr.Code(),
@@ -303,6 +311,35 @@ func (c *Context) mustBindWith(obj interface{}, b binding.Binding) (err error) {
 	return
 }
 
+// FormFile returns the first file for the provided form key.
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	if c.Request.MultipartForm == nil {
+		if err := c.Request.ParseMultipartForm(defaultMaxMemory); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if fhs := c.Request.MultipartForm.File[name]; len(fhs) > 0 {
+		return fhs[0], nil
+	}
+	return nil, errors.WithStack(http.ErrMissingFile)
+}
+
+// SaveUploadedFile uploads the form file to specific dst.
+func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	src, err := file.Open()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer src.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer out.Close()
+	_, err = io.Copy(out, src)
+	return errors.WithStack(err)
+}
+
 func writeStatusCode(w http.ResponseWriter, ecode int) {
 	header := w.Header()
 	header.Set("kratos-status-code", strconv.FormatInt(int64(ecode), 10))
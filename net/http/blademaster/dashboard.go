@@ -0,0 +1,69 @@
+package blademaster
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// _dashboardPanels are the RED panels of the server metrics, the $path
+// variable filters the routes.
+var _dashboardPanels = []struct {
+	title  string
+	unit   string
+	expr   string
+	legend string
+}{
+	{"Rate", "reqps", `sum(rate(http_server_requests_code_total{path=~"$path"}[1m])) by (path)`, "{{path}}"},
+	{"Errors", "reqps", `sum(rate(http_server_requests_code_total{path=~"$path",code!="0"}[1m])) by (path, code)`, "{{path}} {{code}}"},
+	{"Duration p99", "ms", `histogram_quantile(0.99, sum(rate(http_server_requests_duration_ms_bucket{path=~"$path"}[1m])) by (le, path))`, "{{path}}"},
+	{"Duration p50", "ms", `histogram_quantile(0.5, sum(rate(http_server_requests_duration_ms_bucket{path=~"$path"}[1m])) by (le, path))`, "{{path}}"},
+	{"Inflight", "short", `sum(http_server_requests_inflight{path=~"$path"}) by (path)`, "{{path}}"},
+	{"Callers", "reqps", `sum(rate(http_server_requests_code_total{path=~"$path"}[1m])) by (caller)`, "{{caller}}"},
+	{"Request size p95", "bytes", `histogram_quantile(0.95, sum(rate(http_server_requests_request_size_bytes_bucket{path=~"$path"}[1m])) by (le, path))`, "{{path}}"},
+	{"Response size p95", "bytes", `histogram_quantile(0.95, sum(rate(http_server_requests_response_size_bytes_bucket{path=~"$path"}[1m])) by (le, path))`, "{{path}}"},
+	{"Limited", "reqps", `sum(rate(http_server_limit_total{path=~"$path"}[1m])) by (path, reason)`, "{{path}} {{reason}}"},
+	{"BBR dropped", "reqps", `sum(rate(http_server_bbr_total{path=~"$path"}[1m])) by (path)`, "{{path}}"},
+}
+
+// Dashboard returns the grafana dashboard json of the server metrics, which
+// queries the prometheus datasource of the name.
+func Dashboard(datasource string) ([]byte, error) {
+	type m = map[string]interface{}
+	panels := make([]m, 0, len(_dashboardPanels))
+	for i, p := range _dashboardPanels {
+		panels = append(panels, m{
+			"id":         i + 1,
+			"type":       "timeseries",
+			"title":      p.title,
+			"datasource": datasource,
+			"gridPos":    m{"h": 8, "w": 12, "x": (i % 2) * 12, "y": (i / 2) * 8},
+			"fieldConfig": m{
+				"defaults":  m{"unit": p.unit},
+				"overrides": []m{},
+			},
+			"targets": []m{{"refId": "A", "expr": p.expr, "legendFormat": p.legend}},
+		})
+	}
+	dashboard := m{
+		"title":         "blademaster",
+		"uid":           "blademaster-red",
+		"tags":          []string{"blademaster", "http"},
+		"schemaVersion": 27,
+		"refresh":       "30s",
+		"time":          m{"from": "now-1h", "to": "now"},
+		"templating": m{"list": []m{{
+			"name":       "path",
+			"type":       "query",
+			"datasource": datasource,
+			"query":      "label_values(http_server_requests_code_total, path)",
+			"refresh":    2,
+			"multi":      true,
+			"includeAll": true,
+			"allValue":   ".*",
+		}}},
+		"panels": panels,
+	}
+	bs, err := json.MarshalIndent(dashboard, "", "  ")
+	return bs, errors.WithStack(err)
+}
@@ -0,0 +1,177 @@
+package blademaster
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gisvr/golib/container/queue/aqm"
+	"github.com/gisvr/golib/ecode"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	errBodyTooLarge = errors.New("blademaster: body too large")
+	errReadTimeout  = errors.New("blademaster: request body read timeout")
+)
+
+// limitedBody fails the reads beyond n bytes like http.MaxBytesReader, and
+// records whether the limit is exceeded.
+type limitedBody struct {
+	io.ReadCloser
+	n        int64
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (n int, err error) {
+	if b.n <= 0 {
+		b.exceeded = true
+		return 0, errBodyTooLarge
+	}
+	if int64(len(p)) > b.n+1 {
+		p = p[:b.n+1]
+	}
+	n, err = b.ReadCloser.Read(p)
+	if int64(n) <= b.n {
+		b.n -= int64(n)
+		return
+	}
+	n = int(b.n)
+	b.n = 0
+	b.exceeded = true
+	return n, errBodyTooLarge
+}
+
+// deadlineBody fails the reads after the deadline. The deadline is checked
+// before each read, a blocking read is bounded by the server ReadTimeout.
+type deadlineBody struct {
+	io.ReadCloser
+	deadline time.Time
+}
+
+func (b *deadlineBody) Read(p []byte) (int, error) {
+	if time.Now().After(b.deadline) {
+		return 0, errReadTimeout
+	}
+	return b.ReadCloser.Read(p)
+}
+
+// deadlineWriter fails the writes after the deadline with http.ErrHandlerTimeout.
+type deadlineWriter struct {
+	http.ResponseWriter
+	deadline time.Time
+}
+
+func (w *deadlineWriter) Write(p []byte) (int, error) {
+	if time.Now().After(w.deadline) {
+		return 0, http.ErrHandlerTimeout
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher.
+func (w *deadlineWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// _maxWaiters is the max requests waiting for an inflight limiter, the same
+// as the buffer of the aqm queue.
+const _maxWaiters = 2048
+
+// waiter is a request waiting for a running one to be done, it receives
+// whether it is dropped by the CoDel queue.
+type waiter struct {
+	ch chan bool
+	ts time.Time
+}
+
+// inflightLimiter limits the concurrent requests of a route, the excess
+// requests wait in FIFO until a running one is done, and are dropped by the
+// CoDel queue when they waited too long. The count and the handoff of the
+// slots are guarded by the mutex, so a release never misses a waiter.
+type inflightLimiter struct {
+	mutex    sync.Mutex
+	inflight int64
+	waiters  []*waiter
+	queue    *aqm.Queue
+	conf     *aqm.Config
+}
+
+func newInflightLimiter(conf *aqm.Config) *inflightLimiter {
+	return &inflightLimiter{queue: aqm.New(conf), conf: conf}
+}
+
+// reload applies the queue config when it is changed.
+func (l *inflightLimiter) reload(conf *aqm.Config) {
+	l.mutex.Lock()
+	if l.conf != conf {
+		l.conf = conf
+		l.queue.Reload(conf)
+	}
+	l.mutex.Unlock()
+}
+
+// acquire returns nil if the request can be handled, the caller must call
+// release after it is done.
+func (l *inflightLimiter) acquire(c *Context, max int64) error {
+	l.mutex.Lock()
+	if l.inflight < max && len(l.waiters) == 0 {
+		l.inflight++
+		l.mutex.Unlock()
+		return nil
+	}
+	if len(l.waiters) >= _maxWaiters {
+		l.mutex.Unlock()
+		return ecode.LimitExceed
+	}
+	w := &waiter{ch: make(chan bool, 1), ts: time.Now()}
+	l.waiters = append(l.waiters, w)
+	l.mutex.Unlock()
+
+	select {
+	case drop := <-w.ch:
+		if drop {
+			return ecode.LimitExceed
+		}
+		return nil
+	case <-c.Done():
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for i, o := range l.waiters {
+		if o == w {
+			l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+			return ecode.Deadline
+		}
+	}
+	// the slot has been handed off before the request is timed out.
+	if drop := <-w.ch; !drop {
+		l.releaseLocked()
+	}
+	return ecode.Deadline
+}
+
+func (l *inflightLimiter) release() {
+	l.mutex.Lock()
+	l.releaseLocked()
+	l.mutex.Unlock()
+}
+
+// releaseLocked hands off the slot to the first waiter which is not dropped.
+func (l *inflightLimiter) releaseLocked() {
+	for len(l.waiters) > 0 {
+		w := l.waiters[0]
+		l.waiters[0] = nil
+		l.waiters = l.waiters[1:]
+		drop := l.queue.Judge(w.ts)
+		w.ch <- drop
+		if !drop {
+			return
+		}
+	}
+	l.inflight--
+}
@@ -0,0 +1,193 @@
+package blademaster
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gisvr/golib/container/queue/aqm"
+	"github.com/gisvr/golib/ecode"
+	"github.com/gisvr/golib/net/http/blademaster/binding"
+	"github.com/gisvr/golib/net/netutil/breaker"
+	xtime "github.com/gisvr/golib/time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newLimitEngine() *Engine {
+	return NewServer(&ServerConfig{Timeout: xtime.Duration(time.Second)})
+}
+
+func TestMaxBodySize(t *testing.T) {
+	e := newLimitEngine()
+	e.Group("/limit").SetMethodConfig(&MethodConfig{MaxBodySize: 16}).POST("/form", func(c *Context) {
+		c.String(200, "%s", c.Request.Form.Get("name"))
+	})
+
+	for _, test := range []struct {
+		body   string
+		status int
+	}{
+		{"name=kratos", 200},
+		{"name=" + strings.Repeat("x", 32), http.StatusRequestEntityTooLarge},
+	} {
+		req := httptest.NewRequest("POST", "/limit/form", strings.NewReader(test.body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		assert.Equal(t, test.status, w.Code)
+	}
+
+	// the unknown content length is limited while parsing.
+	req := httptest.NewRequest("POST", "/limit/form", strings.NewReader("name="+strings.Repeat("x", 32)))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.ContentLength = -1
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	// the unknown content length of the json body is limited while binding.
+	e.Group("/limit").SetMethodConfig(&MethodConfig{MaxBodySize: 16}).POST("/json", func(c *Context) {
+		var v struct {
+			Name string `json:"name"`
+		}
+		if err := c.BindWith(&v, binding.JSON); err != nil {
+			return
+		}
+		c.String(200, "%s", v.Name)
+	})
+	req = httptest.NewRequest("POST", "/limit/json", strings.NewReader(`{"name":"`+strings.Repeat("x", 32)+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	// the limited requests are labeled by the route as the other metrics.
+	_, sum := gather(t, "http_server_limit_total", map[string]string{"path": "limit/form", "reason": "body"})
+	assert.True(t, sum >= 2, "limited %v", sum)
+	_, sum = gather(t, "http_server_limit_total", map[string]string{"path": "limit/json", "reason": "body"})
+	assert.Equal(t, float64(1), sum)
+}
+
+func TestMaxInflight(t *testing.T) {
+	e := newLimitEngine()
+	release := make(chan struct{})
+	e.GET("/limit/slow", func(c *Context) {
+		<-release
+		c.String(200, "ok")
+	})
+	e.SetMethodConfig("/limit/slow", &MethodConfig{MaxInflight: 1, Timeout: xtime.Duration(50 * time.Millisecond)})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest("GET", "/limit/slow", nil))
+		assert.Equal(t, 200, w.Code)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// the queued request is timed out.
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/limit/slow", nil))
+	assert.Equal(t, ecode.Deadline.Error(), w.Header().Get("kratos-status-code"))
+	close(release)
+	wg.Wait()
+
+	// reload the limit by the server config.
+	e.SetConfig(&ServerConfig{
+		Timeout: xtime.Duration(time.Second),
+		Method: map[string]*MethodConfig{
+			"/limit/slow": {MaxInflight: 10, Queue: &aqm.Config{Target: 10, Internal: 100}},
+		},
+	})
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/limit/slow", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, int64(10), e.methodConfig("/limit/slow").MaxInflight)
+}
+
+func TestRouteParamMethodConfig(t *testing.T) {
+	e := newLimitEngine()
+	e.GET("/limit/user/:id", func(c *Context) {
+		deadline, _ := c.Deadline()
+		c.String(200, "%v", time.Until(deadline) < 200*time.Millisecond)
+	})
+	e.SetMethodConfig("/limit/user/:id", &MethodConfig{Timeout: xtime.Duration(200 * time.Millisecond)})
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/limit/user/1", nil))
+	assert.Equal(t, "true", w.Body.String())
+}
+
+func TestMaxInflightHandoff(t *testing.T) {
+	e := newLimitEngine()
+	e.GET("/limit/busy", func(c *Context) {
+		time.Sleep(time.Millisecond)
+		c.String(200, "ok")
+	})
+	e.SetMethodConfig("/limit/busy", &MethodConfig{MaxInflight: 2, Queue: &aqm.Config{Target: 1000, Internal: 1000}})
+
+	// every queued request is woken up by a release.
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, httptest.NewRequest("GET", "/limit/busy", nil))
+			assert.Equal(t, "ok", w.Body.String())
+		}()
+	}
+	wg.Wait()
+	l := e.limiter("/limit/busy", nil)
+	assert.Equal(t, int64(0), l.inflight)
+	assert.Len(t, l.waiters, 0)
+}
+
+func TestBreaker(t *testing.T) {
+	e := newLimitEngine()
+	b := NewBreaker(&breaker.Config{Window: xtime.Duration(time.Second), Bucket: 10, Request: 10, K: 1.5})
+	e.Use(b.Handler())
+	handler := func(c *Context) {
+		c.JSON(nil, ecode.ServerErr)
+	}
+	e.GET("/limit/broken", handler)
+	e.GET("/limit/critical", handler)
+	e.SetMethodConfig("/limit/critical", &MethodConfig{DisableBreaker: true})
+
+	count := func(path string) (unavailable int) {
+		for i := 0; i < 100; i++ {
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			if w.Header().Get("kratos-status-code") == ecode.ServiceUnavailable.Error() {
+				unavailable++
+			}
+		}
+		return
+	}
+	assert.True(t, count("/limit/broken") > 0)
+	assert.Equal(t, 0, count("/limit/critical"))
+}
+
+func TestRateLimiterRouteKey(t *testing.T) {
+	e := newLimitEngine()
+	l := NewRateLimiter(nil)
+	defer l.Close()
+	e.Use(l.Limit())
+	e.GET("/limit/item/:id", func(c *Context) {
+		c.String(200, "ok")
+	})
+	for _, host := range []string{"a.example.com", "b.example.com"} {
+		req := httptest.NewRequest("GET", "/limit/item/"+host, nil)
+		req.Host = host
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	stats := l.group.Stats()
+	assert.Len(t, stats, 1)
+	assert.Contains(t, stats, "limit/item/:id")
+}
@@ -17,12 +17,13 @@ func Logger() HandlerFunc {
 		now := time.Now()
 		ip := metadata.String(c, metadata.RemoteIP)
 		req := c.Request
-		path := req.URL.Path
 		params := req.Form
 		var quota float64
 		if deadline, ok := c.Context.Deadline(); ok {
 			quota = time.Until(deadline).Seconds()
 		}
+		// the request logger is got by log.FromContext in the handlers.
+		logger := bindLogger(c)
 
 		c.Next()
 
@@ -34,30 +35,25 @@ func Logger() HandlerFunc {
 			caller = noUser
 		}
 
-		if len(c.RoutePath) > 0 {
-			_metricServerReqCodeTotal.Inc(c.RoutePath[1:], caller, req.Method, strconv.FormatInt(int64(cerr.Code()), 10))
-			_metricServerReqDur.Observe(int64(dt/time.Millisecond), c.RoutePath[1:], caller, req.Method)
-		}
+		reportRequest(c, caller, cerr, dt)
 
-		lf := log.Infov
+		lv := log.InfoLevel
 		errmsg := ""
 		isSlow := dt >= (time.Millisecond * 500)
 		if err != nil {
 			errmsg = err.Error()
-			lf = log.Errorv
+			lv = log.ErrorLevel
 			if cerr.Code() > 0 {
-				lf = log.Warnv
+				lv = log.WarnLevel
 			}
 		} else {
 			if isSlow {
-				lf = log.Warnv
+				lv = log.WarnLevel
 			}
 		}
-		lf(c,
-			log.KVString("method", req.Method),
+		logger.Logv(lv,
 			log.KVString("ip", ip),
 			log.KVString("user", caller),
-			log.KVString("path", path),
 			log.KVString("params", params.Encode()),
 			log.KVInt("ret", cerr.Code()),
 			log.KVString("msg", cerr.Message()),
@@ -69,3 +65,23 @@ func Logger() HandlerFunc {
 		)
 	}
 }
+
+// bindLogger binds the request logger with the method and path to the context.
+func bindLogger(c *Context) *log.Logger {
+	logger := log.FromContext(c.Context).With(
+		log.KVString("method", c.Request.Method),
+		log.KVString("path", c.Request.URL.Path),
+	)
+	c.Context = log.NewContext(c.Context, logger)
+	return logger
+}
+
+// reportRequest reports the duration and code metrics of the request.
+func reportRequest(c *Context, caller string, cerr ecode.Codes, dt time.Duration) {
+	route := routeLabel(c)
+	if c.engine != nil {
+		caller = c.engine.callerLabel(caller)
+	}
+	_metricServerReqCodeTotal.Inc(route, caller, c.Request.Method, strconv.FormatInt(int64(cerr.Code()), 10))
+	_metricServerReqDur.Observe(int64(dt/time.Millisecond), route, caller, c.Request.Method)
+}
@@ -8,6 +8,8 @@ const (
 )
 
 var (
+	_sizeBuckets = []float64{256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20}
+
 	_metricServerReqDur = metric.NewHistogramVec(&metric.HistogramVecOpts{
 		Namespace: serverNamespace,
 		Subsystem: "requests",
@@ -23,12 +25,42 @@ var (
 		Help:      "http server requests error count.",
 		Labels:    []string{"path", "caller", "method", "code"},
 	})
+	_metricServerReqInflight = metric.NewGaugeVec(&metric.GaugeVecOpts{
+		Namespace: serverNamespace,
+		Subsystem: "requests",
+		Name:      "inflight",
+		Help:      "http server inflight requests.",
+		Labels:    []string{"path", "method"},
+	})
+	_metricServerReqSize = metric.NewHistogramVec(&metric.HistogramVecOpts{
+		Namespace: serverNamespace,
+		Subsystem: "requests",
+		Name:      "request_size_bytes",
+		Help:      "http server request body size(bytes).",
+		Labels:    []string{"path", "method"},
+		Buckets:   _sizeBuckets,
+	})
+	_metricServerRespSize = metric.NewHistogramVec(&metric.HistogramVecOpts{
+		Namespace: serverNamespace,
+		Subsystem: "requests",
+		Name:      "response_size_bytes",
+		Help:      "http server response body size(bytes).",
+		Labels:    []string{"path", "method"},
+		Buckets:   _sizeBuckets,
+	})
 	_metricServerBBR = metric.NewCounterVec(&metric.CounterVecOpts{
 		Namespace: serverNamespace,
 		Subsystem: "",
 		Name:      "bbr_total",
 		Help:      "http server bbr total.",
-		Labels:    []string{"url", "method"},
+		Labels:    []string{"path", "method"},
+	})
+	_metricServerLimit = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: serverNamespace,
+		Subsystem: "",
+		Name:      "limit_total",
+		Help:      "http server route limit rejected total.",
+		Labels:    []string{"path", "method", "reason"},
 	})
 	_metricClientReqDur = metric.NewHistogramVec(&metric.HistogramVecOpts{
 		Namespace: clientNamespace,
@@ -45,4 +77,11 @@ var (
 		Help:      "http client requests code count.",
 		Labels:    []string{"path", "method", "code"},
 	})
+	_metricClientReqRetry = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: clientNamespace,
+		Subsystem: "requests",
+		Name:      "retry_total",
+		Help:      "http client requests retry count.",
+		Labels:    []string{"path", "method"},
+	})
 )
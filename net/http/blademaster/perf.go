@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	"github.com/gisvr/golib/conf/dsn"
+	"github.com/gisvr/golib/net/admin"
 
 	"github.com/pkg/errors"
 )
@@ -24,6 +25,10 @@ func init() {
 
 func startPerf(engine *Engine) {
 	_perfOnce.Do(func() {
+		// the admin server serves pprof with authentication.
+		if admin.Enabled() {
+			return
+		}
 		if os.Getenv("HTTP_PERF") == "" {
 			prefixRouter := engine.Group("/debug/pprof")
 			{
@@ -1,11 +1,11 @@
 package blademaster
 
 import (
-	"fmt"
 	"sync/atomic"
 	"time"
 
 	"github.com/gisvr/golib/log"
+	"github.com/gisvr/golib/net/admin"
 	limit "github.com/gisvr/golib/ratelimit"
 	"github.com/gisvr/golib/ratelimit/bbr"
 )
@@ -14,14 +14,24 @@ import (
 type RateLimiter struct {
 	group   *bbr.Group
 	logTime int64
+
+	unregister func()
 }
 
 // NewRateLimiter return a ratelimit middleware.
 func NewRateLimiter(conf *bbr.Config) (s *RateLimiter) {
-	return &RateLimiter{
+	s = &RateLimiter{
 		group:   bbr.NewGroup(conf),
 		logTime: time.Now().UnixNano(),
 	}
+	s.unregister = admin.RegisterStats("blademaster.bbr", func() interface{} { return s.group.Stats() })
+	return
+}
+
+// Close unregisters the stats of the limiter.
+func (b *RateLimiter) Close() error {
+	b.unregister()
+	return nil
 }
 
 func (b *RateLimiter) printStats(routePath string, limiter limit.Limiter) {
@@ -32,21 +42,27 @@ func (b *RateLimiter) printStats(routePath string, limiter limit.Limiter) {
 	}
 }
 
-// Limit return a bm handler func.
+// Limit return a bm handler func, the routes with DisableRateLimit in the
+// method config are not limited.
 func (b *RateLimiter) Limit() HandlerFunc {
 	return func(c *Context) {
-		uri := fmt.Sprintf("%s://%s%s", c.Request.URL.Scheme, c.Request.Host, c.Request.URL.Path)
-		limiter := b.group.Get(uri)
+		if mc := c.engine.methodConfig(c.RoutePath); mc != nil && mc.DisableRateLimit {
+			return
+		}
+		// the route is the limiter key and the label, the client host and the
+		// path params are not bounded.
+		route := routeLabel(c)
+		limiter := b.group.Get(route)
 		done, err := limiter.Allow(c)
 		if err != nil {
-			_metricServerBBR.Inc(uri, c.Request.Method)
+			_metricServerBBR.Inc(route, c.method)
 			c.JSON(nil, err)
 			c.Abort()
 			return
 		}
 		defer func() {
 			done(limit.DoneInfo{Op: limit.Success})
-			b.printStats(uri, limiter)
+			b.printStats(route, limiter)
 		}()
 		c.Next()
 	}
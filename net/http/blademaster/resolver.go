@@ -0,0 +1,194 @@
+package blademaster
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gisvr/golib/conf/env"
+	"github.com/gisvr/golib/log"
+	"github.com/gisvr/golib/naming"
+	"github.com/gisvr/golib/net/admin"
+	"github.com/gisvr/golib/net/netutil/breaker"
+)
+
+var (
+	_resolverMu sync.RWMutex
+	_resolvers  = make(map[string]naming.Builder)
+)
+
+// RegisterResolver registers the naming builder, the urls of its scheme like
+// discovery://main.account.api/path are sent to the instances of the appid.
+func RegisterResolver(b naming.Builder) {
+	_resolverMu.Lock()
+	_resolvers[b.Scheme()] = b
+	_resolverMu.Unlock()
+}
+
+func resolverBuilder(scheme string) (naming.Builder, bool) {
+	_resolverMu.RLock()
+	b, ok := _resolvers[scheme]
+	_resolverMu.RUnlock()
+	return b, ok
+}
+
+// target is the instances of an appid, which are watched from the resolver
+// and balanced by the picker.
+type target struct {
+	appid    string
+	resolver naming.Resolver
+	breakers *breaker.Group
+	picker   atomic.Value // store picker
+
+	balancer string
+	stats    map[string]*endpointStat
+
+	done       chan struct{}
+	unregister func()
+}
+
+func newTarget(b naming.Builder, appid string, c *ClientConfig) *target {
+	clusters := make(map[string]struct{}, len(c.Cluster))
+	for _, cluster := range c.Cluster {
+		clusters[cluster] = struct{}{}
+	}
+	zone := c.Zone
+	if zone == "" {
+		zone = env.Zone
+	}
+	subset := c.Subset
+	if subset <= 0 {
+		subset = 50
+	}
+	t := &target{
+		appid:    appid,
+		resolver: b.Build(appid, naming.Filter("http", clusters), naming.ScheduleNode(zone), naming.Subset(subset)),
+		breakers: breaker.NewGroup(c.Breaker),
+		balancer: c.Balancer,
+		stats:    make(map[string]*endpointStat),
+		done:     make(chan struct{}),
+	}
+	t.picker.Store(newPicker(t.balancer, nil))
+	t.unregister = admin.RegisterStats("blademaster.target.breaker."+appid, func() interface{} { return t.breakers.Stats() })
+	// fetch the instances which are already known by the resolver.
+	t.fetch(zone)
+	go t.watchproc(zone)
+	return t
+}
+
+// close stops watching the resolver and unregisters the stats of the target.
+func (t *target) close() {
+	close(t.done)
+	t.resolver.Close()
+	t.unregister()
+}
+
+func (t *target) watchproc(zone string) {
+	ch := t.resolver.Watch()
+	for {
+		select {
+		case <-t.done:
+			return
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+			t.fetch(zone)
+		}
+	}
+}
+
+func (t *target) fetch(zone string) {
+	ins, ok := t.resolver.Fetch(context.Background())
+	if !ok {
+		return
+	}
+	instances := ins.Instances[zone]
+	if len(instances) == 0 {
+		for _, value := range ins.Instances {
+			instances = append(instances, value...)
+		}
+	}
+	t.update(instances)
+}
+
+// update replaces the endpoints, the statistics of the remaining ones are kept.
+// It is not safe for concurrent use, which is called by newTarget and watchproc.
+func (t *target) update(instances []*naming.Instance) {
+	if len(instances) == 0 {
+		// keep the last instances when the resolver returns nothing.
+		return
+	}
+	stats := make(map[string]*endpointStat, len(instances))
+	list := make([]*endpoint, 0, len(instances))
+	for _, ins := range instances {
+		var addr string
+		for _, a := range ins.Addrs {
+			if u, err := url.Parse(a); err == nil && u.Scheme == "http" {
+				addr = u.Host
+			}
+		}
+		if _, ok := stats[addr]; addr == "" || ok {
+			continue
+		}
+		weight, _ := strconv.ParseInt(ins.Metadata[naming.MetaWeight], 10, 64)
+		if weight <= 0 {
+			weight = 10
+		}
+		stat, ok := t.stats[addr]
+		if !ok {
+			stat = newEndpointStat()
+		}
+		stats[addr] = stat
+		list = append(list, &endpoint{
+			addr:   addr,
+			weight: weight,
+			color:  ins.Metadata[naming.MetaColor],
+			brk:    t.breakers.Get(addr),
+			stat:   stat,
+		})
+	}
+	if len(list) == 0 {
+		return
+	}
+	t.stats = stats
+	t.picker.Store(newPicker(t.balancer, list))
+	log.Infof("blademaster: resolve %s got %d instances", t.appid, len(list))
+}
+
+// pick returns an endpoint, done must be called with the result of the request.
+func (t *target) pick(c context.Context) (*endpoint, func(error), error) {
+	return t.picker.Load().(picker).pick(c)
+}
+
+// resolve returns the target of the scheme://appid, ok is false if no
+// resolver is registered for the scheme.
+func (client *Client) resolve(scheme, appid string, c *ClientConfig) (t *target, ok bool) {
+	b, ok := resolverBuilder(scheme)
+	if !ok {
+		return
+	}
+	key := fmt.Sprintf("%s://%s", scheme, appid)
+	client.mutex.RLock()
+	t, ok = client.targets[key]
+	client.mutex.RUnlock()
+	if ok {
+		return
+	}
+	// the resolver is built and fetched without the lock, the target which
+	// loses the race is closed.
+	nt := newTarget(b, appid, c)
+	client.mutex.Lock()
+	if t, ok = client.targets[key]; !ok {
+		t = nt
+		client.targets[key] = t
+	}
+	client.mutex.Unlock()
+	if t != nt {
+		nt.close()
+	}
+	return t, true
+}
@@ -0,0 +1,117 @@
+package blademaster
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gisvr/golib/conf/env"
+	"github.com/gisvr/golib/naming"
+	"github.com/gisvr/golib/net/metadata"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockBuilder struct {
+	instances []*naming.Instance
+	resolvers []*mockResolver
+}
+
+func (b *mockBuilder) Build(id string, opts ...naming.BuildOpt) naming.Resolver {
+	ch := make(chan struct{}, 1)
+	ch <- struct{}{}
+	r := &mockResolver{b: b, ch: ch}
+	b.resolvers = append(b.resolvers, r)
+	return r
+}
+
+func (b *mockBuilder) Scheme() string { return "mockdiscovery" }
+
+type mockResolver struct {
+	b      *mockBuilder
+	ch     chan struct{}
+	closed int32
+}
+
+func (r *mockResolver) Fetch(ctx context.Context) (*naming.InstancesInfo, bool) {
+	return &naming.InstancesInfo{Instances: map[string][]*naming.Instance{env.Zone: r.b.instances}}, len(r.b.instances) > 0
+}
+
+func (r *mockResolver) Watch() <-chan struct{} { return r.ch }
+
+func (r *mockResolver) Close() error {
+	atomic.StoreInt32(&r.closed, 1)
+	return nil
+}
+
+func newInstance(ts *httptest.Server, color string) *naming.Instance {
+	return &naming.Instance{
+		AppID:    "main.test.api",
+		Hostname: ts.URL,
+		Addrs:    []string{"grpc://127.0.0.1:9000", strings.Replace(ts.URL, "https", "http", 1)},
+		Metadata: map[string]string{naming.MetaColor: color},
+	}
+}
+
+func TestDiscoveryClient(t *testing.T) {
+	var good, bad, red int64
+	goodSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&good, 1)
+		w.Write([]byte(`{"code":0,"data":"` + r.URL.Path + `"}`))
+	}))
+	defer goodSrv.Close()
+	badSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&bad, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer badSrv.Close()
+	redSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&red, 1)
+		w.Write([]byte(`{"code":0,"data":"red"}`))
+	}))
+	defer redSrv.Close()
+	b := &mockBuilder{instances: []*naming.Instance{
+		newInstance(goodSrv, ""), newInstance(badSrv, ""), newInstance(redSrv, "red"),
+	}}
+	RegisterResolver(b)
+
+	for _, balancer := range []string{"p2c", "wrr"} {
+		atomic.StoreInt64(&good, 0)
+		atomic.StoreInt64(&bad, 0)
+		client := newSignClient(nil)
+		client.SetConfig(&ClientConfig{Host: map[string]*ClientConfig{
+			"main.test.api": {Balancer: balancer, Timeout: client.conf.Timeout},
+		}})
+		var failed int
+		for i := 0; i < 200; i++ {
+			req, _ := http.NewRequest("GET", "mockdiscovery://main.test.api/x/path", nil)
+			res := new(signResp)
+			if err := client.Do(context.TODO(), req, res); err != nil {
+				failed++
+				continue
+			}
+			assert.Equal(t, "/x/path", res.Data)
+			time.Sleep(time.Millisecond)
+		}
+		// the unhealthy instance is avoided after several failures.
+		assert.True(t, atomic.LoadInt64(&bad) < 50, "%s bad: %d", balancer, bad)
+		assert.Equal(t, int64(failed), atomic.LoadInt64(&bad), balancer)
+		assert.Zero(t, atomic.LoadInt64(&red), balancer)
+	}
+
+	// the colored requests are routed to the instances of the color.
+	client := newSignClient(nil)
+	ctx := metadata.NewContext(context.TODO(), metadata.MD{metadata.Color: "red"})
+	req, _ := http.NewRequest("GET", "mockdiscovery://main.test.api/x/path", nil)
+	res := new(signResp)
+	assert.NoError(t, client.Do(ctx, req, res))
+	assert.Equal(t, "red", res.Data)
+
+	// the resolver is closed with the client.
+	assert.NoError(t, client.Close())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&b.resolvers[len(b.resolvers)-1].closed))
+}
@@ -0,0 +1,117 @@
+package blademaster
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	xhttp "net/http"
+	"strconv"
+	"time"
+
+	"github.com/gisvr/golib/net/netutil"
+
+	pkgerr "github.com/pkg/errors"
+)
+
+const (
+	_idempotencyHeader = "Idempotency-Key"
+)
+
+var (
+	_retryStatus  = []int{xhttp.StatusBadGateway, xhttp.StatusServiceUnavailable, xhttp.StatusGatewayTimeout}
+	_retryBackoff = &netutil.BackoffConfig{
+		MaxDelay:  time.Second,
+		BaseDelay: 50 * time.Millisecond,
+		Factor:    2,
+		Jitter:    0.2,
+	}
+)
+
+// RetryConfig is the retry policy of the client. Only the idempotent requests
+// and the ones with the idempotency key header are retried, on the connection
+// errors and the retryable status. All the attempts share the timeout.
+type RetryConfig struct {
+	// Max is the max retries after the first attempt.
+	Max int `yaml:"max"`
+	// Backoff is the delay between the attempts, it is extended by the
+	// Retry-After header of the response.
+	Backoff *netutil.BackoffConfig `yaml:"backoff"`
+	// Status is the retryable http status, default 502, 503 and 504.
+	Status []int `yaml:"status"`
+	// IdempotencyHeader marks the non-idempotent request retryable, default Idempotency-Key.
+	IdempotencyHeader string `yaml:"idempotencyHeader"`
+}
+
+// retryable reports whether the request can be retried.
+func (rc *RetryConfig) retryable(req *xhttp.Request) bool {
+	if rc == nil || rc.Max <= 0 {
+		return false
+	}
+	switch req.Method {
+	case xhttp.MethodGet, xhttp.MethodHead, xhttp.MethodOptions, xhttp.MethodTrace, xhttp.MethodPut, xhttp.MethodDelete:
+		return true
+	}
+	header := rc.IdempotencyHeader
+	if header == "" {
+		header = _idempotencyHeader
+	}
+	return req.Header.Get(header) != ""
+}
+
+// retryStatus reports whether the response status can be retried.
+func (rc *RetryConfig) retryStatus(status int) bool {
+	statuses := rc.Status
+	if len(statuses) == 0 {
+		statuses = _retryStatus
+	}
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay before the next attempt.
+func (rc *RetryConfig) backoff(retries int, retryAfter time.Duration) time.Duration {
+	bc := rc.Backoff
+	if bc == nil {
+		bc = _retryBackoff
+	}
+	delay := bc.Backoff(retries)
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	return delay
+}
+
+// parseRetryAfter parses the Retry-After header in seconds or http date.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := xhttp.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// rewindable makes the body of the request readable again by GetBody.
+func rewindable(req *xhttp.Request) error {
+	if req.Body == nil || req.Body == xhttp.NoBody || req.GetBody != nil {
+		return nil
+	}
+	bs, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return pkgerr.WithStack(err)
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(bs))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(bs)), nil
+	}
+	return nil
+}
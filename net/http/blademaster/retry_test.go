@@ -0,0 +1,87 @@
+package blademaster
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gisvr/golib/net/netutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientRetry(t *testing.T) {
+	var attempts int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		switch n := atomic.AddInt64(&attempts, 1); {
+		case r.URL.Path == "/always":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.URL.Path == "/notfound":
+			w.WriteHeader(http.StatusNotFound)
+		case n == 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.Write([]byte(`{"code":0,"data":"` + string(body) + `"}`))
+		}
+	}))
+	defer ts.Close()
+
+	client := newSignClient(nil)
+	retry := &RetryConfig{Max: 2, Backoff: &netutil.BackoffConfig{BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond, Factor: 2}}
+	client.SetConfig(&ClientConfig{URL: map[string]*ClientConfig{
+		ts.URL + "/retry":    {Timeout: client.conf.Timeout, Retry: retry},
+		ts.URL + "/always":   {Timeout: client.conf.Timeout, Retry: retry},
+		ts.URL + "/notfound": {Timeout: client.conf.Timeout, Retry: retry},
+	}})
+	send := func(method, path, idempotencyKey string) (*signResp, error) {
+		req, _ := http.NewRequest(method, ts.URL+path, ioutil.NopCloser(strings.NewReader("body")))
+		if idempotencyKey != "" {
+			req.Header.Set("Idempotency-Key", idempotencyKey)
+		}
+		res := new(signResp)
+		err := client.Do(context.TODO(), req, res)
+		return res, err
+	}
+
+	// the body is rewound for the retry.
+	res, err := send("PUT", "/retry", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "body", res.Data)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&attempts))
+
+	// the POST is retried with the idempotency key only.
+	atomic.StoreInt64(&attempts, 0)
+	_, err = send("POST", "/retry", "")
+	assert.Error(t, err)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&attempts))
+	atomic.StoreInt64(&attempts, 0)
+	_, err = send("POST", "/retry", "order-1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&attempts))
+
+	// the attempts are limited by the max retries.
+	atomic.StoreInt64(&attempts, 0)
+	_, err = send("GET", "/always", "")
+	assert.Error(t, err)
+	assert.Equal(t, int64(3), atomic.LoadInt64(&attempts))
+
+	// the status not retryable.
+	atomic.StoreInt64(&attempts, 0)
+	_, err = send("GET", "/notfound", "")
+	assert.Error(t, err)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&attempts))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3"))
+	assert.Zero(t, parseRetryAfter("invalid"))
+	d := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.True(t, d > 58*time.Second && d <= time.Minute, d)
+}
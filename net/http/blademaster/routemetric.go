@@ -0,0 +1,128 @@
+package blademaster
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+const (
+	// _unmatchedRoute is the path label of the requests matching no route.
+	_unmatchedRoute = "unmatched"
+	// _otherCaller is the caller label beyond the max callers.
+	_otherCaller = "other"
+	// _defaultMaxCallers is the default max caller label values.
+	_defaultMaxCallers = 100
+)
+
+// routeLabel returns the path label of the request, which is the route
+// template to bound the label values, eg: user/:id rather than user/42.
+func routeLabel(c *Context) string {
+	if len(c.RoutePath) == 0 {
+		return _unmatchedRoute
+	}
+	return c.RoutePath[1:]
+}
+
+// labelSet bounds the values of a label, the values beyond max are
+// reported as other.
+type labelSet struct {
+	mutex  sync.RWMutex
+	values map[string]struct{}
+}
+
+func (s *labelSet) label(v string, max int) string {
+	s.mutex.RLock()
+	_, ok := s.values[v]
+	n := len(s.values)
+	s.mutex.RUnlock()
+	if ok {
+		return v
+	}
+	if n >= max {
+		return _otherCaller
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.values == nil {
+		s.values = make(map[string]struct{})
+	}
+	if _, ok = s.values[v]; !ok && len(s.values) >= max {
+		return _otherCaller
+	}
+	s.values[v] = struct{}{}
+	return v
+}
+
+// callerLabel returns the caller label, the callers beyond the
+// ServerConfig.MaxCallers are reported as other.
+func (engine *Engine) callerLabel(caller string) string {
+	engine.lock.RLock()
+	max := engine.conf.MaxCallers
+	engine.lock.RUnlock()
+	if max <= 0 {
+		max = _defaultMaxCallers
+	}
+	return engine.callers.label(caller, max)
+}
+
+// countedBody counts the read bytes of the request body.
+type countedBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countedBody) Read(p []byte) (n int, err error) {
+	n, err = b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return
+}
+
+// countedWriter counts the written bytes of the response body, and records
+// the status code.
+type countedWriter struct {
+	http.ResponseWriter
+	n      int64
+	status int
+}
+
+func (w *countedWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *countedWriter) Write(p []byte) (n int, err error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err = w.ResponseWriter.Write(p)
+	w.n += int64(n)
+	return
+}
+
+// Flush implements http.Flusher.
+func (w *countedWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// observeRoute reports the inflight requests of the route, and the sizes of
+// the request and response body after the request is done.
+func observeRoute(c *Context) func() {
+	path, method := routeLabel(c), c.Request.Method
+	body := &countedBody{ReadCloser: c.Request.Body}
+	if c.Request.Body != nil {
+		c.Request.Body = body
+	}
+	writer := &countedWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	_metricServerReqInflight.Inc(path, method)
+	return func() {
+		_metricServerReqInflight.Add(-1, path, method)
+		_metricServerReqSize.Observe(body.n, path, method)
+		_metricServerRespSize.Observe(writer.n, path, method)
+	}
+}
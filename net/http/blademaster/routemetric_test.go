@@ -0,0 +1,83 @@
+package blademaster
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+// gather returns the samples of the metric which have the labels.
+func gather(t *testing.T, name string, labels map[string]string) (n int, sum float64) {
+	mfs, err := prometheus.DefaultGatherer.Gather()
+	assert.NoError(t, err)
+	for _, mf := range mfs {
+		if mf.GetName() != name {
+			continue
+		}
+	next:
+		for _, m := range mf.GetMetric() {
+			for _, lp := range m.GetLabel() {
+				if v, ok := labels[lp.GetName()]; ok && v != lp.GetValue() {
+					continue next
+				}
+			}
+			n++
+			switch {
+			case m.GetHistogram() != nil:
+				sum += m.GetHistogram().GetSampleSum()
+			case m.GetGauge() != nil:
+				sum += m.GetGauge().GetValue()
+			case m.GetCounter() != nil:
+				sum += m.GetCounter().GetValue()
+			}
+		}
+	}
+	return
+}
+
+func TestRouteMetrics(t *testing.T) {
+	e := newLimitEngine()
+	e.Use(Logger())
+	e.POST("/metric/user/:id", func(c *Context) {
+		c.String(200, "%s", c.Request.Form.Get("name"))
+	})
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("POST", fmt.Sprintf("/metric/user/%d", i), strings.NewReader("name=kratos"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/metric/none/42", nil))
+
+	route := map[string]string{"path": "metric/user/:id"}
+	n, sum := gather(t, "http_server_requests_request_size_bytes", route)
+	assert.Equal(t, 1, n, "labeled by the route template")
+	assert.Equal(t, float64(3*len("name=kratos")), sum)
+	_, sum = gather(t, "http_server_requests_response_size_bytes", route)
+	assert.Equal(t, float64(3*len("kratos")), sum)
+	_, sum = gather(t, "http_server_requests_inflight", route)
+	assert.Equal(t, float64(0), sum)
+	n, _ = gather(t, "http_server_requests_code_total", map[string]string{"path": _unmatchedRoute})
+	assert.Equal(t, 1, n)
+}
+
+func TestLabelSet(t *testing.T) {
+	s := &labelSet{}
+	assert.Equal(t, "a", s.label("a", 2))
+	assert.Equal(t, "b", s.label("b", 2))
+	assert.Equal(t, _otherCaller, s.label("c", 2))
+	assert.Equal(t, "a", s.label("a", 2))
+	assert.Equal(t, "c", s.label("c", 3), "the max is reloaded")
+}
+
+func TestDashboard(t *testing.T) {
+	bs, err := Dashboard("Prometheus")
+	assert.NoError(t, err)
+	committed, err := ioutil.ReadFile("dashboard.json")
+	assert.NoError(t, err)
+	assert.Equal(t, string(bs)+"\n", string(committed), "dashboard.json is generated by Dashboard(\"Prometheus\")")
+}
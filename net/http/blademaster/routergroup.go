@@ -1,6 +1,7 @@
 package blademaster
 
 import (
+	"net/http"
 	"regexp"
 )
 
@@ -21,6 +22,10 @@ type IRoutes interface {
 	POST(string, ...HandlerFunc) IRoutes
 	PUT(string, ...HandlerFunc) IRoutes
 	DELETE(string, ...HandlerFunc) IRoutes
+
+	StaticFile(string, string) IRoutes
+	Static(string, string) IRoutes
+	StaticFS(string, http.FileSystem) IRoutes
 }
 
 // RouterGroup is used internally to configure router, a RouterGroup is associated with a prefix
@@ -31,6 +36,7 @@ type RouterGroup struct {
 	engine     *Engine
 	root       bool
 	baseConfig *MethodConfig
+	static     *StaticConfig
 }
 
 var _ IRouter = &RouterGroup{}
@@ -57,6 +63,7 @@ func (group *RouterGroup) Group(relativePath string, handlers ...HandlerFunc) *R
 		basePath: group.calculateAbsolutePath(relativePath),
 		engine:   group.engine,
 		root:     false,
+		static:   group.static,
 	}
 }
 
@@ -2,6 +2,7 @@ package blademaster
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"net"
@@ -14,7 +15,9 @@ import (
 	"time"
 
 	"github.com/gisvr/golib/conf/dsn"
+	"github.com/gisvr/golib/container/queue/aqm"
 	"github.com/gisvr/golib/log"
+	"github.com/gisvr/golib/net/admin"
 	"github.com/gisvr/golib/net/criticality"
 	"github.com/gisvr/golib/net/ip"
 	"github.com/gisvr/golib/net/metadata"
@@ -33,6 +36,12 @@ var (
 	_httpDSN       string
 	default405Body = []byte("405 method not allowed")
 	default404Body = []byte("404 page not found")
+
+	// _engines are the engines not shut down, whose metadata are served by
+	// the /metadata of the admin server.
+	_adminOnce   sync.Once
+	_enginesLock sync.RWMutex
+	_engines     = make(map[*Engine]struct{})
 )
 
 func init() {
@@ -79,11 +88,35 @@ type ServerConfig struct {
 	Timeout      xtime.Duration `dsn:"query.timeout"`
 	ReadTimeout  xtime.Duration `dsn:"query.readTimeout"`
 	WriteTimeout xtime.Duration `dsn:"query.writeTimeout"`
+	// MaxCallers is the max caller label values of the request metrics, the
+	// excess callers are reported as other, default 100.
+	MaxCallers int `dsn:"query.maxCallers"`
+	// Method is the method configs keyed by the route path, it takes
+	// precedence over the ones set by SetMethodConfig and is reloaded by SetConfig.
+	Method map[string]*MethodConfig `dsn:"-"`
 }
 
-// MethodConfig is
+// MethodConfig is the per route config, the zero value of a field means no limit.
 type MethodConfig struct {
+	// Timeout overrides the server timeout of the route.
 	Timeout xtime.Duration
+	// ReadTimeout is the deadline of reading the request body.
+	ReadTimeout xtime.Duration
+	// WriteTimeout is the deadline of writing the response.
+	WriteTimeout xtime.Duration
+	// MaxBodySize is the max bytes of the request body, the larger requests
+	// are rejected with 413.
+	MaxBodySize int64
+	// MaxInflight is the max concurrent requests of the route, the excess
+	// requests wait in a CoDel queue and are rejected with ecode.LimitExceed
+	// when the queue is congested.
+	MaxInflight int64
+	// Queue is the CoDel queue config of MaxInflight.
+	Queue *aqm.Config
+	// DisableRateLimit opts the route out of the RateLimiter middleware.
+	DisableRateLimit bool
+	// DisableBreaker opts the route out of the Breaker middleware.
+	DisableBreaker bool
 }
 
 // Start listen and serve bm engine by given DSN.
@@ -129,8 +162,11 @@ type Engine struct {
 
 	pcLock        sync.RWMutex
 	methodConfigs map[string]*MethodConfig
+	limiters      map[string]*inflightLimiter
+	callers       labelSet
 
 	injections []injection
+	unregister func()
 
 	// If enabled, the url.RawPath will be used to find parameters.
 	UseRawPath bool
@@ -160,6 +196,8 @@ type injection struct {
 }
 
 // NewServer returns a new blank Engine instance without any middleware attached.
+// The /metrics, /metadata and pprof routes are only served by the admin server
+// if admin.Init is called before.
 func NewServer(conf *ServerConfig) *Engine {
 	if conf == nil {
 		if !flag.Parsed() {
@@ -177,6 +215,7 @@ func NewServer(conf *ServerConfig) *Engine {
 		trees:                  make(methodTrees, 0, 9),
 		metastore:              make(map[string]map[string]interface{}),
 		methodConfigs:          make(map[string]*MethodConfig),
+		limiters:               make(map[string]*inflightLimiter),
 		HandleMethodNotAllowed: true,
 		injections:             make([]injection, 0),
 	}
@@ -184,9 +223,30 @@ func NewServer(conf *ServerConfig) *Engine {
 		panic(err)
 	}
 	engine.RouterGroup.engine = engine
-	// NOTE add prometheus monitor location
-	engine.addRoute("GET", "/metrics", monitor())
-	engine.addRoute("GET", "/metadata", engine.metadata())
+	// the authenticated admin server serves the metrics and the metadata if
+	// it is enabled, which must be initialized before the engines.
+	if !admin.Enabled() {
+		// NOTE add prometheus monitor location
+		engine.addRoute("GET", "/metrics", monitor())
+		engine.addRoute("GET", "/metadata", engine.metadata())
+	}
+	_adminOnce.Do(func() {
+		admin.Handle("/metadata", http.HandlerFunc(adminMetadata))
+	})
+	_enginesLock.Lock()
+	_engines[engine] = struct{}{}
+	_enginesLock.Unlock()
+	unregister := admin.RegisterConfig("blademaster.server", func() interface{} {
+		engine.lock.RLock()
+		defer engine.lock.RUnlock()
+		return engine.conf
+	})
+	engine.unregister = func() {
+		unregister()
+		_enginesLock.Lock()
+		delete(_engines, engine)
+		_enginesLock.Unlock()
+	}
 	engine.NoRoute(func(c *Context) {
 		c.Bytes(404, "text/plain", default404Body)
 		c.Abort()
@@ -233,12 +293,18 @@ func (engine *Engine) addRoute(method, path string, handlers ...HandlerFunc) {
 		engine.trees = append(engine.trees, methodTree{method: method, root: root})
 	}
 
-	prelude := func(c *Context) {
+	handlers = append([]HandlerFunc{engine.prelude(method, path)}, handlers...)
+	root.addRoute(path, handlers)
+}
+
+// prelude returns the first handler of the route, it applies the method config
+// of the route before calling the rest handlers.
+func (engine *Engine) prelude(method, path string) HandlerFunc {
+	return func(c *Context) {
 		c.method = method
 		c.RoutePath = path
+		engine.handleRoute(c)
 	}
-	handlers = append([]HandlerFunc{prelude}, handlers...)
-	root.addRoute(path, handlers)
 }
 
 func (engine *Engine) prepareHandler(c *Context) {
@@ -284,15 +350,25 @@ func (engine *Engine) prepareHandler(c *Context) {
 }
 
 func (engine *Engine) handleContext(c *Context) {
-	var cancel func()
 	req := c.Request
-	ctype := req.Header.Get("Content-Type")
-	switch {
-	case strings.Contains(ctype, "multipart/form-data"):
-		req.ParseMultipartForm(defaultMaxMemory)
-	default:
-		req.ParseForm()
+	md := metadata.MD{
+		metadata.RemoteIP:    remoteIP(req),
+		metadata.RemotePort:  remotePort(req),
+		metadata.Criticality: string(criticality.Critical),
 	}
+	parseMetadataTo(req, md)
+	c.Context = metadata.NewContext(context.Background(), md)
+	engine.prepareHandler(c)
+	c.Next()
+}
+
+// handleRoute limits the request by the method config of c.RoutePath, parses
+// the form, and calls the rest handlers with the timeout.
+func (engine *Engine) handleRoute(c *Context) {
+	var cancel func()
+	defer observeRoute(c)()
+	req := c.Request
+	start := time.Now()
 	// get derived timeout from http request header,
 	// compare with the engine configured,
 	// and use the minimum one
@@ -300,29 +376,85 @@ func (engine *Engine) handleContext(c *Context) {
 	tm := time.Duration(engine.conf.Timeout)
 	engine.lock.RUnlock()
 	// the method config is preferred
-	if pc := engine.methodConfig(c.Request.URL.Path); pc != nil {
-		tm = time.Duration(pc.Timeout)
+	mc := engine.methodConfig(c.RoutePath)
+	if mc == nil {
+		mc = &MethodConfig{}
+	}
+	if mc.Timeout > 0 {
+		tm = time.Duration(mc.Timeout)
 	}
 	if ctm := timeout(req); ctm > 0 && tm > ctm {
 		tm = ctm
 	}
-	md := metadata.MD{
-		metadata.RemoteIP:    remoteIP(req),
-		metadata.RemotePort:  remotePort(req),
-		metadata.Criticality: string(criticality.Critical),
+	var body *limitedBody
+	if mc.MaxBodySize > 0 {
+		if req.ContentLength > mc.MaxBodySize {
+			engine.rejectTooLarge(c)
+			return
+		}
+		body = &limitedBody{ReadCloser: req.Body, n: mc.MaxBodySize}
+		req.Body = body
+	}
+	if mc.ReadTimeout > 0 {
+		req.Body = &deadlineBody{ReadCloser: req.Body, deadline: start.Add(time.Duration(mc.ReadTimeout))}
+	}
+	if mc.WriteTimeout > 0 {
+		c.Writer = &deadlineWriter{ResponseWriter: c.Writer, deadline: start.Add(time.Duration(mc.WriteTimeout))}
+	}
+	ctype := req.Header.Get("Content-Type")
+	switch {
+	case strings.Contains(ctype, "multipart/form-data"):
+		req.ParseMultipartForm(defaultMaxMemory)
+	default:
+		req.ParseForm()
+	}
+	if body != nil && body.exceeded {
+		engine.rejectTooLarge(c)
+		return
 	}
-	parseMetadataTo(req, md)
-	ctx := metadata.NewContext(context.Background(), md)
 	if tm > 0 {
-		c.Context, cancel = context.WithTimeout(ctx, tm)
+		c.Context, cancel = context.WithTimeout(c.Context, tm)
 	} else {
-		c.Context, cancel = context.WithCancel(ctx)
+		c.Context, cancel = context.WithCancel(c.Context)
 	}
 	defer cancel()
-	engine.prepareHandler(c)
+	if mc.MaxInflight > 0 {
+		limiter := engine.limiter(c.RoutePath, mc.Queue)
+		if err := limiter.acquire(c, mc.MaxInflight); err != nil {
+			_metricServerLimit.Inc(routeLabel(c), c.method, "inflight")
+			c.JSON(nil, err)
+			c.Abort()
+			return
+		}
+		defer limiter.release()
+	}
 	c.Next()
 }
 
+func (engine *Engine) rejectTooLarge(c *Context) {
+	_metricServerLimit.Inc(routeLabel(c), c.method, "body")
+	c.Writer.Header().Set("Connection", "close")
+	c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+}
+
+// limiter returns the inflight limiter of the path, the queue config is
+// reloaded if it is changed.
+func (engine *Engine) limiter(path string, conf *aqm.Config) *inflightLimiter {
+	engine.pcLock.RLock()
+	l, ok := engine.limiters[path]
+	engine.pcLock.RUnlock()
+	if !ok {
+		engine.pcLock.Lock()
+		if l, ok = engine.limiters[path]; !ok {
+			l = newInflightLimiter(conf)
+			engine.limiters[path] = l
+		}
+		engine.pcLock.Unlock()
+	}
+	l.reload(conf)
+	return l
+}
+
 // SetConfig is used to set the engine configuration.
 // Only the valid config will be loaded.
 func (engine *Engine) SetConfig(conf *ServerConfig) (err error) {
@@ -339,8 +471,14 @@ func (engine *Engine) SetConfig(conf *ServerConfig) (err error) {
 }
 
 func (engine *Engine) methodConfig(path string) *MethodConfig {
+	engine.lock.RLock()
+	mc, ok := engine.conf.Method[path]
+	engine.lock.RUnlock()
+	if ok {
+		return mc
+	}
 	engine.pcLock.RLock()
-	mc := engine.methodConfigs[path]
+	mc = engine.methodConfigs[path]
 	engine.pcLock.RUnlock()
 	return mc
 }
@@ -361,6 +499,7 @@ func (engine *Engine) Server() *http.Server {
 
 // Shutdown the http server without interrupting active connections.
 func (engine *Engine) Shutdown(ctx context.Context) error {
+	engine.unregister()
 	server := engine.Server()
 	if server == nil {
 		return errors.New("blademaster: no server")
@@ -468,6 +607,20 @@ func (engine *Engine) metadata() HandlerFunc {
 	}
 }
 
+// adminMetadata serves the metadata of all the engines on the admin server.
+func adminMetadata(w http.ResponseWriter, r *http.Request) {
+	meta := make(map[string]map[string]interface{})
+	_enginesLock.RLock()
+	for engine := range _engines {
+		for path, m := range engine.metastore {
+			meta[path] = m
+		}
+	}
+	_enginesLock.RUnlock()
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(meta)
+}
+
 // Inject is
 func (engine *Engine) Inject(pattern string, handlers ...HandlerFunc) {
 	engine.injections = append(engine.injections, injection{
@@ -507,9 +660,9 @@ func (engine *Engine) NoMethod(handlers ...HandlerFunc) {
 }
 
 func (engine *Engine) rebuild404Handlers() {
-	engine.allNoRoute = engine.combineHandlers(engine.noRoute)
+	engine.allNoRoute = append([]HandlerFunc{engine.prelude("", "")}, engine.combineHandlers(engine.noRoute)...)
 }
 
 func (engine *Engine) rebuild405Handlers() {
-	engine.allNoMethod = engine.combineHandlers(engine.noMethod)
+	engine.allNoMethod = append([]HandlerFunc{engine.prelude("", "")}, engine.combineHandlers(engine.noMethod)...)
 }
@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	criticalityPkg "github.com/gisvr/golib/net/criticality"
+	"github.com/gisvr/golib/net/admin"
 	"github.com/gisvr/golib/net/metadata"
 	xtime "github.com/gisvr/golib/time"
 
@@ -136,3 +138,13 @@ func TestNoneCriticality(t *testing.T) {
 		assert.Equal(t, testCase.expected, criticalityPkg.Criticality(body))
 	}
 }
+
+func TestAdminEnabledRoutes(t *testing.T) {
+	assert.NoError(t, admin.Init(&admin.Config{Addr: "127.0.0.1:0", Token: "secret"}))
+	e := NewServer(&ServerConfig{Timeout: xtime.Duration(time.Second)})
+	for _, path := range []string{"/metrics", "/metadata"} {
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+	}
+}
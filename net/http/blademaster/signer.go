@@ -0,0 +1,131 @@
+package blademaster
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	xhttp "net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gisvr/golib/net/auth"
+
+	pkgerr "github.com/pkg/errors"
+)
+
+// Signer signs the outgoing requests of the Client.
+type Signer interface {
+	Sign(req *xhttp.Request) error
+}
+
+// HMACSigner signs the requests by auth.Sign, which are verified by the
+// auth.HMAC verifier of the server.
+type HMACSigner struct {
+	AppKey string
+	Secret string
+	// Offset is added to the local clock, it corrects the known clock skew against the server.
+	Offset time.Duration
+}
+
+var _ Signer = &HMACSigner{}
+
+// NewHMACSigner returns a hmac signer of the appkey.
+func NewHMACSigner(appkey, secret string) *HMACSigner {
+	return &HMACSigner{AppKey: appkey, Secret: secret}
+}
+
+// Sign adds appkey, ts, nonce and sign to the query of the GET requests and
+// to the body of the form POST requests, or to the headers for other bodies
+// which are signed by the content-sha256 header. The multipart body and the
+// body without GetBody are not hashed, see contentSHA256.
+func (s *HMACSigner) Sign(req *xhttp.Request) (err error) {
+	nonce := make([]byte, 16)
+	if _, err = rand.Read(nonce); err != nil {
+		return pkgerr.WithStack(err)
+	}
+	signParams := url.Values{}
+	signParams.Set(auth.ParamAppKey, s.AppKey)
+	signParams.Set(auth.ParamTs, strconv.FormatInt(time.Now().Add(s.Offset).Unix(), 10))
+	signParams.Set(auth.ParamNonce, hex.EncodeToString(nonce))
+
+	query := req.URL.Query()
+	isForm := req.Body != nil && strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded")
+	switch {
+	case req.Body == nil || req.Body == xhttp.NoBody || req.Method == xhttp.MethodGet:
+		for k, v := range signParams {
+			query[k] = v
+		}
+		query.Set(auth.ParamSign, auth.Sign(s.Secret, req.Method, req.URL.Path, query))
+		req.URL.RawQuery = query.Encode()
+	case isForm:
+		var bs []byte
+		if bs, err = ioutil.ReadAll(req.Body); err != nil {
+			return pkgerr.WithStack(err)
+		}
+		req.Body.Close()
+		var form url.Values
+		if form, err = url.ParseQuery(string(bs)); err != nil {
+			return pkgerr.WithStack(err)
+		}
+		for k, v := range signParams {
+			form[k] = v
+		}
+		// the server verifies the form and the query together as http.Request.Form.
+		all := url.Values{}
+		for k, v := range form {
+			all[k] = v
+		}
+		for k, v := range query {
+			all[k] = append(all[k], v...)
+		}
+		form.Set(auth.ParamSign, auth.Sign(s.Secret, req.Method, req.URL.Path, all))
+		body := form.Encode()
+		req.Body = ioutil.NopCloser(strings.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader(body)), nil
+		}
+		req.ContentLength = int64(len(body))
+	default:
+		var hash string
+		if hash, err = contentSHA256(req); err != nil {
+			return
+		}
+		if hash != "" {
+			signParams.Set(auth.ParamContentSHA256, hash)
+			req.Header.Set(auth.HeaderContentSHA256, hash)
+		}
+		for k, v := range signParams {
+			query[k] = v
+		}
+		req.Header.Set(auth.HeaderAppKey, signParams.Get(auth.ParamAppKey))
+		req.Header.Set(auth.HeaderTs, signParams.Get(auth.ParamTs))
+		req.Header.Set(auth.HeaderNonce, signParams.Get(auth.ParamNonce))
+		req.Header.Set(auth.HeaderSign, auth.Sign(s.Secret, req.Method, req.URL.Path, query))
+	}
+	return
+}
+
+// contentSHA256 returns the content-sha256 of the body without buffering it.
+// The hash set in the header by the caller is kept, the multipart body is not
+// hashed as the server verifies it by nothing, and the body which can not be
+// read again by GetBody is streamed as auth.UnsignedPayload.
+func contentSHA256(req *xhttp.Request) (string, error) {
+	if hash := req.Header.Get(auth.HeaderContentSHA256); hash != "" {
+		return hash, nil
+	}
+	if strings.Contains(req.Header.Get("Content-Type"), "multipart/form-data") {
+		return "", nil
+	}
+	if req.GetBody == nil {
+		return auth.UnsignedPayload, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return "", pkgerr.WithStack(err)
+	}
+	defer body.Close()
+	return auth.ContentSHA256Reader(body)
+}
@@ -0,0 +1,149 @@
+package blademaster
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gisvr/golib/ecode"
+	"github.com/gisvr/golib/net/auth"
+	"github.com/gisvr/golib/net/netutil/breaker"
+	xtime "github.com/gisvr/golib/time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type signResp struct {
+	Code int    `json:"code"`
+	Data string `json:"data"`
+}
+
+// captureSigner keeps the last signed request.
+type captureSigner struct {
+	Signer
+	req *http.Request
+}
+
+func (s *captureSigner) Sign(req *http.Request) error {
+	s.req = req
+	return s.Signer.Sign(req)
+}
+
+func newSignClient(signer Signer) *Client {
+	client := NewClient(&ClientConfig{
+		Dial:    xtime.Duration(time.Second),
+		Timeout: xtime.Duration(time.Second),
+		Breaker: &breaker.Config{
+			Window:  xtime.Duration(10 * time.Second),
+			Bucket:  10,
+			Request: 100,
+			K:       1.5,
+		},
+	})
+	client.SetSigner(signer)
+	return client
+}
+
+func TestHMACSigner(t *testing.T) {
+	e := NewServer(nil)
+	a := NewAuth(auth.NewHMAC(&auth.HMACConfig{
+		Apps: map[string]*auth.App{"app": {Secret: "secret"}},
+	}))
+	handler := func(c *Context) {
+		pr, _ := auth.FromContext(c)
+		c.JSON(pr.ID+":"+c.Request.Form.Get("name"), nil)
+	}
+	g := e.Group("/sign", a.Require("authenticated"))
+	g.GET("/get", handler)
+	g.POST("/post", handler)
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	signer := &captureSigner{Signer: NewHMACSigner("app", "secret")}
+	client := newSignClient(signer)
+	params := url.Values{"name": {"kratos"}}
+	for _, method := range []string{"GET", "POST"} {
+		path := "/sign/" + map[string]string{"GET": "get", "POST": "post"}[method]
+		req, err := client.NewRequest(method, ts.URL+path, "", params)
+		assert.NoError(t, err)
+		res := new(signResp)
+		assert.NoError(t, client.Do(context.TODO(), req, res))
+		assert.Equal(t, 0, res.Code, method)
+		assert.Equal(t, "app:kratos", res.Data, method)
+	}
+
+	// json body are signed by the headers.
+	req, _ := http.NewRequest("POST", ts.URL+"/sign/post?name=bm", bytes.NewBufferString(`{"name":"json"}`))
+	req.Header.Set("Content-Type", "application/json")
+	res := new(signResp)
+	assert.NoError(t, client.Do(context.TODO(), req, res))
+	assert.Equal(t, "app:bm", res.Data)
+
+	// replay the signed request.
+	replay, _ := http.NewRequest("POST", signer.req.URL.String(), bytes.NewBufferString(`{"name":"json"}`))
+	replay.Header = signer.req.Header.Clone()
+	resp, err := http.DefaultClient.Do(replay)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, ecode.Unauthorized.Error(), resp.Header.Get("kratos-status-code"))
+
+	// the json body is tampered after signed.
+	req, _ = http.NewRequest("POST", ts.URL+"/sign/post", bytes.NewBufferString(`{"name":"json"}`))
+	req.Header.Set("Content-Type", "application/json")
+	assert.NoError(t, signer.Sign(req))
+	assert.Equal(t, auth.ContentSHA256([]byte(`{"name":"json"}`)), req.Header.Get(auth.HeaderContentSHA256))
+	req.Body = ioutil.NopCloser(bytes.NewBufferString(`{"name":"evil"}`))
+	req.ContentLength = -1
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, ecode.Unauthorized.Error(), resp.Header.Get("kratos-status-code"))
+
+	// the multipart body is not hashed on both sides.
+	mbody := new(bytes.Buffer)
+	mw := multipart.NewWriter(mbody)
+	mw.WriteField("name", "multipart")
+	fw, _ := mw.CreateFormFile("file", "a.txt")
+	fw.Write([]byte("file"))
+	mw.Close()
+	req, _ = http.NewRequest("POST", ts.URL+"/sign/post", bytes.NewReader(mbody.Bytes()))
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	res = new(signResp)
+	assert.NoError(t, client.Do(context.TODO(), req, res))
+	assert.Equal(t, "app:multipart", res.Data)
+	assert.Empty(t, signer.req.Header.Get(auth.HeaderContentSHA256))
+
+	// the streamed body is signed as unsigned payload without buffered.
+	req, _ = client.NewUploadRequest("POST", ts.URL+"/sign/post?name=upload", "application/octet-stream", ioutil.NopCloser(bytes.NewReader([]byte("stream"))), 6, nil)
+	res = new(signResp)
+	assert.NoError(t, client.Do(context.TODO(), req, res))
+	assert.Equal(t, "app:upload", res.Data)
+	assert.Equal(t, auth.UnsignedPayload, signer.req.Header.Get(auth.HeaderContentSHA256))
+
+	// the body beyond the limit is not read before authenticated.
+	req, _ = http.NewRequest("POST", ts.URL+"/sign/post", bytes.NewReader(make([]byte, _maxAuthBody+1)))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	res = new(signResp)
+	assert.NoError(t, client.Do(context.TODO(), req, res))
+	assert.Equal(t, ecode.Unauthorized.Code(), res.Code)
+
+	// the wrong secret.
+	client = newSignClient(NewHMACSigner("app", "wrong"))
+	req, _ = client.NewRequest("GET", ts.URL+"/sign/get", "", params)
+	res = new(signResp)
+	assert.NoError(t, client.Do(context.TODO(), req, res))
+	assert.Equal(t, ecode.Unauthorized.Code(), res.Code)
+
+	// the clock skew beyond the tolerance.
+	client = newSignClient(&HMACSigner{AppKey: "app", Secret: "secret", Offset: -time.Hour})
+	req, _ = client.NewRequest("GET", ts.URL+"/sign/get", "", params)
+	res = new(signResp)
+	assert.NoError(t, client.Do(context.TODO(), req, res))
+	assert.Equal(t, ecode.Unauthorized.Code(), res.Code)
+}
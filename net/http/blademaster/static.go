@@ -0,0 +1,192 @@
+package blademaster
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	xtime "github.com/gisvr/golib/time"
+)
+
+// StaticConfig is the static file serving config.
+type StaticConfig struct {
+	// Index is the file served for a directory request, "index.html" by default.
+	// Set it to "-" to disable the index file lookup.
+	Index string
+	// Browse enables directory listing when the directory has no index file.
+	// Directory listing is disabled by default.
+	Browse bool
+	// MaxAge is the max-age of the Cache-Control header, no Cache-Control
+	// header will be set if it is zero.
+	MaxAge xtime.Duration
+	// CacheControl overrides the Cache-Control header generated by MaxAge.
+	CacheControl string
+}
+
+const _defaultIndexFile = "index.html"
+
+var _defaultStaticConfig = &StaticConfig{Index: _defaultIndexFile}
+
+// Dir returns a http.FileSystem that can be used by StaticFS.
+// The requested name is always cleaned before it is opened, so the files
+// outside of root can not be accessed.
+func Dir(root string) http.FileSystem {
+	return http.Dir(root)
+}
+
+// StaticFile registers a single route in order to serve a single file of the local filesystem.
+// router.StaticFile("favicon.ico", "./resources/favicon.ico")
+func (group *RouterGroup) StaticFile(relativePath, filePath string) IRoutes {
+	if strings.Contains(relativePath, ":") || strings.Contains(relativePath, "*") {
+		panic("blademaster: URL parameters can not be used when serving a static file")
+	}
+	fs := Dir(filepath.Dir(filePath))
+	name := "/" + filepath.Base(filePath)
+	conf := group.staticConfig()
+	handler := func(c *Context) {
+		serveStatic(c, fs, name, conf)
+	}
+	group.GET(relativePath, handler)
+	group.HEAD(relativePath, handler)
+	return group.returnObj()
+}
+
+// Static serves files from the given file system root.
+// Internally a http.FileSystem is used, therefore a bare 404 status is written
+// instead of calling the Router's NoRoute handlers.
+// router.Static("/static", "/var/www")
+func (group *RouterGroup) Static(relativePath, root string) IRoutes {
+	return group.StaticFS(relativePath, Dir(root))
+}
+
+// StaticFS works just like `Static()` but a custom `http.FileSystem` can be used instead,
+// e.g. an embedded asset file system.
+func (group *RouterGroup) StaticFS(relativePath string, fs http.FileSystem) IRoutes {
+	if strings.Contains(relativePath, ":") || strings.Contains(relativePath, "*") {
+		panic("blademaster: URL parameters can not be used when serving a static folder")
+	}
+	handler := group.createStaticHandler(fs)
+	urlPattern := path.Join(relativePath, "/*filepath")
+
+	group.GET(urlPattern, handler)
+	group.HEAD(urlPattern, handler)
+	return group.returnObj()
+}
+
+// SetStaticConfig is used to set the config of static routes registered after it.
+func (group *RouterGroup) SetStaticConfig(config *StaticConfig) *RouterGroup {
+	group.static = config
+	return group
+}
+
+func (group *RouterGroup) staticConfig() *StaticConfig {
+	if group.static != nil {
+		return group.static
+	}
+	return _defaultStaticConfig
+}
+
+func (group *RouterGroup) createStaticHandler(fs http.FileSystem) HandlerFunc {
+	conf := group.staticConfig()
+	return func(c *Context) {
+		// NOTE cleanPath eliminates all the '..' elements, so the
+		// requested name never escapes from the root of fs.
+		name := cleanPath(c.Params.ByName("filepath"))
+		serveStatic(c, fs, name, conf)
+	}
+}
+
+func serveStatic(c *Context, fs http.FileSystem, name string, conf *StaticConfig) {
+	f, err := fs.Open(name)
+	if err != nil {
+		c.AbortWithStatus(staticErrorCode(err))
+		return
+	}
+	defer f.Close()
+	d, err := f.Stat()
+	if err != nil {
+		c.AbortWithStatus(staticErrorCode(err))
+		return
+	}
+	if d.IsDir() {
+		// redirect to the canonical path so that relative links work.
+		if u := c.Request.URL.Path; !strings.HasSuffix(u, "/") {
+			c.Redirect(http.StatusMovedPermanently, path.Base(u)+"/")
+			c.Abort()
+			return
+		}
+		index := conf.Index
+		if index == "" {
+			index = _defaultIndexFile
+		}
+		if index != "-" {
+			if ff, err := fs.Open(path.Join(name, index)); err == nil {
+				defer ff.Close()
+				if dd, err := ff.Stat(); err == nil && !dd.IsDir() {
+					serveStaticContent(c, ff, dd, conf)
+					return
+				}
+			}
+		}
+		if !conf.Browse {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		listDir(c, f)
+		return
+	}
+	serveStaticContent(c, f, d, conf)
+}
+
+func serveStaticContent(c *Context, f http.File, d os.FileInfo, conf *StaticConfig) {
+	header := c.Writer.Header()
+	header.Set("ETag", fmt.Sprintf(`W/"%x-%x"`, d.ModTime().Unix(), d.Size()))
+	if conf.CacheControl != "" {
+		header.Set("Cache-Control", conf.CacheControl)
+	} else if conf.MaxAge > 0 {
+		header.Set("Cache-Control", "public, max-age="+strconv.FormatInt(int64(time.Duration(conf.MaxAge)/time.Second), 10))
+	}
+	// ServeContent handles Last-Modified, If-None-Match, If-Modified-Since and Range requests.
+	http.ServeContent(c.Writer, c.Request, d.Name(), d.ModTime(), f)
+	c.Abort()
+}
+
+func listDir(c *Context, f http.File) {
+	dirs, err := f.Readdir(-1)
+	if err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].Name() < dirs[j].Name() })
+	var b strings.Builder
+	b.WriteString("<pre>\n")
+	for _, d := range dirs {
+		name := d.Name()
+		if d.IsDir() {
+			name += "/"
+		}
+		u := url.URL{Path: name}
+		fmt.Fprintf(&b, "<a href=\"%s\">%s</a>\n", u.String(), html.EscapeString(name))
+	}
+	b.WriteString("</pre>\n")
+	c.Bytes(http.StatusOK, "text/html; charset=utf-8", []byte(b.String()))
+	c.Abort()
+}
+
+func staticErrorCode(err error) int {
+	if os.IsNotExist(err) {
+		return http.StatusNotFound
+	}
+	if os.IsPermission(err) {
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,116 @@
+package blademaster
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupStatic(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "bm-static")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello blademaster"), 0644))
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sub", "a.txt"), []byte("a"), 0644))
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "site"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "site", "index.html"), []byte("<html></html>"), 0644))
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func doStatic(e *Engine, method, path string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	return w
+}
+
+func TestStatic(t *testing.T) {
+	dir, clean := setupStatic(t)
+	defer clean()
+
+	e := NewServer(nil)
+	e.Static("/static", dir)
+	e.StaticFile("/hello", filepath.Join(dir, "hello.txt"))
+
+	w := doStatic(e, "GET", "/static/hello.txt", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "hello blademaster", w.Body.String())
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.NotEmpty(t, w.Header().Get("Last-Modified"))
+
+	w = doStatic(e, "GET", "/static/hello.txt", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = doStatic(e, "GET", "/static/hello.txt", map[string]string{"Range": "bytes=0-4"})
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "hello", w.Body.String())
+
+	w = doStatic(e, "HEAD", "/static/hello.txt", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	w = doStatic(e, "GET", "/hello", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "hello blademaster", w.Body.String())
+
+	w = doStatic(e, "GET", "/static/site/", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "<html></html>", w.Body.String())
+
+	w = doStatic(e, "GET", "/static/sub/", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doStatic(e, "GET", "/static/missing.txt", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestStaticTraversal(t *testing.T) {
+	dir, clean := setupStatic(t)
+	defer clean()
+
+	e := NewServer(nil)
+	e.Static("/static", filepath.Join(dir, "sub"))
+
+	req := httptest.NewRequest("GET", "/static/a.txt", nil)
+	req.URL.Path = "/static/../hello.txt"
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	assert.NotEqual(t, "hello blademaster", w.Body.String())
+
+	e.UseRawPath = true
+	req = httptest.NewRequest("GET", "/static/a.txt", nil)
+	req.URL.RawPath = "/static/..%2fhello.txt"
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	assert.NotEqual(t, "hello blademaster", w.Body.String())
+}
+
+func TestStaticConfig(t *testing.T) {
+	dir, clean := setupStatic(t)
+	defer clean()
+
+	e := NewServer(nil)
+	g := e.Group("/assets").SetStaticConfig(&StaticConfig{Browse: true, CacheControl: "no-cache"})
+	g.Static("/", dir)
+
+	w := doStatic(e, "GET", "/assets/sub/", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `<a href="a.txt">a.txt</a>`)
+
+	w = doStatic(e, "GET", "/assets/sub", nil)
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+
+	w = doStatic(e, "GET", "/assets/hello.txt", nil)
+	assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+}
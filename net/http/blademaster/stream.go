@@ -0,0 +1,182 @@
+package blademaster
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	xhttp "net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gisvr/golib/conf/env"
+	"github.com/gisvr/golib/net/auth"
+
+	pkgerr "github.com/pkg/errors"
+)
+
+// limitResponse limits the body of the response to max bytes, the larger
+// body fails with errBodyTooLarge.
+func limitResponse(resp *xhttp.Response, max int64) io.ReadCloser {
+	if max <= 0 {
+		return resp.Body
+	}
+	if resp.ContentLength > max {
+		max = 0
+	}
+	return &limitedBody{ReadCloser: resp.Body, n: max}
+}
+
+// streamBody finishes the request when it is closed.
+type streamBody struct {
+	io.ReadCloser
+	finish func(error)
+	once   sync.Once
+	err    error
+}
+
+func (b *streamBody) Read(p []byte) (n int, err error) {
+	if n, err = b.ReadCloser.Read(p); err != nil && err != io.EOF {
+		b.err = err
+	}
+	return
+}
+
+func (b *streamBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		b.finish(b.err)
+	})
+	return err
+}
+
+// progressReader reports the bytes read to progress.
+type progressReader struct {
+	io.Reader
+	n        int64
+	total    int64
+	progress func(n, total int64)
+}
+
+func (r *progressReader) Read(p []byte) (n int, err error) {
+	n, err = r.Reader.Read(p)
+	if n > 0 {
+		r.n += int64(n)
+		r.progress(r.n, r.total)
+	}
+	return
+}
+
+// Stream sends an HTTP request and returns the response without reading the
+// body, the caller must close the body. The breaker, the metrics and the
+// timeout of the request last until the body is closed.
+func (client *Client) Stream(c context.Context, req *xhttp.Request, v ...string) (resp *xhttp.Response, err error) {
+	resp, config, finish, err := client.send(c, req, v...)
+	if err != nil {
+		return
+	}
+	resp.Body = &streamBody{ReadCloser: limitResponse(resp, config.MaxResponseSize), finish: finish}
+	return
+}
+
+// JSONStream sends an HTTP request and decodes the json response from the
+// stream without buffering the whole body.
+func (client *Client) JSONStream(c context.Context, req *xhttp.Request, res interface{}, v ...string) (err error) {
+	resp, err := client.Stream(c, req, v...)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if err = json.NewDecoder(resp.Body).Decode(res); err != nil {
+		err = pkgerr.Wrapf(err, "host:%s, url:%s", resp.Request.URL.Host, resp.Request.URL.Path)
+	}
+	return
+}
+
+// Download sends an HTTP request and writes the response body to the file of
+// path, which is replaced only if the whole body is received. progress is
+// called with the received and total bytes if it is not nil, total is -1 if
+// it is unknown.
+func (client *Client) Download(c context.Context, req *xhttp.Request, path string, progress func(received, total int64), v ...string) (n int64, err error) {
+	resp, err := client.Stream(c, req, v...)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		err = pkgerr.WithStack(err)
+		return
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+	var r io.Reader = resp.Body
+	if progress != nil {
+		r = &progressReader{Reader: r, total: resp.ContentLength, progress: progress}
+	}
+	n, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		err = pkgerr.Wrapf(err, "host:%s, url:%s", resp.Request.URL.Host, resp.Request.URL.Path)
+		return
+	}
+	err = pkgerr.WithStack(os.Rename(f.Name(), path))
+	return
+}
+
+// NewUploadRequest new http request which uploads the body of size bytes,
+// size is -1 if it is unknown. progress is called with the sent and total
+// bytes if it is not nil. The body is rewound for the retries if it is an
+// io.Seeker, otherwise it is buffered in memory when the retry is enabled.
+// The body is signed as auth.UnsignedPayload by the HMACSigner.
+func (client *Client) NewUploadRequest(method, uri, contentType string, body io.Reader, size int64, progress func(sent, total int64)) (req *xhttp.Request, err error) {
+	wrap := func() io.ReadCloser {
+		r := body
+		if progress != nil {
+			r = &progressReader{Reader: body, total: size, progress: progress}
+		}
+		if rc, ok := body.(io.ReadCloser); ok {
+			return struct {
+				io.Reader
+				io.Closer
+			}{r, rc}
+		}
+		return ioutil.NopCloser(r)
+	}
+	if req, err = xhttp.NewRequest(method, uri, wrap()); err != nil {
+		err = pkgerr.Wrapf(err, "method:%s,uri:%s", method, uri)
+		return
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = xhttp.NoBody
+	}
+	if seeker, ok := body.(io.Seeker); ok && size != 0 {
+		var offset int64
+		if offset, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			err = pkgerr.WithStack(err)
+			return
+		}
+		req.GetBody = func() (io.ReadCloser, error) {
+			if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+				return nil, pkgerr.WithStack(err)
+			}
+			return wrap(), nil
+		}
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if client.signer != nil {
+		// the signer does not read the whole body to hash it.
+		req.Header.Set(auth.HeaderContentSHA256, auth.UnsignedPayload)
+	}
+	req.Header.Set("User-Agent", _noKickUserAgent+" "+env.AppID)
+	return
+}
@@ -0,0 +1,90 @@
+package blademaster
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientStream(t *testing.T) {
+	payload := strings.Repeat("kratos", 1024)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/upload":
+			bs, _ := ioutil.ReadAll(r.Body)
+			w.Write([]byte(`{"code":0,"data":"` + r.Header.Get("Content-Type") + `:` + string(bs) + `"}`))
+		case "/json":
+			w.Write([]byte(`{"code":0,"data":"stream"}`))
+		default:
+			w.Write([]byte(payload))
+		}
+	}))
+	defer ts.Close()
+
+	client := newSignClient(nil)
+	client.SetConfig(&ClientConfig{URL: map[string]*ClientConfig{
+		ts.URL + "/limited": {Timeout: client.conf.Timeout, MaxResponseSize: 1024},
+	}})
+
+	// the response body is read as a stream.
+	req, _ := http.NewRequest("GET", ts.URL+"/file", nil)
+	resp, err := client.Stream(context.TODO(), req)
+	if assert.NoError(t, err) {
+		bs, _ := ioutil.ReadAll(resp.Body)
+		assert.Equal(t, payload, string(bs))
+		assert.NoError(t, resp.Body.Close())
+	}
+
+	res := new(signResp)
+	req, _ = http.NewRequest("GET", ts.URL+"/json", nil)
+	assert.NoError(t, client.JSONStream(context.TODO(), req, res))
+	assert.Equal(t, "stream", res.Data)
+
+	// the response larger than the limit.
+	req, _ = http.NewRequest("GET", ts.URL+"/limited", nil)
+	_, err = client.Raw(context.TODO(), req)
+	assert.Error(t, err)
+
+	// download to the file with progress.
+	dir, _ := ioutil.TempDir("", "bm-download")
+	defer os.RemoveAll(dir)
+	var received int64
+	req, _ = http.NewRequest("GET", ts.URL+"/file", nil)
+	n, err := client.Download(context.TODO(), req, filepath.Join(dir, "file"), func(n, total int64) {
+		received = n
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(payload)), n)
+	assert.Equal(t, n, received)
+	bs, _ := ioutil.ReadFile(filepath.Join(dir, "file"))
+	assert.Equal(t, payload, string(bs))
+	req, _ = http.NewRequest("GET", ts.URL+"/limited", nil)
+	_, err = client.Download(context.TODO(), req, filepath.Join(dir, "limited"), nil)
+	assert.Error(t, err)
+	files, _ := ioutil.ReadDir(dir)
+	assert.Len(t, files, 1, "the partial download is removed")
+
+	// upload the reader with progress.
+	var sent int64
+	req, err = client.NewUploadRequest("PUT", ts.URL+"/upload", "text/plain", bytes.NewReader([]byte("upload")), 6, func(n, total int64) {
+		sent = n
+		assert.Equal(t, int64(6), total)
+	})
+	assert.NoError(t, err)
+	res = new(signResp)
+	assert.NoError(t, client.Do(context.TODO(), req, res))
+	assert.Equal(t, "text/plain:upload", res.Data)
+	assert.Equal(t, int64(6), sent)
+	body, err := req.GetBody()
+	assert.NoError(t, err)
+	bs, _ = ioutil.ReadAll(body)
+	assert.Equal(t, "upload", string(bs), "the seeker body is rewound")
+}
@@ -0,0 +1,243 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gisvr/golib/net/http/blademaster"
+	xtime "github.com/gisvr/golib/time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	_defaultName = "default"
+	// ProxyFromEnvironment uses the proxy of the HTTP_PROXY, HTTPS_PROXY and NO_PROXY env.
+	ProxyFromEnvironment = "env"
+)
+
+var (
+	_mutex   sync.RWMutex
+	_clients = make(map[string]*http.Client)
+
+	_tlsVersions = map[string]uint16{
+		"1.0": tls.VersionTLS10,
+		"1.1": tls.VersionTLS11,
+		"1.2": tls.VersionTLS12,
+		"1.3": tls.VersionTLS13,
+	}
+)
+
+// Config is the named clients config, eg:
+//
+//	clients:
+//	  default:
+//	    timeout: 5s
+//	  payment:
+//	    timeout: 2s
+//	    proxy: http://127.0.0.1:3128
+//	    tls:
+//	      rootCAs: [/etc/ssl/payment-ca.pem]
+//	      certFile: /etc/ssl/client.pem
+//	      keyFile: /etc/ssl/client.key
+type Config struct {
+	Clients map[string]*ClientConfig `yaml:"clients"`
+}
+
+// ClientConfig is the config of a client, the zero value of a field means the default.
+type ClientConfig struct {
+	Timeout               xtime.Duration `yaml:"timeout"`               // default 5s.
+	Dial                  xtime.Duration `yaml:"dial"`                  // default 5s.
+	KeepAlive             xtime.Duration `yaml:"keepAlive"`             // default 30s.
+	TLSHandshakeTimeout   xtime.Duration `yaml:"tlsHandshakeTimeout"`   // default 5s.
+	ResponseHeaderTimeout xtime.Duration `yaml:"responseHeaderTimeout"` // no timeout by default.
+	ExpectContinueTimeout xtime.Duration `yaml:"expectContinueTimeout"` // default 1s.
+	IdleConnTimeout       xtime.Duration `yaml:"idleConnTimeout"`       // default 90s.
+	MaxIdleConns          int            `yaml:"maxIdleConns"`          // default 100.
+	MaxIdleConnsPerHost   int            `yaml:"maxIdleConnsPerHost"`   // default 10.
+	MaxConnsPerHost       int            `yaml:"maxConnsPerHost"`       // no limit by default.
+	// Proxy is the proxy url, or ProxyFromEnvironment, no proxy if it is empty.
+	Proxy string `yaml:"proxy"`
+	// HTTP2 enables the http2 negotiation of the tls connections.
+	HTTP2 bool       `yaml:"http2"`
+	TLS   *TLSConfig `yaml:"tls"`
+}
+
+// TLSConfig is the tls config of a client, the server certificate is verified
+// by the system roots by default.
+type TLSConfig struct {
+	// RootCAs are the pem files of the roots which verify the server
+	// certificates instead of the system roots.
+	RootCAs []string `yaml:"rootCAs"`
+	// CertFile and KeyFile are the client certificate for the mutual tls.
+	CertFile   string `yaml:"certFile"`
+	KeyFile    string `yaml:"keyFile"`
+	ServerName string `yaml:"serverName"`
+	// MinVersion is the min tls version, 1.0, 1.1, 1.2(default) or 1.3.
+	MinVersion string `yaml:"minVersion"`
+	// InsecureSkipVerify disables the verification of the server certificate,
+	// it should only be used in tests.
+	InsecureSkipVerify bool `yaml:"insecureSkipVerify"`
+}
+
+func (c *ClientConfig) fix() *ClientConfig {
+	nc := &ClientConfig{}
+	if c != nil {
+		*nc = *c
+	}
+	defaults := []struct {
+		v *xtime.Duration
+		d time.Duration
+	}{
+		{&nc.Timeout, 5 * time.Second},
+		{&nc.Dial, 5 * time.Second},
+		{&nc.KeepAlive, 30 * time.Second},
+		{&nc.TLSHandshakeTimeout, 5 * time.Second},
+		{&nc.ExpectContinueTimeout, time.Second},
+		{&nc.IdleConnTimeout, 90 * time.Second},
+	}
+	for _, d := range defaults {
+		if *d.v <= 0 {
+			*d.v = xtime.Duration(d.d)
+		}
+	}
+	if nc.MaxIdleConns <= 0 {
+		nc.MaxIdleConns = 100
+	}
+	if nc.MaxIdleConnsPerHost <= 0 {
+		nc.MaxIdleConnsPerHost = 10
+	}
+	return nc
+}
+
+// tlsConfig builds the tls config, nil means the defaults of the transport.
+func (c *TLSConfig) tlsConfig() (*tls.Config, error) {
+	if c == nil {
+		return nil, nil
+	}
+	conf := &tls.Config{MinVersion: tls.VersionTLS12}
+	conf.ServerName = c.ServerName
+	conf.InsecureSkipVerify = c.InsecureSkipVerify
+	if c.MinVersion != "" {
+		v, ok := _tlsVersions[c.MinVersion]
+		if !ok {
+			return nil, errors.Errorf("http: invalid tls min version: %s", c.MinVersion)
+		}
+		conf.MinVersion = v
+	}
+	if len(c.RootCAs) > 0 {
+		pool := x509.NewCertPool()
+		for _, file := range c.RootCAs {
+			pem, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, errors.Wrapf(err, "http: read root ca: %s", file)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, errors.Errorf("http: no certificate in root ca: %s", file)
+			}
+		}
+		conf.RootCAs = pool
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "http: load client certificate: %s", c.CertFile)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+	return conf, nil
+}
+
+// NewClient returns a http client of the config, whose requests are traced
+// and observed by the metrics labeled by the name.
+func NewClient(name string, c *ClientConfig) (*http.Client, error) {
+	c = c.fix()
+	tlsConf, err := c.TLS.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	return newClient(name, c, tlsConf)
+}
+
+// newClient returns a client of the fixed config with the tls config.
+func newClient(name string, c *ClientConfig, tlsConf *tls.Config) (*http.Client, error) {
+	var proxy func(*http.Request) (*url.URL, error)
+	switch c.Proxy {
+	case "":
+	case ProxyFromEnvironment:
+		proxy = http.ProxyFromEnvironment
+	default:
+		u, err := url.Parse(c.Proxy)
+		if err != nil {
+			return nil, errors.Wrapf(err, "http: invalid proxy: %s", c.Proxy)
+		}
+		proxy = http.ProxyURL(u)
+	}
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   time.Duration(c.Dial),
+			KeepAlive: time.Duration(c.KeepAlive),
+		}).DialContext,
+		TLSClientConfig:       tlsConf,
+		TLSHandshakeTimeout:   time.Duration(c.TLSHandshakeTimeout),
+		ResponseHeaderTimeout: time.Duration(c.ResponseHeaderTimeout),
+		ExpectContinueTimeout: time.Duration(c.ExpectContinueTimeout),
+		IdleConnTimeout:       time.Duration(c.IdleConnTimeout),
+		MaxIdleConns:          c.MaxIdleConns,
+		MaxIdleConnsPerHost:   c.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       c.MaxConnsPerHost,
+		ForceAttemptHTTP2:     c.HTTP2,
+	}
+	return &http.Client{
+		Timeout:   time.Duration(c.Timeout),
+		Transport: blademaster.NewTraceTransport(&metricTransport{name: name, rt: transport}, name),
+	}, nil
+}
+
+// Init builds the clients of the config, which replace the existing ones.
+// Nothing is replaced if any config is invalid.
+func Init(c *Config) error {
+	clients := make(map[string]*http.Client, len(c.Clients))
+	for name, cc := range c.Clients {
+		client, err := NewClient(name, cc)
+		if err != nil {
+			return errors.WithMessagef(err, "http: client %s", name)
+		}
+		clients[name] = client
+	}
+	_mutex.Lock()
+	for name, client := range clients {
+		_clients[name] = client
+	}
+	_mutex.Unlock()
+	return nil
+}
+
+// Get returns the client of the name, the default client is returned if the
+// name is not configured, which has the default config unless it is configured.
+func Get(name string) *http.Client {
+	_mutex.RLock()
+	client, ok := _clients[name]
+	if !ok {
+		client, ok = _clients[_defaultName]
+	}
+	_mutex.RUnlock()
+	if ok {
+		return client
+	}
+	_mutex.Lock()
+	defer _mutex.Unlock()
+	if client, ok = _clients[_defaultName]; !ok {
+		// the default config is always valid.
+		client, _ = NewClient(_defaultName, nil)
+		_clients[_defaultName] = client
+	}
+	return client
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	xtime "github.com/gisvr/golib/time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClientTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+	dir, _ := ioutil.TempDir("", "http")
+	defer os.RemoveAll(dir)
+	ca := filepath.Join(dir, "ca.pem")
+	ioutil.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0644)
+
+	client, err := NewClient("test", nil)
+	assert.NoError(t, err)
+	_, err = client.Get(ts.URL)
+	assert.Error(t, err, "the server certificate is verified by default")
+
+	client, err = NewClient("test", &ClientConfig{TLS: &TLSConfig{RootCAs: []string{ca}}})
+	assert.NoError(t, err)
+	resp, err := client.Get(ts.URL)
+	if assert.NoError(t, err) {
+		bs, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, "ok", string(bs))
+	}
+
+	_, err = NewClient("test", &ClientConfig{TLS: &TLSConfig{RootCAs: []string{filepath.Join(dir, "none.pem")}}})
+	assert.Error(t, err)
+	_, err = NewClient("test", &ClientConfig{TLS: &TLSConfig{MinVersion: "0.9"}})
+	assert.Error(t, err)
+	_, err = NewClient("test", &ClientConfig{Proxy: "://bad"})
+	assert.Error(t, err)
+}
+
+func TestInitGet(t *testing.T) {
+	assert.NotNil(t, Get("unknown"))
+	assert.Error(t, Init(&Config{Clients: map[string]*ClientConfig{
+		"fast": {Timeout: xtime.Duration(time.Second)},
+		"bad":  {Proxy: "://bad"},
+	}}))
+	assert.Equal(t, Get("fast"), Get(_defaultName), "nothing is replaced")
+
+	assert.NoError(t, Init(&Config{Clients: map[string]*ClientConfig{
+		"default": {Timeout: xtime.Duration(2 * time.Second)},
+		"fast":    {Timeout: xtime.Duration(time.Second)},
+	}}))
+	assert.Equal(t, time.Second, Get("fast").Timeout)
+	assert.Equal(t, 2*time.Second, Get("unknown").Timeout)
+}
+
+func TestTLSConfigMinVersion(t *testing.T) {
+	conf, err := (*TLSConfig)(nil).tlsConfig()
+	assert.NoError(t, err)
+	assert.Nil(t, conf, "the transport defaults without the tls config")
+
+	conf, err = (&TLSConfig{}).tlsConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), conf.MinVersion)
+}
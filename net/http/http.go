@@ -2,40 +2,45 @@ package http
 
 import (
 	"crypto/tls"
-	"net"
 	"net/http"
-	"net/url"
 	"sync"
 	"time"
+
+	xtime "github.com/gisvr/golib/time"
 )
 
-var httpOnce sync.Once
-var netClient *http.Client
-var ProxyUrl string
-var TlsCheck bool
+var (
+	httpOnce  sync.Once
+	netClient *http.Client
+
+	// ProxyUrl is the proxy of the client returned by GetHTTPClient.
+	//
+	// Deprecated: use ClientConfig.Proxy instead.
+	ProxyUrl string
+	// TlsCheck enables the server certificate verification of the client
+	// returned by GetHTTPClient.
+	//
+	// Deprecated: use ClientConfig.TLS instead, which verifies by default.
+	TlsCheck bool
+)
 
+// GetHTTPClient returns the legacy client, which is configured by ProxyUrl and TlsCheck.
+//
+// Deprecated: use Get or NewClient instead.
 func GetHTTPClient() *http.Client {
 	httpOnce.Do(func() {
-		var proxy = func(_ *http.Request) (*url.URL, error) {
-			if ProxyUrl == "" {
-				return nil, nil
-			}
-			return url.Parse(ProxyUrl)
-		}
-		var netTransport = &http.Transport{
-			Dial: (&net.Dialer{
-				Timeout:   5 * time.Second,
-				KeepAlive: 30 * time.Second,
-			}).Dial,
-			TLSHandshakeTimeout:   5 * time.Second,
-			ResponseHeaderTimeout: 5 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
-			Proxy:                 proxy,
-			TLSClientConfig:       &tls.Config{InsecureSkipVerify: !TlsCheck},
-		}
-		netClient = &http.Client{
-			Timeout:   time.Second * 5,
-			Transport: netTransport,
+		c := (&ClientConfig{
+			ResponseHeaderTimeout: xtime.Duration(5 * time.Second),
+			Proxy:                 ProxyUrl,
+		}).fix()
+		// the legacy client keeps the default min tls version of the transport.
+		tlsConf := &tls.Config{InsecureSkipVerify: !TlsCheck}
+		var err error
+		if netClient, err = newClient("legacy", c, tlsConf); err != nil {
+			// an invalid proxy failed every request before, now the requests
+			// are sent without the proxy.
+			c.Proxy = ""
+			netClient, _ = newClient("legacy", c, tlsConf)
 		}
 	})
 	return netClient
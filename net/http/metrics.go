@@ -0,0 +1,47 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gisvr/golib/stat/metric"
+)
+
+const clientNamespace = "http_client"
+
+var (
+	_metricClientDur = metric.NewHistogramVec(&metric.HistogramVecOpts{
+		Namespace: clientNamespace,
+		Subsystem: "transport",
+		Name:      "duration_ms",
+		Help:      "http named client requests duration(ms).",
+		Labels:    []string{"client", "method"},
+		Buckets:   []float64{5, 10, 25, 50, 100, 250, 500, 1000},
+	})
+	_metricClientCodeTotal = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: clientNamespace,
+		Subsystem: "transport",
+		Name:      "code_total",
+		Help:      "http named client requests code count.",
+		Labels:    []string{"client", "method", "code"},
+	})
+)
+
+// metricTransport observes the requests of a named client.
+type metricTransport struct {
+	name string
+	rt   http.RoundTripper
+}
+
+func (t *metricTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	now := time.Now()
+	resp, err := t.rt.RoundTrip(req)
+	_metricClientDur.Observe(int64(time.Since(now)/time.Millisecond), t.name, req.Method)
+	code := "failed"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	_metricClientCodeTotal.Inc(t.name, req.Method, code)
+	return resp, err
+}
@@ -34,6 +34,9 @@ const (
 	// Device 客户端信息
 	Device = "device"
 
+	// Principal 认证后的调用方身份
+	Principal = "principal"
+
 	// Criticality 重要性
 	Criticality = "criticality"
 )
@@ -40,6 +40,13 @@ type Breaker interface {
 	MarkFailed()
 }
 
+// Stat is the snapshot of a breaker in the window.
+type Stat struct {
+	State   int32
+	Success int64
+	Total   int64
+}
+
 // Group represents a class of CircuitBreaker and forms a namespace in which
 // units of CircuitBreaker.
 type Group struct {
@@ -139,6 +146,19 @@ func (g *Group) Get(key string) Breaker {
 	return brk
 }
 
+// Stats returns the snapshots of the breakers keyed by the name.
+func (g *Group) Stats() map[string]Stat {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	stats := make(map[string]Stat, len(g.brks))
+	for name, brk := range g.brks {
+		if b, ok := brk.(interface{ snapshot() Stat }); ok {
+			stats[name] = b.snapshot()
+		}
+	}
+	return stats
+}
+
 // Reload reload the group by specified config, this may let all inner breaker
 // reset to a new one.
 func (g *Group) Reload(conf *Config) {
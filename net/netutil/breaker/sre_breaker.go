@@ -92,6 +92,11 @@ func (b *sreBreaker) MarkFailed() {
 	b.stat.Add(0)
 }
 
+func (b *sreBreaker) snapshot() Stat {
+	success, total := b.summary()
+	return Stat{State: atomic.LoadInt32(&b.state), Success: success, Total: total}
+}
+
 func (b *sreBreaker) trueOnProba(proba float64) (truth bool) {
 	b.randLock.Lock()
 	truth = b.r.Float64() < proba
@@ -1,7 +1,6 @@
 package logging
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -9,7 +8,6 @@ import (
 	"fmt"
 	"github.com/gisvr/golib/utils"
 	"math/rand"
-	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -114,6 +112,8 @@ type streamWrapper struct {
 	WrapperContext context.Context
 
 	ID string
+
+	logger *log.Logger
 }
 
 func (s *streamWrapper) Context() context.Context {
@@ -122,17 +122,15 @@ func (s *streamWrapper) Context() context.Context {
 
 func (s *streamWrapper) RecvMsg(m interface{}) error {
 	if err := s.ServerStream.RecvMsg(m); err != nil {
-		log.Errorf("[stream] id:%v recv error %v, type:%T",
-			s.ID, err, m)
+		s.logger.Errorf("[stream] recv error %v, type:%T", err, m)
 		return err
 	}
 
 	_, isProtoMsg := m.(proto.Message)
 	if canDumpGrpcBody() && isProtoMsg {
-		log.Infof("[stream] id:%v recv msg type:%T data:\n%#v",
-			s.ID, m, Marshal(m))
+		s.logger.Infof("[stream] recv msg type:%T data:\n%#v", m, Marshal(m))
 	} else {
-		log.Infof("[stream] id:%v recv msg type:%T", s.ID, m)
+		s.logger.Infof("[stream] recv msg type:%T", m)
 	}
 
 	return nil
@@ -142,10 +140,9 @@ func (s *streamWrapper) SendMsg(m interface{}) error {
 	err := s.ServerStream.SendMsg(m)
 	_, isProtoMsg := m.(proto.Message)
 	if canDumpGrpcBody() && isProtoMsg {
-		log.Infof("[stream] id:%v send msg type:%T err:%v data:\n%v",
-			s.ID, m, err, Marshal(m))
+		s.logger.Infof("[stream] send msg type:%T err:%v data:\n%v", m, err, Marshal(m))
 	} else {
-		log.Infof("[stream] id:%v send msg type:%T err:%v", s.ID, m, err)
+		s.logger.Infof("[stream] send msg type:%T err:%v", m, err)
 	}
 
 	return err
@@ -168,11 +165,12 @@ func canDumpGrpcMeta() bool {
 	return DumpGrpcMeta == Enable
 }
 
-func printWithData(data interface{}, withData bool, format string, args ...interface{}) {
+func printWithData(logger *log.Logger, data interface{}, withData bool, format string, args ...interface{}) {
+	logger = logger.WithDepth(1)
 	if withData {
-		log.Infof("%s data:\n%v", fmt.Sprintf(format, args...), Marshal(data))
+		logger.Infof("%s data:\n%v", fmt.Sprintf(format, args...), Marshal(data))
 	} else {
-		log.Infof(format, args...)
+		logger.Infof(format, args...)
 	}
 }
 
@@ -197,180 +195,144 @@ func ServerLogInterceptor(ctx context.Context, req interface{}, info *grpc.Unary
 		id = getRandomID()
 	}
 
+	rl := newRequestLog(ctx, id)
+	logger := rl.Logger()
+
 	if canDumpGrpcMeta() {
-		log.Infof("[req] id:%v method:%v metadata:%+v", id, info.FullMethod, md)
+		logger.Infof("[req] method:%v metadata:%+v", info.FullMethod, log.RedactValue(md))
 	}
 
 	if canDumpGrpcBody() {
-		log.Infof("[req] id:%v method:%v device:%v req:\n%v",
-			id, info.FullMethod, device, Marshal(req))
+		logger.Infof("[req] method:%v device:%v req:\n%v",
+			info.FullMethod, device, Marshal(req))
 	}
 
 	st := time.Now()
 
-	ctx = context.WithValue(ctx, requestIDKey{}, id)
-
-	res, err := handler(ctx, req)
+	res, err := handler(rl.Context(ctx), req)
 
-	printWithData(res, canDumpGrpcBody(),
-		"[rsp] id:%v method:%v cost:%v err:%v",
-		id, info.FullMethod, time.Since(st), err)
+	printWithData(logger, res, canDumpGrpcBody(),
+		"[rsp] method:%v cost:%v err:%v",
+		info.FullMethod, time.Since(st), err)
 
 	return res, err
 }
 
-// Fields defines map with key string and value interface
-type Fields map[string]interface{}
-
+// requestLog is the logger of a request, whose logs have the request id.
 type requestLog struct {
-	ID   string
-	Data Fields
-
-	cache string
+	ID string
 
-	sync.Mutex
+	mu     sync.Mutex
+	logger *log.Logger
 }
 
-func (r *requestLog) CreateRequest() *requestLog {
-	nr := &requestLog{
-		ID:   fmt.Sprintf("%s-%s", r.ID, getRandomID()),
-		Data: make(Fields, len(r.Data)+5),
-	}
-
-	for k, v := range r.Data {
-		nr.Data[k] = v
+func newRequestLog(ctx context.Context, id string) *requestLog {
+	return &requestLog{
+		ID:     id,
+		logger: log.FromContext(ctx).With(log.KVString("id", id)),
 	}
-
-	nr.Data["id"] = nr.ID
-
-	return nr
 }
 
-func (r *requestLog) Context(ctx context.Context) context.Context {
-	return context.WithValue(context.WithValue(ctx, requestIDKey{}, r.ID), requestLogKey{}, r)
-}
-
-func (r *requestLog) Fields() string {
-	r.Lock()
-	defer r.Unlock()
-
-	if r.cache != "" {
-		return r.cache
-	}
-
-	b := &bytes.Buffer{}
-
-	keys := make([]string, 0, len(r.Data))
-	for k := range r.Data {
-		keys = append(keys, k)
-	}
-
-	sort.Strings(keys)
-
-	for _, key := range keys {
-		r.appendKeyValue(b, key, r.Data[key])
+// CreateRequest returns a child request log, whose id is prefixed by the parent id.
+func (r *requestLog) CreateRequest() *requestLog {
+	id := fmt.Sprintf("%s-%s", r.ID, getRandomID())
+	return &requestLog{
+		ID:     id,
+		logger: r.Logger().With(log.KVString("id", id)),
 	}
-
-	r.cache = b.String() + " "
-
-	return r.cache
 }
 
-func (r *requestLog) appendKeyValue(b *bytes.Buffer, key string, value interface{}) {
-	if b.Len() > 0 {
-		b.WriteByte(' ')
-	}
-	b.WriteString(key)
-	b.WriteByte(':')
-	r.appendValue(b, value)
+// Context returns a context which carries the request id and the request
+// log, the logger is also got by log.FromContext.
+func (r *requestLog) Context(ctx context.Context) context.Context {
+	ctx = context.WithValue(context.WithValue(ctx, requestIDKey{}, r.ID), requestLogKey{}, r)
+	return log.NewContext(ctx, r.Logger())
 }
 
-func (r *requestLog) appendValue(b *bytes.Buffer, value interface{}) {
-	stringVal, ok := value.(string)
-	if !ok {
-		stringVal = fmt.Sprint(value)
-	}
-
-	b.WriteString(stringVal)
+// Logger returns the logger with the request id and the fields set.
+func (r *requestLog) Logger() *log.Logger {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.logger
 }
 
+// Set binds the key-value pairs to the following logs.
 func (r *requestLog) Set(kv ...interface{}) {
 	if len(kv)%2 == 1 {
 		panic(fmt.Sprintf("requestLog: Set got the odd number of input: %d", len(kv)))
 	}
 
-	r.Lock()
-
-	var key string
-	for i, s := range kv {
-		if i%2 == 0 {
-			stringVal, ok := s.(string)
-			if !ok {
-				stringVal = fmt.Sprint(s)
-			}
-			key = stringVal
-			continue
+	fields := make([]log.D, 0, len(kv)/2)
+	for i := 0; i < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
 		}
-		r.Data[key] = s
+		fields = append(fields, log.KV(key, kv[i+1]))
 	}
 
-	r.cache = ""
+	r.mu.Lock()
+	r.logger = r.logger.With(fields...)
+	r.mu.Unlock()
+}
 
-	r.Unlock()
+// caller returns the logger which reports the caller of the requestLog methods.
+func (r *requestLog) caller() *log.Logger {
+	return r.Logger().WithDepth(1)
 }
 
 func (r *requestLog) Info(args ...interface{}) {
-	log.InfoDepth(1, append([]interface{}{r.Fields()}, args...)...)
+	r.caller().Info(args...)
 }
 
 func (r *requestLog) Infof(format string, args ...interface{}) {
-	log.InfoDepth(1, r.Fields(), fmt.Sprintf(format, args...))
+	r.caller().Infof(format, args...)
 }
 
 func (r *requestLog) Infoln(args ...interface{}) {
-	log.InfoDepth(1, r.Fields(), fmt.Sprintln(args...))
+	r.caller().Info(fmt.Sprintln(args...))
 }
 
 func (r *requestLog) Debug(args ...interface{}) {
-	if log.V(int32(LogLevelDebug)).IsEnable() {
-		log.InfoDepth(1, append([]interface{}{r.Fields()}, args...)...)
+	if r.DebugEnabled() {
+		r.caller().Info(args...)
 	}
 }
 
 func (r *requestLog) Debugf(format string, args ...interface{}) {
-	if log.V(int32(LogLevelDebug)).IsEnable() {
-		log.InfoDepth(1, r.Fields(), fmt.Sprintf(format, args...))
+	if r.DebugEnabled() {
+		r.caller().Infof(format, args...)
 	}
 }
 
 func (r *requestLog) Debugln(args ...interface{}) {
-	if log.V(int32(LogLevelDebug)).IsEnable() {
-		log.InfoDepth(1, r.Fields(), fmt.Sprintln(args...))
+	if r.DebugEnabled() {
+		r.caller().Info(fmt.Sprintln(args...))
 	}
 }
 
 func (r *requestLog) Error(args ...interface{}) {
-	log.ErrorDepth(1, append([]interface{}{r.Fields()}, args...)...)
+	r.caller().Error(args...)
 }
 
 func (r *requestLog) Errorf(format string, args ...interface{}) {
-	log.ErrorDepth(1, r.Fields(), fmt.Sprintf(format, args...))
+	r.caller().Errorf(format, args...)
 }
 
 func (r *requestLog) Errorln(args ...interface{}) {
-	log.ErrorDepth(1, r.Fields(), fmt.Sprintln(args...))
+	r.caller().Error(fmt.Sprintln(args...))
 }
 
 func (r *requestLog) Warn(args ...interface{}) {
-	log.WarnDepth(1, append([]interface{}{r.Fields()}, args...)...)
+	r.caller().Warn(args...)
 }
 
 func (r *requestLog) Warnf(format string, args ...interface{}) {
-	log.WarnDepth(1, r.Fields(), fmt.Sprintf(format, args...))
+	r.caller().Warnf(format, args...)
 }
 
 func (r *requestLog) Warnln(args ...interface{}) {
-	log.WarnDepth(1, r.Fields(), fmt.Sprintln(args...))
+	r.caller().Warn(fmt.Sprintln(args...))
 }
 
 func (r *requestLog) DebugEnabled() bool {
@@ -379,23 +341,16 @@ func (r *requestLog) DebugEnabled() bool {
 
 // RequestLogFromContext ...
 func RequestLogFromContext(ctx context.Context) *requestLog {
-	if rl, ok := ctx.Value(requestIDKey{}).(*requestLog); ok {
+	if rl, ok := ctx.Value(requestLogKey{}).(*requestLog); ok {
 		return rl
 	}
 
-	rl := &requestLog{
-		ID:   GetRequestIDFromContext(ctx),
-		Data: make(Fields, 5),
-	}
-
-	rl.Data["id"] = rl.ID
-
-	return rl
+	return newRequestLog(ctx, GetRequestIDFromContext(ctx))
 }
 
-// Marshal ...
+// Marshal formats v in json, the sensitive values are masked by the log redact rules.
 func Marshal(v interface{}) string {
-	return utils.JsonFormat(v)
+	return utils.JsonFormat(log.RedactValue(v))
 }
 
 func JsonMarshal(v interface{}) string {
@@ -410,24 +365,25 @@ func JsonMarshal(v interface{}) string {
 // ClientLogInterceptor ...
 func ClientLogInterceptor(ctx context.Context, method string, req interface{}, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
 	id := GetRequestIDFromContext(ctx)
+	logger := log.FromContext(ctx).With(log.KVString("id", id))
 
 	if canDumpGrpcMeta() {
 		md, _ := metadata.FromOutgoingContext(ctx)
-		log.Infof("[req] *client id:%v method:%v md:%+v", id, method, md)
+		logger.Infof("[req] *client method:%v md:%+v", method, log.RedactValue(md))
 	}
 
 	if canDumpGrpcBody() {
-		log.Infof("[req] *client id:%v method:%v target:%v req:\n%v",
-			id, method, cc.Target(), Marshal(req))
+		logger.Infof("[req] *client method:%v target:%v req:\n%v",
+			method, cc.Target(), Marshal(req))
 	}
 
 	start := time.Now()
 
 	err := invoker(ctx, method, req, reply, cc, opts...)
 
-	printWithData(reply, canDumpGrpcBody(),
-		"[rsp] *client id:%v method:%v cost:%v err:%v",
-		id, method, time.Since(start), err)
+	printWithData(logger, reply, canDumpGrpcBody(),
+		"[rsp] *client method:%v cost:%v err:%v",
+		method, time.Since(start), err)
 
 	return err
 }
@@ -439,47 +395,43 @@ func StreamServerLogInterceptor(srv interface{}, ss grpc.ServerStream, info *grp
 	}
 
 	id := getRandomID()
+	rl := newRequestLog(ss.Context(), id)
 
 	wrapper := &streamWrapper{
 		ServerStream:   ss,
-		WrapperContext: context.WithValue(ss.Context(), requestIDKey{}, id),
+		WrapperContext: rl.Context(ss.Context()),
 		ID:             id,
+		logger:         rl.Logger(),
 	}
 
 	if canDumpGrpcMeta() {
 		md, _ := metadata.FromIncomingContext(ss.Context())
-		log.Infof("[stream] [server] id:%v method:%v metadata:md %+v", id, info.FullMethod, md)
+		wrapper.logger.Infof("[stream] [server] method:%v metadata:md %+v", info.FullMethod, log.RedactValue(md))
 	}
 
-	log.Infof("[stream] [server] id:%v method:%v connected",
-		wrapper.ID, info.FullMethod)
+	wrapper.logger.Infof("[stream] [server] method:%v connected", info.FullMethod)
 
 	st := time.Now()
 
 	err := handler(srv, wrapper)
 
-	log.Infof("[stream] [server] id:%v method:%v closed. cost:%v err:%v",
-		wrapper.ID, info.FullMethod, time.Since(st), err)
+	wrapper.logger.Infof("[stream] [server] method:%v closed. cost:%v err:%v",
+		info.FullMethod, time.Since(st), err)
 
 	return err
 }
 
 // StreamLogInterceptor log for stream
 func StreamClientLogInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
-	if !log.V(int32(LogLevel)).IsEnable() {
-		return streamer(ctx, desc, cc, method, opts...)
-	}
-
-	id := getRandomID()
-
-	if canDumpGrpcMeta() {
-		md, _ := metadata.FromOutgoingContext(ctx)
-		log.Infof("[stream] [client] id:%v method:%v metadata:md %+v", id, method, md)
+	if log.V(int32(LogLevel)).IsEnable() {
+		logger := log.FromContext(ctx).With(log.KVString("id", getRandomID()))
+		if canDumpGrpcMeta() {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			logger.Infof("[stream] [client] method:%v metadata:md %+v", method, log.RedactValue(md))
+		}
+		logger.Infof("[stream] [client] method:%v connected", method)
 	}
 
-	log.V(int32(LogLevel)).Infof("[stream] [client] id:%v method:%v connected",
-		id, method)
-
 	return streamer(ctx, desc, cc, method, opts...)
 }
 
@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	nauth "github.com/gisvr/golib/net/auth"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Config is the per method policy config, the policy of a method is looked up by
+//  1. the full method, eg: /demo.service.v1.Demo/SayHello
+//  2. the service, eg: /demo.service.v1.Demo/*
+//  3. the Default
+type Config struct {
+	Policies map[string]string
+	// Default is the policy of the methods not in Policies, "anonymous" if it is empty.
+	Default string
+}
+
+// Auth is the authentication and authorization server interceptor, both the
+// unary and streaming methods must be intercepted, eg:
+//
+//	a := auth.New(c, verifiers...)
+//	s := warden.NewServer(nil, grpc.StreamInterceptor(a.Stream()))
+//	s.Use(a.Unary())
+type Auth struct {
+	authn    *nauth.Authenticator
+	policies map[string]*nauth.Policy
+	def      *nauth.Policy
+}
+
+// New returns an auth interceptor which authenticates requests by the verifiers
+// in order and checks them by the policies. It panics if any policy is invalid.
+func New(c *Config, verifiers ...nauth.Verifier) *Auth {
+	a := &Auth{
+		authn:    nauth.New(verifiers...),
+		policies: make(map[string]*nauth.Policy, len(c.Policies)),
+		def:      nauth.MustPolicy("anonymous"),
+	}
+	if c.Default != "" {
+		a.def = nauth.MustPolicy(c.Default)
+	}
+	for method, p := range c.Policies {
+		a.policies[method] = nauth.MustPolicy(p)
+	}
+	return a
+}
+
+func (a *Auth) policy(fullMethod string) *nauth.Policy {
+	if p, ok := a.policies[fullMethod]; ok {
+		return p
+	}
+	if i := strings.LastIndex(fullMethod, "/"); i > 0 {
+		if p, ok := a.policies[fullMethod[:i]+"/*"]; ok {
+			return p
+		}
+	}
+	return a.def
+}
+
+// authorize authenticates and authorizes the method, it returns the context
+// with the principal if the request is authenticated.
+func (a *Auth) authorize(ctx context.Context, fullMethod string) (context.Context, error) {
+	gmd, _ := metadata.FromIncomingContext(ctx)
+	pr, err := a.authn.Authenticate(ctx, authRequest{method: fullMethod, md: gmd})
+	if err != nil && err != nauth.ErrNoCredential {
+		return ctx, err
+	}
+	if err = a.policy(fullMethod).Check(pr); err != nil {
+		return ctx, err
+	}
+	if pr != nil {
+		ctx = nauth.NewContext(ctx, pr)
+	}
+	return ctx, nil
+}
+
+// Unary is a server interceptor that authenticates and authorizes the incoming request.
+func (a *Auth) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, args *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := a.authorize(ctx, args.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Stream is a server interceptor that authenticates and authorizes the incoming
+// stream, the principal is in the context of the stream.
+func (a *Auth) Stream() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, args *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authorize(ss.Context(), args.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// serverStream overrides the context of the grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// authRequest adapts the grpc metadata to auth.Request.
+type authRequest struct {
+	method string
+	md     metadata.MD
+}
+
+func (r authRequest) Method() string     { return "POST" }
+func (r authRequest) Path() string       { return r.method }
+func (r authRequest) Params() url.Values { return nil }
+
+func (r authRequest) Header(key string) string {
+	if vals := r.md.Get(key); len(vals) > 0 {
+		return vals[0]
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	nauth "github.com/gisvr/golib/net/auth"
+)
+
+type mockStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *mockStream) Context() context.Context {
+	return s.ctx
+}
+
+func newTestAuth() *Auth {
+	return New(&Config{Default: "authenticated"}, nauth.NewAPIKeys(&nauth.APIKeyConfig{Keys: map[string]*nauth.APIKey{
+		nauth.HashAPIKey("key1"): {ID: "ops", Mid: 10},
+	}}))
+}
+
+func TestStream(t *testing.T) {
+	stream := newTestAuth().Stream()
+	info := &grpc.StreamServerInfo{FullMethod: "/testproto.Greeter/StreamHello", IsServerStream: true}
+	var called bool
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		called = true
+		pr, ok := nauth.FromContext(ss.Context())
+		assert.True(t, ok)
+		assert.Equal(t, "ops", pr.ID)
+		return nil
+	}
+
+	err := stream(nil, &mockStream{ctx: context.Background()}, info, handler)
+	assert.Error(t, err)
+	assert.False(t, called)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "key1"))
+	assert.NoError(t, stream(nil, &mockStream{ctx: ctx}, info, handler))
+	assert.True(t, called)
+}
+
+func TestUnary(t *testing.T) {
+	unary := newTestAuth().Unary()
+	info := &grpc.UnaryServerInfo{FullMethod: "/testproto.Greeter/SayHello"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	_, err := unary(context.Background(), nil, info, handler)
+	assert.Error(t, err)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-api-key", "key1"))
+	resp, err := unary(ctx, nil, info, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+}
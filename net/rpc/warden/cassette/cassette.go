@@ -0,0 +1,97 @@
+// Package cassette records and replays the warden client calls by the
+// net/cassette package, eg: client.Use(cassette.UnaryClientInterceptor(cs)).
+package cassette
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"net/http"
+
+	"github.com/gisvr/golib/net/cassette"
+
+	"github.com/gogo/protobuf/jsonpb"
+	gogoproto "github.com/gogo/protobuf/proto"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	_marshaler   = &jsonpb.Marshaler{OrigName: true}
+	_unmarshaler = &jsonpb.Unmarshaler{AllowUnknownFields: true}
+)
+
+// UnaryClientInterceptor returns an interceptor which records the calls, or
+// replays them without invoking. The calls are matched by the full method and
+// the request message in json, the outgoing metadata is recorded but not matched.
+func UnaryClientInterceptor(cs *cassette.Cassette) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) (err error) {
+		creq := &cassette.Request{Method: method}
+		if creq.Body, err = marshal(req); err != nil {
+			return
+		}
+		if md, ok := metadata.FromOutgoingContext(ctx); ok {
+			creq.Header = http.Header(md.Copy())
+		}
+		if !cs.Recording() {
+			var resp *cassette.Response
+			if resp, err = cs.Find(creq); err != nil {
+				return
+			}
+			return replay(resp, reply)
+		}
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		resp := &cassette.Response{Status: int(codes.OK)}
+		if err != nil {
+			st, ok := status.FromError(err)
+			if !ok {
+				// the non status errors are not recorded.
+				return
+			}
+			bs, merr := proto.Marshal(st.Proto())
+			if merr != nil {
+				return
+			}
+			resp.Status = int(st.Code())
+			resp.Error = base64.StdEncoding.EncodeToString(bs)
+		} else if resp.Body, err = marshal(reply); err != nil {
+			return
+		}
+		cs.Record(creq, resp)
+		return
+	}
+}
+
+func marshal(msg interface{}) (string, error) {
+	pb, ok := msg.(gogoproto.Message)
+	if !ok {
+		return "", errors.Errorf("cassette: %T is not a proto message", msg)
+	}
+	s, err := _marshaler.MarshalToString(pb)
+	return s, errors.Wrapf(err, "cassette: marshal %T", msg)
+}
+
+// replay unmarshals the reply or returns the recorded status error.
+func replay(resp *cassette.Response, reply interface{}) error {
+	if resp.Error != "" {
+		bs, err := base64.StdEncoding.DecodeString(resp.Error)
+		if err != nil {
+			return errors.Wrap(err, "cassette: decode status")
+		}
+		// the status proto is got from the status package to avoid importing its package.
+		sp := status.New(codes.Unknown, "").Proto()
+		if err = proto.Unmarshal(bs, sp); err != nil {
+			return errors.Wrap(err, "cassette: unmarshal status")
+		}
+		return status.FromProto(sp).Err()
+	}
+	pb, ok := reply.(gogoproto.Message)
+	if !ok {
+		return errors.Errorf("cassette: %T is not a proto message", reply)
+	}
+	return errors.Wrapf(_unmarshaler.Unmarshal(bytes.NewBufferString(resp.Body), pb), "cassette: unmarshal %T", reply)
+}
@@ -0,0 +1,51 @@
+package cassette
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gisvr/golib/net/cassette"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "cassette")
+	defer os.RemoveAll(dir)
+	conf := &cassette.Config{Path: filepath.Join(dir, "grpc.json"), Mode: cassette.ModeRecord}
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		in := req.(*types.StringValue)
+		if in.Value == "" {
+			return status.Error(codes.InvalidArgument, "empty name")
+		}
+		reply.(*types.StringValue).Value = "hello " + in.Value
+		return nil
+	}
+
+	cs, _ := cassette.New(conf)
+	interceptor := UnaryClientInterceptor(cs)
+	reply := new(types.StringValue)
+	assert.NoError(t, interceptor(context.TODO(), "/hello.Greeter/SayHello", &types.StringValue{Value: "kratos"}, reply, nil, invoker))
+	assert.Error(t, interceptor(context.TODO(), "/hello.Greeter/SayHello", &types.StringValue{}, new(types.StringValue), nil, invoker))
+	assert.NoError(t, cs.Save())
+
+	conf.Mode = cassette.ModeReplay
+	cs, err := cassette.New(conf)
+	assert.NoError(t, err)
+	interceptor = UnaryClientInterceptor(cs)
+	reply = new(types.StringValue)
+	assert.NoError(t, interceptor(context.TODO(), "/hello.Greeter/SayHello", &types.StringValue{Value: "kratos"}, reply, nil, nil))
+	assert.Equal(t, "hello kratos", reply.Value)
+	err = interceptor(context.TODO(), "/hello.Greeter/SayHello", &types.StringValue{}, new(types.StringValue), nil, nil)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, "empty name", status.Convert(err).Message())
+	err = interceptor(context.TODO(), "/hello.Greeter/SayHello", &types.StringValue{}, new(types.StringValue), nil, nil)
+	assert.Error(t, err, "all the interactions are used")
+}
@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/gisvr/golib/net/admin"
 	"github.com/gisvr/golib/net/rpc/warden/resolver"
 	"github.com/gisvr/golib/net/rpc/warden/resolver/direct"
 
@@ -84,6 +85,8 @@ type Client struct {
 
 	opts     []grpc.DialOption
 	handlers []grpc.UnaryClientInterceptor
+
+	unregister func()
 }
 
 // TimeoutCallOption timeout option.
@@ -236,7 +239,9 @@ func (c *Client) SetConfig(conf *ClientConfig) (err error) {
 	c.mutex.Lock()
 	c.conf = conf
 	if c.breaker == nil {
-		c.breaker = breaker.NewGroup(conf.Breaker)
+		brk := breaker.NewGroup(conf.Breaker)
+		c.breaker = brk
+		c.unregister = admin.RegisterStats("warden.client.breaker", func() interface{} { return brk.Stats() })
 	} else {
 		c.breaker.Reload(conf.Breaker)
 	}
@@ -244,6 +249,18 @@ func (c *Client) SetConfig(conf *ClientConfig) (err error) {
 	return nil
 }
 
+// Close unregisters the stats of the client, the dialed connections are
+// closed by their owners.
+func (c *Client) Close() error {
+	c.mutex.RLock()
+	unregister := c.unregister
+	c.mutex.RUnlock()
+	if unregister != nil {
+		unregister()
+	}
+	return nil
+}
+
 // Use attachs a global inteceptor to the Client.
 // For example, this is the right place for a circuit breaker or error management inteceptor.
 func (c *Client) Use(handlers ...grpc.UnaryClientInterceptor) *Client {
@@ -58,17 +58,17 @@ func extractLogDialOption(opts []grpc.DialOption) (flag int8) {
 	return
 }
 
-func logFn(code int, dt time.Duration) func(context.Context, ...log.D) {
+func logLevel(code int, dt time.Duration) log.Level {
 	switch {
 	case code < 0:
-		return log.Errorv
+		return log.ErrorLevel
 	case dt >= time.Millisecond*500:
 		// TODO: slowlog make it configurable.
-		return log.Warnv
+		return log.WarnLevel
 	case code > 0:
-		return log.Warnv
+		return log.WarnLevel
 	}
-	return log.Infov
+	return log.InfoLevel
 }
 
 // clientLogging warden grpc logging
@@ -113,7 +113,7 @@ func clientLogging(dialOptions ...grpc.DialOption) grpc.UnaryClientInterceptor {
 		if err != nil {
 			logFields = append(logFields, log.KVString("error", err.Error()), log.KVString("stack", fmt.Sprintf("%+v", err)))
 		}
-		logFn(code, duration)(ctx, logFields...)
+		log.FromContext(ctx).Logv(logLevel(code, duration), logFields...)
 		return err
 	}
 }
@@ -135,8 +135,9 @@ func serverLogging(logFlag int8) grpc.UnaryServerInterceptor {
 			quota = time.Until(deadline).Seconds()
 		}
 
-		// call server handler
-		resp, err := handler(ctx, req)
+		// call server handler with the request logger, which is got by log.FromContext.
+		logger := log.FromContext(ctx).With(log.KVString("path", info.FullMethod))
+		resp, err := handler(log.NewContext(ctx, logger), req)
 
 		// after server response
 		code := ecode.Cause(err).Code()
@@ -155,7 +156,6 @@ func serverLogging(logFlag int8) grpc.UnaryServerInterceptor {
 		logFields := []log.D{
 			log.KVString("user", caller),
 			log.KVString("ip", remoteIP),
-			log.KVString("path", info.FullMethod),
 			log.KVInt("ret", code),
 			log.KVFloat64("ts", duration.Seconds()),
 			log.KVFloat64("timeout_quota", quota),
@@ -168,7 +168,7 @@ func serverLogging(logFlag int8) grpc.UnaryServerInterceptor {
 		if err != nil {
 			logFields = append(logFields, log.KVString("error", err.Error()), log.KVString("stack", fmt.Sprintf("%+v", err)))
 		}
-		logFn(code, duration)(ctx, logFields...)
+		logger.Logv(logLevel(code, duration), logFields...)
 		return resp, err
 	}
 }
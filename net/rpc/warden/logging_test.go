@@ -6,7 +6,6 @@ import (
 	"errors"
 	"io/ioutil"
 	"os"
-	"reflect"
 	"testing"
 	"time"
 
@@ -16,7 +15,7 @@ import (
 	"github.com/gisvr/golib/log"
 )
 
-func Test_logFn(t *testing.T) {
+func Test_logLevel(t *testing.T) {
 	type args struct {
 		code int
 		dt   time.Duration
@@ -24,38 +23,38 @@ func Test_logFn(t *testing.T) {
 	tests := []struct {
 		name string
 		args args
-		want func(context.Context, ...log.D)
+		want log.Level
 	}{
 		{
 			name: "ok",
 			args: args{code: 0, dt: time.Millisecond},
-			want: log.Infov,
+			want: log.InfoLevel,
 		},
 		{
 			name: "slowlog",
 			args: args{code: 0, dt: time.Second},
-			want: log.Warnv,
+			want: log.WarnLevel,
 		},
 		{
 			name: "business error",
 			args: args{code: 2233, dt: time.Millisecond},
-			want: log.Warnv,
+			want: log.WarnLevel,
 		},
 		{
 			name: "system error",
 			args: args{code: -1, dt: 0},
-			want: log.Errorv,
+			want: log.ErrorLevel,
 		},
 		{
 			name: "system error and slowlog",
 			args: args{code: -1, dt: time.Second},
-			want: log.Errorv,
+			want: log.ErrorLevel,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := logFn(tt.args.code, tt.args.dt); reflect.ValueOf(got).Pointer() != reflect.ValueOf(tt.want).Pointer() {
-				t.Errorf("unexpect log function!")
+			if got := logLevel(tt.args.code, tt.args.dt); got != tt.want {
+				t.Errorf("unexpect log level %s, want %s", got, tt.want)
 			}
 		})
 	}
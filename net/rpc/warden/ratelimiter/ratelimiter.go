@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/gisvr/golib/log"
+	"github.com/gisvr/golib/net/admin"
 	limit "github.com/gisvr/golib/ratelimit"
 	"github.com/gisvr/golib/ratelimit/bbr"
 	"github.com/gisvr/golib/stat/metric"
@@ -26,14 +27,24 @@ var (
 type RateLimiter struct {
 	group   *bbr.Group
 	logTime int64
+
+	unregister func()
 }
 
 // New return a ratelimit middleware.
 func New(conf *bbr.Config) (s *RateLimiter) {
-	return &RateLimiter{
+	s = &RateLimiter{
 		group:   bbr.NewGroup(conf),
 		logTime: time.Now().UnixNano(),
 	}
+	s.unregister = admin.RegisterStats("warden.bbr", func() interface{} { return s.group.Stats() })
+	return
+}
+
+// Close unregisters the stats of the limiter.
+func (b *RateLimiter) Close() error {
+	b.unregister()
+	return nil
 }
 
 func (b *RateLimiter) printStats(fullMethod string, limiter limit.Limiter) {
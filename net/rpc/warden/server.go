@@ -79,6 +79,7 @@ type Server struct {
 
 	server   *grpc.Server
 	handlers []grpc.UnaryServerInterceptor
+	limiter  *ratelimiter.RateLimiter
 }
 
 // handle return a new unary server interceptor for OpenTracing\Logging\LinkTimeout.
@@ -188,7 +189,8 @@ func NewServer(conf *ServerConfig, opt ...grpc.ServerOption) (s *Server) {
 	opt = append(opt, keepParam, grpc.UnaryInterceptor(s.interceptor))
 	s.server = grpc.NewServer(opt...)
 	s.Use(s.recovery(), s.handle(), serverLogging(conf.LogFlag), s.stats(), s.validate())
-	s.Use(ratelimiter.New(nil).Limit())
+	s.limiter = ratelimiter.New(nil)
+	s.Use(s.limiter.Limit())
 	return
 }
 
@@ -347,6 +349,7 @@ func (s *Server) Serve(lis net.Listener) error {
 // accepting new connections and RPCs and blocks until all the pending RPCs are
 // finished or the context deadline is reached.
 func (s *Server) Shutdown(ctx context.Context) (err error) {
+	defer s.limiter.Close()
 	ch := make(chan struct{})
 	go func() {
 		s.server.GracefulStop()
@@ -37,6 +37,8 @@ type Config struct {
 	ProtocolVersion int32 `dsn:"query.protocol_version,1"`
 	// Probability probability sampling
 	Probability float32 `dsn:"-"`
+	// Propagation the propagations injected, e.g. kratos, w3c, b3, b3multi
+	Propagation []string `dsn:"query.propagation,kratos"`
 }
 
 func parseDSN(rawdsn string) (*Config, error) {
@@ -48,6 +50,9 @@ func parseDSN(rawdsn string) (*Config, error) {
 	if _, err = d.Bind(cfg); err != nil {
 		return nil, errors.Wrapf(err, "trace: invalid dsn: %s", rawdsn)
 	}
+	if _, err = parsePropagations(cfg.Propagation); err != nil {
+		return nil, errors.Wrapf(err, "trace: invalid dsn: %s", rawdsn)
+	}
 	return cfg, nil
 }
 
@@ -58,7 +63,8 @@ func TracerFromEnvFlag() (Tracer, error) {
 		return nil, err
 	}
 	report := newReport(cfg.Network, cfg.Addr, time.Duration(cfg.Timeout), cfg.ProtocolVersion)
-	return NewTracer(env.AppID, report, cfg.DisableSample), nil
+	ps, _ := parsePropagations(cfg.Propagation)
+	return NewTracer(env.AppID, report, cfg.DisableSample, WithPropagation(ps...)), nil
 }
 
 // Init init trace report.
@@ -70,6 +76,10 @@ func Init(cfg *Config) {
 			panic(fmt.Errorf("parse trace dsn error: %s", err))
 		}
 	}
+	ps, err := parsePropagations(cfg.Propagation)
+	if err != nil {
+		panic(err)
+	}
 	report := newReport(cfg.Network, cfg.Addr, time.Duration(cfg.Timeout), cfg.ProtocolVersion)
-	SetGlobalTracer(NewTracer(env.AppID, report, cfg.DisableSample))
+	SetGlobalTracer(NewTracer(env.AppID, report, cfg.DisableSample, WithPropagation(ps...)))
 }
@@ -5,3 +5,17 @@ const (
 	KratosTraceID    = "kratos-trace-id"
 	KratosTraceDebug = "kratos-trace-debug"
 )
+
+// W3C Trace Context and B3 keys, they are lowercase as the gRPC metadata keys
+// and http.Header canonicalizes them.
+const (
+	W3CTraceParent = "traceparent"
+	W3CTraceState  = "tracestate"
+
+	B3Single       = "b3"
+	B3TraceID      = "x-b3-traceid"
+	B3SpanID       = "x-b3-spanid"
+	B3ParentSpanID = "x-b3-parentspanid"
+	B3Sampled      = "x-b3-sampled"
+	B3Flags        = "x-b3-flags"
+)
@@ -10,6 +10,9 @@ import (
 const (
 	flagSampled = 0x01
 	flagDebug   = 0x02
+
+	// _extTraceIDHigh is the extension prefix of the high 64 bits trace id.
+	_extTraceIDHigh = "h-"
 )
 
 var (
@@ -23,6 +26,10 @@ type spanContext struct {
 	// Usually generated as a random number.
 	TraceID uint64
 
+	// TraceIDHigh is the high 64 bits of the 128 bits trace id of the W3C
+	// and B3 propagations, it is 0 if the trace id is 64 bits.
+	TraceIDHigh uint64
+
 	// SpanID represents span ID that must be unique within its trace,
 	// but does not have to be globally unique.
 	SpanID uint64
@@ -39,6 +46,10 @@ type spanContext struct {
 
 	// Level current level
 	Level int
+
+	// TraceState is the tracestate of the W3C propagation, it is passed
+	// through to the children as it is.
+	TraceState string
 }
 
 func (c spanContext) isSampled() bool {
@@ -67,12 +78,17 @@ var emptyContext = spanContext{}
 // - :1 debug flag
 // extend:
 // sample-rate: s-{base16(BigEndian(float32))}
+// trace-id-high: h-{base16(TraceIDHigh)}, the high 64 bits of the 128 bits
+// trace id, which is ignored by the older versions.
 func (c spanContext) String() string {
-	base := make([]string, 4)
+	base := make([]string, 4, 5)
 	base[0] = strconv.FormatUint(uint64(c.TraceID), 16)
 	base[1] = strconv.FormatUint(uint64(c.SpanID), 16)
 	base[2] = strconv.FormatUint(uint64(c.ParentID), 16)
 	base[3] = strconv.FormatUint(uint64(c.Flags), 16)
+	if c.TraceIDHigh != 0 {
+		base = append(base, _extTraceIDHigh+strconv.FormatUint(c.TraceIDHigh, 16))
+	}
 	return strings.Join(base, ":")
 }
 
@@ -106,5 +122,12 @@ func contextFromString(value string) (spanContext, error) {
 		ParentID: rets[2],
 		Flags:    byte(rets[3]),
 	}
+	for _, ext := range items[4:] {
+		if strings.HasPrefix(ext, _extTraceIDHigh) {
+			if sctx.TraceIDHigh, err = strconv.ParseUint(ext[len(_extTraceIDHigh):], 16, 64); err != nil {
+				return emptyContext, errInvalidTracerString
+			}
+		}
+	}
 	return sctx, nil
 }
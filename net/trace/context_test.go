@@ -24,3 +24,25 @@ func TestSpanContext(t *testing.T) {
 		t.Errorf("wrong spancontext get %+v -> %+v", pctx, pctx2)
 	}
 }
+
+func TestSpanContext128(t *testing.T) {
+	pctx := spanContext{TraceIDHigh: 0x4bf92f3577b34da6, TraceID: 0xa3ce929d0e0e4736, SpanID: 0xb7ad6b7169203331, Flags: flagSampled}
+	value := pctx.String()
+	// the older versions parse the low 64 bits and ignore the extension.
+	if value != "a3ce929d0e0e4736:b7ad6b7169203331:0:1:h-4bf92f3577b34da6" {
+		t.Errorf("wrong 128 bits trace id %s", value)
+	}
+	pctx2, err := contextFromString(value)
+	if err != nil {
+		t.Error(err)
+	}
+	if pctx2 != pctx {
+		t.Errorf("wrong spancontext get %+v -> %+v", pctx, pctx2)
+	}
+	if _, err = contextFromString("4bf92f3577b34da6a3ce929d0e0e4736:1:0:1"); err == nil {
+		t.Error("expect error of the trace id longer than 64 bits")
+	}
+	if _, err = contextFromString("a3ce929d0e0e4736:1:0:1:h-x"); err == nil {
+		t.Error("expect error of the invalid trace id extension")
+	}
+}
@@ -14,13 +14,13 @@ const (
 )
 
 // NewTracer new a tracer.
-func NewTracer(serviceName string, report reporter, disableSample bool) Tracer {
+func NewTracer(serviceName string, report reporter, disableSample bool, opts ...TracerOption) Tracer {
 	sampler := newSampler(_probability)
 
 	// default internal tags
 	tags := extendTag()
 	stdlog := log.New(os.Stderr, "trace", log.LstdFlags)
-	return &dapper{
+	d := &dapper{
 		serviceName:   serviceName,
 		disableSample: disableSample,
 		propagators: map[interface{}]propagator{
@@ -32,7 +32,29 @@ func NewTracer(serviceName string, report reporter, disableSample bool) Tracer {
 		tags:     tags,
 		pool:     &sync.Pool{New: func() interface{} { return new(Span) }},
 		stdlog:   stdlog,
+
+		propagations: []Propagation{PropagationKratos},
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	// extract the injected propagations first.
+	d.extractions = append(d.extractions, d.propagations...)
+	for _, p := range _propagations {
+		if !hasPropagation(d.extractions, p) {
+			d.extractions = append(d.extractions, p)
+		}
 	}
+	return d
+}
+
+func hasPropagation(ps []Propagation, p Propagation) bool {
+	for _, v := range ps {
+		if v == p {
+			return true
+		}
+	}
+	return false
 }
 
 type dapper struct {
@@ -44,6 +66,8 @@ type dapper struct {
 	pool          *sync.Pool
 	stdlog        *log.Logger
 	sampler       sampler
+	propagations  []Propagation
+	extractions   []Propagation
 }
 
 func (d *dapper) New(operationName string, opts ...Option) Trace {
@@ -86,10 +110,12 @@ func (d *dapper) newSpanWithContext(operationName string, pctx spanContext) Trac
 	}
 	level := pctx.Level + 1
 	nctx := spanContext{
-		TraceID:  pctx.TraceID,
-		ParentID: pctx.SpanID,
-		Flags:    pctx.Flags,
-		Level:    level,
+		TraceID:     pctx.TraceID,
+		TraceIDHigh: pctx.TraceIDHigh,
+		ParentID:    pctx.SpanID,
+		Flags:       pctx.Flags,
+		Level:       level,
+		TraceState:  pctx.TraceState,
 	}
 	if pctx.SpanID == 0 {
 		nctx.SpanID = pctx.TraceID
@@ -148,12 +174,18 @@ func (d *dapper) extract(format interface{}, carrier interface{}) (Trace, error)
 			return nil, err
 		}
 	}
-	pctx, err := contextFromString(carr.Get(KratosTraceID))
-	if err != nil {
-		return nil, err
+	err := ErrTraceNotFound
+	for _, p := range d.extractions {
+		pctx, perr := _codecs[p].extract(carr)
+		if perr == nil {
+			// NOTE: call SetTitle after extract trace
+			return d.newSpanWithContext("", pctx), nil
+		}
+		if err == ErrTraceNotFound {
+			err = perr
+		}
 	}
-	// NOTE: call SetTitle after extract trace
-	return d.newSpanWithContext("", pctx), nil
+	return nil, err
 }
 
 func (d *dapper) Close() error {
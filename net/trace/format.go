@@ -0,0 +1,246 @@
+package trace
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Propagation is a format of the trace context in the HTTP header and gRPC
+// metadata, the tracer injects the configured propagations and extracts any
+// of them.
+type Propagation string
+
+// support propagation list
+const (
+	// PropagationKratos is the kratos-trace-id key of spanContext.String(),
+	// the trace id carries the low 64 bits, the high 64 bits of a 128 bits
+	// trace id are carried by an extension which the older versions ignore.
+	PropagationKratos Propagation = "kratos"
+	// PropagationW3C is the traceparent and tracestate keys of W3C Trace Context.
+	PropagationW3C Propagation = "w3c"
+	// PropagationB3 is the b3 single key of Zipkin B3.
+	PropagationB3 Propagation = "b3"
+	// PropagationB3Multi is the x-b3-* keys of Zipkin B3.
+	PropagationB3Multi Propagation = "b3multi"
+)
+
+// _propagations are the propagations in the order of extraction if they are
+// not configured to inject.
+var _propagations = []Propagation{PropagationKratos, PropagationW3C, PropagationB3, PropagationB3Multi}
+
+type codec struct {
+	inject func(c spanContext, fn func(k, v string))
+	// extract returns ErrTraceNotFound if the keys are absent.
+	extract func(carr Carrier) (spanContext, error)
+}
+
+var _codecs = map[Propagation]codec{
+	PropagationKratos:  {injectKratos, extractKratos},
+	PropagationW3C:     {injectW3C, extractW3C},
+	PropagationB3:      {injectB3, extractB3},
+	PropagationB3Multi: {injectB3Multi, extractB3Multi},
+}
+
+// parsePropagations parses the propagation names, the names may be separated
+// by comma.
+func parsePropagations(names []string) ([]Propagation, error) {
+	var ps []Propagation
+	for _, name := range names {
+		for _, n := range strings.Split(name, ",") {
+			if n = strings.TrimSpace(n); n == "" {
+				continue
+			}
+			p := Propagation(strings.ToLower(n))
+			if _, ok := _codecs[p]; !ok {
+				return nil, errors.Errorf("trace: unknown propagation: %s", n)
+			}
+			ps = append(ps, p)
+		}
+	}
+	return ps, nil
+}
+
+func injectKratos(c spanContext, fn func(k, v string)) {
+	fn(KratosTraceID, c.String())
+}
+
+func extractKratos(carr Carrier) (spanContext, error) {
+	v := carr.Get(KratosTraceID)
+	if v == "" {
+		return emptyContext, ErrTraceNotFound
+	}
+	return contextFromString(v)
+}
+
+// injectW3C injects the traceparent:
+// {version}-{trace-id}-{parent-id}-{trace-flags}
+// version: 00
+// trace-id: 16 bytes base16
+// parent-id: 8 bytes base16
+// trace-flags: 1 byte base16, only the sampled flag is defined
+func injectW3C(c spanContext, fn func(k, v string)) {
+	fn(W3CTraceParent, fmt.Sprintf("00-%016x%016x-%016x-%02x", c.TraceIDHigh, c.TraceID, c.SpanID, c.Flags&flagSampled))
+	if c.TraceState != "" {
+		fn(W3CTraceState, c.TraceState)
+	}
+}
+
+func extractW3C(carr Carrier) (spanContext, error) {
+	v := strings.TrimSpace(carr.Get(W3CTraceParent))
+	if v == "" {
+		return emptyContext, ErrTraceNotFound
+	}
+	// the future versions may append fields after the trace-flags.
+	if len(v) < 55 || v[2] != '-' || v[35] != '-' || v[52] != '-' ||
+		v[:2] == "ff" || (len(v) > 55 && (v[:2] == "00" || v[55] != '-')) {
+		return emptyContext, ErrTraceCorrupted
+	}
+	high, ok1 := parseHexID(v[3:19])
+	low, ok2 := parseHexID(v[19:35])
+	span, ok3 := parseHexID(v[36:52])
+	flags, ok4 := parseHexID(v[53:55])
+	if _, ok := parseHexID(v[:2]); !ok || !ok1 || !ok2 || !ok3 || !ok4 || high|low == 0 || span == 0 {
+		return emptyContext, ErrTraceCorrupted
+	}
+	return spanContext{
+		TraceID:     low,
+		TraceIDHigh: high,
+		SpanID:      span,
+		Flags:       byte(flags) & flagSampled,
+		TraceState:  strings.TrimSpace(carr.Get(W3CTraceState)),
+	}, nil
+}
+
+// b3TraceID returns the 16 or 32 base16 trace id.
+func b3TraceID(c spanContext) string {
+	if c.TraceIDHigh != 0 {
+		return fmt.Sprintf("%016x%016x", c.TraceIDHigh, c.TraceID)
+	}
+	return fmt.Sprintf("%016x", c.TraceID)
+}
+
+// injectB3 injects the b3:
+// {TraceId}-{SpanId}-{SamplingState}-{ParentSpanId}
+// SamplingState: d for debug, 1 for sampled and 0 for not sampled
+// ParentSpanId: omitted if the span is a root span
+func injectB3(c spanContext, fn func(k, v string)) {
+	state := "0"
+	switch {
+	case c.isDebug():
+		state = "d"
+	case c.isSampled():
+		state = "1"
+	}
+	v := b3TraceID(c) + "-" + fmt.Sprintf("%016x", c.SpanID) + "-" + state
+	if c.ParentID != 0 {
+		v += fmt.Sprintf("-%016x", c.ParentID)
+	}
+	fn(B3Single, v)
+}
+
+func extractB3(carr Carrier) (spanContext, error) {
+	v := strings.TrimSpace(carr.Get(B3Single))
+	if v == "" {
+		return emptyContext, ErrTraceNotFound
+	}
+	parts := strings.Split(v, "-")
+	if len(parts) == 1 {
+		// only the sampling state, there is no trace to continue.
+		return emptyContext, ErrTraceNotFound
+	}
+	if len(parts) > 4 {
+		return emptyContext, ErrTraceCorrupted
+	}
+	c, ok := parseB3IDs(parts[0], parts[1])
+	if !ok {
+		return emptyContext, ErrTraceCorrupted
+	}
+	if len(parts) > 2 {
+		if c.Flags, ok = parseB3Sampled(parts[2]); !ok {
+			return emptyContext, ErrTraceCorrupted
+		}
+	}
+	if len(parts) > 3 {
+		if _, ok = parseHexID(parts[3]); !ok || len(parts[3]) != 16 {
+			return emptyContext, ErrTraceCorrupted
+		}
+	}
+	return c, nil
+}
+
+// injectB3Multi injects the x-b3-* keys, x-b3-sampled is omitted if x-b3-flags
+// is the debug flag.
+func injectB3Multi(c spanContext, fn func(k, v string)) {
+	fn(B3TraceID, b3TraceID(c))
+	fn(B3SpanID, fmt.Sprintf("%016x", c.SpanID))
+	if c.ParentID != 0 {
+		fn(B3ParentSpanID, fmt.Sprintf("%016x", c.ParentID))
+	}
+	switch {
+	case c.isDebug():
+		fn(B3Flags, "1")
+	case c.isSampled():
+		fn(B3Sampled, "1")
+	default:
+		fn(B3Sampled, "0")
+	}
+}
+
+func extractB3Multi(carr Carrier) (spanContext, error) {
+	tid, sid := strings.TrimSpace(carr.Get(B3TraceID)), strings.TrimSpace(carr.Get(B3SpanID))
+	if tid == "" && sid == "" {
+		return emptyContext, ErrTraceNotFound
+	}
+	c, ok := parseB3IDs(tid, sid)
+	if !ok {
+		return emptyContext, ErrTraceCorrupted
+	}
+	if v := strings.TrimSpace(carr.Get(B3Sampled)); v != "" {
+		if c.Flags, ok = parseB3Sampled(v); !ok {
+			return emptyContext, ErrTraceCorrupted
+		}
+	}
+	if strings.TrimSpace(carr.Get(B3Flags)) == "1" {
+		c.Flags = flagSampled | flagDebug
+	}
+	return c, nil
+}
+
+func parseB3IDs(tid, sid string) (c spanContext, ok bool) {
+	switch len(tid) {
+	case 16:
+		c.TraceID, ok = parseHexID(tid)
+	case 32:
+		var ok1 bool
+		c.TraceIDHigh, ok1 = parseHexID(tid[:16])
+		c.TraceID, ok = parseHexID(tid[16:])
+		ok = ok && ok1
+	}
+	if !ok || c.TraceIDHigh|c.TraceID == 0 || len(sid) != 16 {
+		return emptyContext, false
+	}
+	if c.SpanID, ok = parseHexID(sid); !ok || c.SpanID == 0 {
+		return emptyContext, false
+	}
+	return c, true
+}
+
+func parseB3Sampled(v string) (byte, bool) {
+	switch v {
+	case "1", "true":
+		return flagSampled, true
+	case "0", "false":
+		return 0, true
+	case "d":
+		return flagSampled | flagDebug, true
+	}
+	return 0, false
+}
+
+func parseHexID(s string) (uint64, bool) {
+	id, err := strconv.ParseUint(s, 16, 64)
+	return id, err == nil
+}
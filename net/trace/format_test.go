@@ -0,0 +1,142 @@
+package trace
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestPropagationInjectExtract(t *testing.T) {
+	for _, p := range _propagations {
+		t.Run(string(p), func(t *testing.T) {
+			report := &mockReport{}
+			t1 := NewTracer("service1", report, true, WithPropagation(p))
+			t2 := NewTracer("service2", report, true)
+			sp1 := t1.New("opt_1", EnableDebug())
+			sp2 := sp1.Fork("", "opt_client")
+
+			header := make(http.Header)
+			assert.NoError(t, t1.Inject(sp2, HTTPFormat, header))
+			sp3, err := t2.Extract(HTTPFormat, header)
+			assert.NoError(t, err)
+			ctx2, ctx3 := sp2.(*Span).context, sp3.(*Span).context
+			assert.Equal(t, ctx2.TraceID, ctx3.TraceID)
+			assert.Equal(t, ctx2.SpanID, ctx3.ParentID)
+			assert.True(t, ctx3.isSampled())
+
+			md := make(metadata.MD)
+			assert.NoError(t, t1.Inject(sp2, GRPCFormat, md))
+			sp4, err := t2.Extract(GRPCFormat, md)
+			assert.NoError(t, err)
+			assert.Equal(t, ctx2.TraceID, sp4.(*Span).context.TraceID)
+			assert.Equal(t, ctx2.SpanID, sp4.(*Span).context.ParentID)
+		})
+	}
+}
+
+func TestPropagationInjectKeys(t *testing.T) {
+	report := &mockReport{}
+	sp := NewTracer("service1", report, true, WithPropagation(PropagationW3C, PropagationB3Multi)).New("opt_1")
+	header := make(http.Header)
+	sp.Visit(header.Set)
+	assert.Empty(t, header.Get(KratosTraceID))
+	assert.Empty(t, header.Get(B3Single))
+	assert.NotEmpty(t, header.Get(W3CTraceParent))
+	assert.NotEmpty(t, header.Get(B3TraceID))
+	assert.Equal(t, "1", header.Get(B3Sampled))
+
+	sp = NewTracer("service1", report, true).New("opt_1")
+	header = make(http.Header)
+	sp.Visit(header.Set)
+	assert.Len(t, header, 1)
+	assert.NotEmpty(t, header.Get(KratosTraceID))
+
+	assert.Panics(t, func() { WithPropagation("unknown") })
+}
+
+func TestExtractW3C(t *testing.T) {
+	report := &mockReport{}
+	tr := NewTracer("service1", report, true, WithPropagation(PropagationW3C))
+	md := metadata.MD{
+		W3CTraceParent: []string{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		W3CTraceState:  []string{"congo=t61rcWkgMzE"},
+	}
+	sp, err := tr.Extract(GRPCFormat, md)
+	assert.NoError(t, err)
+	ctx := sp.(*Span).context
+	assert.Equal(t, uint64(0x4bf92f3577b34da6), ctx.TraceIDHigh)
+	assert.Equal(t, uint64(0xa3ce929d0e0e4736), ctx.TraceID)
+	assert.Equal(t, uint64(0x00f067aa0ba902b7), ctx.ParentID)
+	assert.True(t, ctx.isSampled())
+	// the trace id of the logs carries the high 64 bits by the extension.
+	assert.Regexp(t, "^a3ce929d0e0e4736:.*:h-4bf92f3577b34da6$", sp.TraceID())
+
+	// the trace id and tracestate are passed through to the children.
+	out := make(metadata.MD)
+	assert.NoError(t, tr.Inject(sp.Fork("", "opt_client"), GRPCFormat, out))
+	assert.Regexp(t, "^00-4bf92f3577b34da6a3ce929d0e0e4736-[0-9a-f]{16}-01$", out.Get(W3CTraceParent)[0])
+	assert.Equal(t, []string{"congo=t61rcWkgMzE"}, out.Get(W3CTraceState))
+
+	for _, v := range []string{
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01",
+	} {
+		_, err = tr.Extract(HTTPFormat, http.Header{"Traceparent": []string{v}})
+		assert.Equal(t, ErrTraceCorrupted, err, v)
+	}
+	// future versions may append fields.
+	_, err = tr.Extract(HTTPFormat, http.Header{"Traceparent": []string{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"}})
+	assert.NoError(t, err)
+}
+
+func TestExtractB3(t *testing.T) {
+	report := &mockReport{}
+	tr := NewTracer("service1", report, true)
+
+	header := make(http.Header)
+	header.Set(B3Single, "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-d-05e3ac9a4f6e3b90")
+	sp, err := tr.Extract(HTTPFormat, header)
+	assert.NoError(t, err)
+	ctx := sp.(*Span).context
+	assert.Equal(t, uint64(0x80f198ee56343ba8), ctx.TraceIDHigh)
+	assert.Equal(t, uint64(0x64fe8b2a57d3eff7), ctx.TraceID)
+	assert.Equal(t, uint64(0xe457b5a2e4d86bd1), ctx.ParentID)
+	assert.True(t, ctx.isDebug())
+
+	header = make(http.Header)
+	header.Set(B3TraceID, "463ac35c9f6413ad")
+	header.Set(B3SpanID, "a2fb4a1d1a96d312")
+	header.Set(B3Sampled, "0")
+	sp, err = tr.Extract(HTTPFormat, header)
+	assert.NoError(t, err)
+	ctx = sp.(*Span).context
+	assert.Equal(t, uint64(0x463ac35c9f6413ad), ctx.TraceID)
+	assert.Equal(t, uint64(0xa2fb4a1d1a96d312), ctx.ParentID)
+	assert.False(t, ctx.isSampled())
+
+	_, err = tr.Extract(HTTPFormat, http.Header{"B3": []string{"0"}})
+	assert.Equal(t, ErrTraceNotFound, err)
+	_, err = tr.Extract(HTTPFormat, http.Header{"B3": []string{"463ac35c9f6413ad-a2fb4a1d1a96d312-x"}})
+	assert.Equal(t, ErrTraceCorrupted, err)
+	_, err = tr.Extract(HTTPFormat, make(http.Header))
+	assert.Equal(t, ErrTraceNotFound, err)
+}
+
+func TestParsePropagations(t *testing.T) {
+	ps, err := parsePropagations([]string{"w3c, B3", "b3multi"})
+	assert.NoError(t, err)
+	assert.Equal(t, []Propagation{PropagationW3C, PropagationB3, PropagationB3Multi}, ps)
+	_, err = parsePropagations([]string{"jaeger"})
+	assert.Error(t, err)
+
+	cfg, err := parseDSN("unixgram:///var/run/dapper-collect/dapper-collect.sock?propagation=kratos,w3c")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"kratos,w3c"}, cfg.Propagation)
+	_, err = parseDSN("unixgram:///var/run/dapper-collect/dapper-collect.sock?propagation=jaeger")
+	assert.Error(t, err)
+}
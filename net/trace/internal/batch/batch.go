@@ -0,0 +1,242 @@
+// Package batch sends the spans of the trace reporters in batches in a
+// goroutine, with a bounded queue, retry and the self metrics.
+package batch
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/gisvr/golib/stat/metric"
+)
+
+// results of the spans and batches.
+const (
+	_resultSent    = "sent"
+	_resultFull    = "dropped_full"
+	_resultClosed  = "dropped_closed"
+	_resultFailed  = "failed"
+	_resultRetried = "retried"
+)
+
+var (
+	// ErrQueueFull occurs when the span is dropped as the queue is full.
+	ErrQueueFull = errors.New("trace: report queue is full")
+	// ErrClosed occurs when the span is written after the reporter closed.
+	ErrClosed = errors.New("trace: reporter already closed")
+)
+
+var (
+	_metricSpans = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: "trace",
+		Subsystem: "report",
+		Name:      "spans_total",
+		Help:      "trace reporter spans by result.",
+		Labels:    []string{"reporter", "result"},
+	})
+	_metricBatches = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: "trace",
+		Subsystem: "report",
+		Name:      "batches_total",
+		Help:      "trace reporter batches by result.",
+		Labels:    []string{"reporter", "result"},
+	})
+	_metricQueue = metric.NewGaugeVec(&metric.GaugeVecOpts{
+		Namespace: "trace",
+		Subsystem: "report",
+		Name:      "queue_length",
+		Help:      "trace reporter pending spans.",
+		Labels:    []string{"reporter"},
+	})
+	_metricDuration = metric.NewHistogramVec(&metric.HistogramVecOpts{
+		Namespace: "trace",
+		Subsystem: "report",
+		Name:      "send_duration_ms",
+		Help:      "trace reporter send batch duration(ms).",
+		Labels:    []string{"reporter"},
+		Buckets:   []float64{5, 10, 25, 50, 100, 250, 500, 1000, 5000},
+	})
+)
+
+// Config is the config of the batcher.
+type Config struct {
+	// Name is the reporter label of the metrics, e.g. otlp, jaeger.
+	Name string
+	// BatchSize is the max spans of a batch, default 100.
+	BatchSize int
+	// FlushInterval is the max time a span waits for the batch, default 1s.
+	FlushInterval time.Duration
+	// QueueSize is the max pending spans, the spans are dropped if it is full,
+	// default 2048.
+	QueueSize int
+	// MaxRetry is the max retries of a batch, default 3, the batches are not
+	// retried after closed.
+	MaxRetry int
+	// RetryBackoff is the first retry interval which is doubled after each
+	// retry, default 100ms.
+	RetryBackoff time.Duration
+}
+
+// Sender sends a batch of the spans, the batch is retried unless the error
+// is Permanent.
+type Sender func(spans []interface{}) error
+
+type permanentError struct {
+	error
+}
+
+// Permanent marks the error not retryable, e.g. the 4xx responses.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+// Batcher queues the spans and sends them in batches by a goroutine.
+type Batcher struct {
+	c    *Config
+	send Sender
+
+	mu     sync.RWMutex
+	closed bool
+	ch     chan interface{}
+	stop   chan struct{} // closed by Close, which stops the retries.
+	done   chan struct{}
+}
+
+// New new a batcher and starts the goroutine.
+func New(c *Config, send Sender) *Batcher {
+	if c.BatchSize <= 0 {
+		c.BatchSize = 100
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = time.Second
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = 2048
+	}
+	if c.MaxRetry <= 0 {
+		c.MaxRetry = 3
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = 100 * time.Millisecond
+	}
+	b := &Batcher{
+		c:    c,
+		send: send,
+		ch:   make(chan interface{}, c.QueueSize),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go b.proc()
+	return b
+}
+
+// Add queues the span, it is dropped if the queue is full.
+func (b *Batcher) Add(span interface{}) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		_metricSpans.Inc(b.c.Name, _resultClosed)
+		return ErrClosed
+	}
+	select {
+	case b.ch <- span:
+		_metricQueue.Inc(b.c.Name)
+		return nil
+	default:
+		_metricSpans.Inc(b.c.Name, _resultFull)
+		return ErrQueueFull
+	}
+}
+
+func (b *Batcher) proc() {
+	defer close(b.done)
+	tk := time.NewTicker(b.c.FlushInterval)
+	defer tk.Stop()
+	spans := make([]interface{}, 0, b.c.BatchSize)
+	flush := func(retry bool) {
+		if len(spans) > 0 {
+			_metricQueue.Add(-float64(len(spans)), b.c.Name)
+			b.post(spans, retry)
+			spans = make([]interface{}, 0, b.c.BatchSize)
+		}
+	}
+	for {
+		select {
+		case span, ok := <-b.ch:
+			if !ok {
+				flush(false)
+				return
+			}
+			if spans = append(spans, span); len(spans) >= b.c.BatchSize {
+				flush(true)
+			}
+		case <-tk.C:
+			flush(true)
+		}
+	}
+}
+
+// post sends the batch, it is retried with backoff until the batcher is closed.
+func (b *Batcher) post(spans []interface{}, retry bool) {
+	backoff := b.c.RetryBackoff
+	for i := 0; ; i++ {
+		start := time.Now()
+		err := b.send(spans)
+		_metricDuration.Observe(int64(time.Since(start)/time.Millisecond), b.c.Name)
+		if err == nil {
+			_metricBatches.Inc(b.c.Name, _resultSent)
+			_metricSpans.Add(float64(len(spans)), b.c.Name, _resultSent)
+			return
+		}
+		_, permanent := err.(permanentError)
+		if permanent || !retry || i >= b.c.MaxRetry || b.stopped() {
+			b.fail(spans, err)
+			return
+		}
+		_metricBatches.Inc(b.c.Name, _resultRetried)
+		select {
+		case <-time.After(backoff):
+		case <-b.stop:
+			b.fail(spans, err)
+			return
+		}
+		backoff *= 2
+	}
+}
+
+func (b *Batcher) fail(spans []interface{}, err error) {
+	fmt.Fprintf(os.Stderr, "trace: %s report %d spans error(%v)\n", b.c.Name, len(spans), err)
+	_metricBatches.Inc(b.c.Name, _resultFailed)
+	_metricSpans.Add(float64(len(spans)), b.c.Name, _resultFailed)
+}
+
+func (b *Batcher) stopped() bool {
+	select {
+	case <-b.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close sends the pending spans once without retry, the retries of the
+// in-flight batch are stopped too.
+func (b *Batcher) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	close(b.stop)
+	close(b.ch)
+	b.mu.Unlock()
+	<-b.done
+	return nil
+}
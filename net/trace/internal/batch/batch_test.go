@@ -0,0 +1,115 @@
+package batch
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]interface{}
+	errs    []error
+}
+
+func (r *recorder) send(spans []interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, spans)
+	if len(r.errs) > 0 {
+		err := r.errs[0]
+		r.errs = r.errs[1:]
+		return err
+	}
+	return nil
+}
+
+func (r *recorder) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.batches)
+}
+
+// wait waits until n batches are sent.
+func (r *recorder) wait(n int) {
+	for i := 0; i < 100 && r.len() < n; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBatchSize(t *testing.T) {
+	r := &recorder{}
+	b := New(&Config{Name: "test", BatchSize: 2, FlushInterval: time.Hour}, r.send)
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, b.Add(i))
+	}
+	assert.NoError(t, b.Close())
+	assert.Equal(t, [][]interface{}{{0, 1}, {2, 3}, {4}}, r.batches)
+	assert.Equal(t, ErrClosed, b.Add(5))
+}
+
+func TestFlushInterval(t *testing.T) {
+	r := &recorder{}
+	b := New(&Config{Name: "test", FlushInterval: 10 * time.Millisecond}, r.send)
+	defer b.Close()
+	b.Add(1)
+	for i := 0; i < 100 && r.len() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, 1, r.len())
+}
+
+func TestRetry(t *testing.T) {
+	r := &recorder{errs: []error{errors.New("unavailable"), errors.New("unavailable")}}
+	b := New(&Config{Name: "test", BatchSize: 1, RetryBackoff: time.Millisecond}, r.send)
+	b.Add(1)
+	r.wait(3)
+	b.Close()
+	assert.Equal(t, [][]interface{}{{1}, {1}, {1}}, r.batches)
+
+	r = &recorder{errs: []error{Permanent(errors.New("bad request"))}}
+	b = New(&Config{Name: "test", BatchSize: 1, RetryBackoff: time.Millisecond}, r.send)
+	b.Add(1)
+	r.wait(1)
+	b.Close()
+	assert.Len(t, r.batches, 1)
+
+	r = &recorder{errs: []error{errors.New("1"), errors.New("2"), errors.New("3"), errors.New("4"), errors.New("5")}}
+	b = New(&Config{Name: "test", BatchSize: 1, MaxRetry: 2, RetryBackoff: time.Millisecond}, r.send)
+	b.Add(1)
+	r.wait(3)
+	time.Sleep(20 * time.Millisecond)
+	b.Close()
+	assert.Len(t, r.batches, 3)
+}
+
+func TestCloseStopsRetry(t *testing.T) {
+	r := &recorder{errs: []error{errors.New("1"), errors.New("2"), errors.New("3")}}
+	b := New(&Config{Name: "test", BatchSize: 1, RetryBackoff: time.Hour}, r.send)
+	b.Add(1)
+	r.wait(1)
+	start := time.Now()
+	b.Close()
+	assert.True(t, time.Since(start) < time.Second, "the backoff is interrupted")
+	assert.Len(t, r.batches, 1, "the batch is not retried after closed")
+}
+
+func TestQueueFull(t *testing.T) {
+	block := make(chan struct{})
+	b := New(&Config{Name: "test", BatchSize: 1, QueueSize: 1}, func([]interface{}) error {
+		<-block
+		return nil
+	})
+	// the first span is blocked in send, the second is queued.
+	b.Add(1)
+	var err error
+	for i := 0; i < 100 && err == nil; i++ {
+		err = b.Add(i)
+	}
+	assert.Equal(t, ErrQueueFull, err)
+	close(block)
+	b.Close()
+}
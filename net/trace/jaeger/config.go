@@ -0,0 +1,30 @@
+package jaeger
+
+import (
+	"github.com/gisvr/golib/conf/env"
+	"github.com/gisvr/golib/net/trace"
+	xtime "github.com/gisvr/golib/time"
+)
+
+// Config config.
+// endpoint should be the collector endpoint to send the spans to over HTTP,
+// e.g. http://localhost:14268/api/traces, or the spans are sent to the agent
+// address over UDP, e.g. localhost:6831.
+type Config struct {
+	Endpoint  string `dsn:"endpoint"`
+	AgentAddr string `dsn:"address"`
+	// Headers are the extra request headers of the collector, e.g. Authorization.
+	Headers       map[string]string `dsn:"-"`
+	MaxPacketSize int               `dsn:"query.max_packet_size,65000"`
+	BatchSize     int               `dsn:"query.batch_size,100"`
+	FlushInterval xtime.Duration    `dsn:"query.flush_interval,1s"`
+	QueueSize     int               `dsn:"query.queue_size,2048"`
+	MaxRetry      int               `dsn:"query.max_retry,3"`
+	Timeout       xtime.Duration    `dsn:"query.timeout,5s"`
+	DisableSample bool              `dsn:"query.disable_sample"`
+}
+
+// Init init trace report.
+func Init(c *Config, opts ...trace.TracerOption) {
+	trace.SetGlobalTracer(trace.NewTracer(env.AppID, newReport(c), c.DisableSample, opts...))
+}
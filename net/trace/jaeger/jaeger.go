@@ -0,0 +1,249 @@
+package jaeger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/uber/jaeger-client-go/thrift"
+	j "github.com/uber/jaeger-client-go/thrift-gen/jaeger"
+	"github.com/uber/jaeger-client-go/utils"
+
+	"github.com/gisvr/golib/conf/env"
+	"github.com/gisvr/golib/net/trace"
+	"github.com/gisvr/golib/net/trace/internal/batch"
+	xtime "github.com/gisvr/golib/time"
+)
+
+const (
+	// _udpPacketMaxLength is the max size of the packet of the jaeger agent.
+	_udpPacketMaxLength = utils.UDPPacketMaxLength
+	// _emitBatchOverhead is the bytes of the emitBatch message except the
+	// process and spans.
+	_emitBatchOverhead = 70
+)
+
+// span is the jaeger span of the trace.Span which is reused after WriteSpan
+// returns.
+type span struct {
+	service string
+	span    *j.Span
+}
+
+type report struct {
+	c      *Config
+	client *http.Client
+	agent  *utils.AgentClientUDP
+	batch  *batch.Batcher
+
+	// sizeBuf and sizeProto calculate the size of the udp packets.
+	sizeBuf   *thrift.TMemoryBuffer
+	sizeProto thrift.TProtocol
+}
+
+// newReport new a report, it panics if the agent address is invalid.
+func newReport(c *Config) *report {
+	if c.MaxPacketSize <= 0 {
+		c.MaxPacketSize = _udpPacketMaxLength
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = xtime.Duration(5 * time.Second)
+	}
+	r := &report{c: c}
+	if c.Endpoint != "" {
+		r.client = &http.Client{Timeout: time.Duration(c.Timeout)}
+	} else {
+		agent, err := utils.NewAgentClientUDP(c.AgentAddr, c.MaxPacketSize)
+		if err != nil {
+			panic(fmt.Errorf("jaeger: new agent client %s error: %s", c.AgentAddr, err))
+		}
+		r.agent = agent
+		r.sizeBuf = thrift.NewTMemoryBufferLen(c.MaxPacketSize)
+		r.sizeProto = thrift.NewTCompactProtocolFactory().GetProtocol(r.sizeBuf)
+	}
+	r.batch = batch.New(&batch.Config{
+		Name:          "jaeger",
+		BatchSize:     c.BatchSize,
+		FlushInterval: time.Duration(c.FlushInterval),
+		QueueSize:     c.QueueSize,
+		MaxRetry:      c.MaxRetry,
+	}, r.send)
+	return r
+}
+
+// WriteSpan write a trace span to queue.
+func (r *report) WriteSpan(raw *trace.Span) error {
+	return r.batch.Add(&span{service: raw.ServiceName(), span: toJaegerSpan(raw)})
+}
+
+// Close close the report.
+func (r *report) Close() error {
+	err := r.batch.Close()
+	if r.agent != nil {
+		r.agent.Close()
+	}
+	return err
+}
+
+// send sends the batches of the spans grouped by the service as the process.
+func (r *report) send(spans []interface{}) error {
+	var services []string
+	groups := make(map[string][]*j.Span)
+	for _, v := range spans {
+		sp, ok := v.(*span)
+		if !ok {
+			return batch.Permanent(fmt.Errorf("jaeger: unknown span type %T", v))
+		}
+		if _, ok := groups[sp.service]; !ok {
+			services = append(services, sp.service)
+		}
+		groups[sp.service] = append(groups[sp.service], sp.span)
+	}
+	if r.agent != nil {
+		return r.emit(services, groups)
+	}
+	for i, service := range services {
+		if err := r.post(&j.Batch{Process: newProcess(service), Spans: groups[service]}); err != nil {
+			if i > 0 {
+				// the batches of the other services have been sent.
+				return batch.Permanent(err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// post posts the batch in the thrift binary protocol to the collector.
+func (r *report) post(b *j.Batch) error {
+	body, err := thrift.NewTSerializer().Write(b)
+	if err != nil {
+		return batch.Permanent(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, r.c.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return batch.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/x-thrift")
+	for k, v := range r.c.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("http status %d", resp.StatusCode)
+	default:
+		return batch.Permanent(fmt.Errorf("http status %d", resp.StatusCode))
+	}
+}
+
+// emit emits the batches to the agent, the spans are split into the packets
+// within MaxPacketSize, the errors are not retried as the packets may have
+// been sent partly.
+func (r *report) emit(services []string, groups map[string][]*j.Span) error {
+	var err error
+	for _, service := range services {
+		process := newProcess(service)
+		overhead := r.sizeOf(process) + _emitBatchOverhead
+		spans := groups[service]
+		start, size := 0, overhead
+		for i, sp := range spans {
+			n := r.sizeOf(sp)
+			if size+n > r.c.MaxPacketSize && i > start {
+				if eerr := r.agent.EmitBatch(&j.Batch{Process: process, Spans: spans[start:i]}); eerr != nil {
+					err = eerr
+				}
+				start, size = i, overhead
+			}
+			size += n
+		}
+		if eerr := r.agent.EmitBatch(&j.Batch{Process: process, Spans: spans[start:]}); eerr != nil {
+			err = eerr
+		}
+	}
+	return batch.Permanent(err)
+}
+
+func (r *report) sizeOf(s thrift.TStruct) int {
+	r.sizeBuf.Reset()
+	s.Write(r.sizeProto)
+	return r.sizeBuf.Len()
+}
+
+func newProcess(service string) *j.Process {
+	return &j.Process{
+		ServiceName: service,
+		Tags:        []*j.Tag{toJaegerTag("hostname", env.Hostname)},
+	}
+}
+
+// toJaegerSpan converts the span, the times are in microseconds, the flags
+// are the same as the jaeger flags, sampled 0x01 and debug 0x02.
+func toJaegerSpan(raw *trace.Span) *j.Span {
+	ctx := raw.Context()
+	sp := &j.Span{
+		TraceIdLow:    int64(ctx.TraceID),
+		TraceIdHigh:   int64(ctx.TraceIDHigh),
+		SpanId:        int64(ctx.SpanID),
+		ParentSpanId:  int64(ctx.ParentID),
+		OperationName: raw.OperationName(),
+		Flags:         int32(ctx.Flags),
+		StartTime:     raw.StartTime().UnixNano() / int64(time.Microsecond),
+		Duration:      int64(raw.Duration() / time.Microsecond),
+	}
+	tags := raw.Tags()
+	sp.Tags = make([]*j.Tag, 0, len(tags))
+	for _, tag := range tags {
+		sp.Tags = append(sp.Tags, toJaegerTag(tag.Key, tag.Value))
+	}
+	logs := raw.Logs()
+	sp.Logs = make([]*j.Log, 0, len(logs))
+	for _, lg := range logs {
+		jl := &j.Log{
+			Timestamp: lg.Timestamp / int64(time.Microsecond),
+			Fields:    make([]*j.Tag, 0, len(lg.Fields)),
+		}
+		for _, f := range lg.Fields {
+			jl.Fields = append(jl.Fields, toJaegerTag(f.Key, string(f.Value)))
+		}
+		sp.Logs = append(sp.Logs, jl)
+	}
+	return sp
+}
+
+func toJaegerTag(key string, val interface{}) *j.Tag {
+	tag := &j.Tag{Key: key}
+	switch val := val.(type) {
+	case string:
+		tag.VType, tag.VStr = j.TagType_STRING, &val
+	case bool:
+		tag.VType, tag.VBool = j.TagType_BOOL, &val
+	case int:
+		v := int64(val)
+		tag.VType, tag.VLong = j.TagType_LONG, &v
+	case int32:
+		v := int64(val)
+		tag.VType, tag.VLong = j.TagType_LONG, &v
+	case int64:
+		tag.VType, tag.VLong = j.TagType_LONG, &val
+	case float32:
+		v := float64(val)
+		tag.VType, tag.VDouble = j.TagType_DOUBLE, &v
+	case float64:
+		tag.VType, tag.VDouble = j.TagType_DOUBLE, &val
+	default:
+		v := fmt.Sprint(val)
+		tag.VType, tag.VStr = j.TagType_STRING, &v
+	}
+	return tag
+}
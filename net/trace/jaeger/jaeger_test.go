@@ -0,0 +1,167 @@
+package jaeger
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/jaeger-client-go/thrift"
+	"github.com/uber/jaeger-client-go/thrift-gen/agent"
+	j "github.com/uber/jaeger-client-go/thrift-gen/jaeger"
+
+	"github.com/gisvr/golib/net/trace"
+	xtime "github.com/gisvr/golib/time"
+)
+
+func newSpans(tracer trace.Tracer) (sp1, sp2 trace.Trace) {
+	sp1 = tracer.New("root")
+	sp2 = sp1.Fork("", "client").SetTag(trace.TagInt64("n", 3), trace.TagFloat64("f", 0.5))
+	sp2.SetLog(trace.Log(trace.LogEvent, "retry"))
+	return sp1, sp2
+}
+
+func assertBatch(t *testing.T, b *j.Batch, traceID, spanID, parentID uint64) {
+	assert.Equal(t, "service1", b.Process.ServiceName)
+	assert.Equal(t, "hostname", b.Process.Tags[0].Key)
+	if !assert.Len(t, b.Spans, 2) {
+		return
+	}
+	sp := b.Spans[0]
+	assert.Equal(t, int64(traceID), sp.TraceIdLow)
+	assert.Equal(t, int64(spanID), sp.SpanId)
+	assert.Equal(t, int64(parentID), sp.ParentSpanId)
+	assert.Equal(t, "client", sp.OperationName)
+	assert.Equal(t, int32(1), sp.Flags)
+	assert.True(t, sp.StartTime > time.Now().Add(-time.Minute).UnixNano()/int64(time.Microsecond))
+
+	tags := make(map[string]*j.Tag)
+	for _, tag := range sp.Tags {
+		tags[tag.Key] = tag
+	}
+	assert.Equal(t, "client", *tags[trace.TagSpanKind].VStr)
+	assert.Equal(t, int64(3), *tags["n"].VLong)
+	assert.Equal(t, 0.5, *tags["f"].VDouble)
+	assert.True(t, *tags[trace.TagError].VBool)
+
+	assert.Len(t, sp.Logs, 2)
+	assert.Equal(t, trace.LogEvent, sp.Logs[0].Fields[0].Key)
+	assert.Equal(t, "retry", *sp.Logs[0].Fields[0].VStr)
+	assert.Equal(t, "root", b.Spans[1].OperationName)
+}
+
+func TestJaegerHTTP(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		batches []*j.Batch
+		status  = []int{http.StatusServiceUnavailable}
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/traces", r.URL.Path)
+		assert.Equal(t, "application/x-thrift", r.Header.Get("Content-Type"))
+		body, _ := ioutil.ReadAll(r.Body)
+		b := j.NewBatch()
+		buf := thrift.NewTMemoryBuffer()
+		buf.Write(body)
+		assert.NoError(t, b.Read(thrift.NewTBinaryProtocolTransport(buf)))
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, b)
+		if len(status) > 0 {
+			w.WriteHeader(status[0])
+			status = status[1:]
+		}
+	}))
+	defer ts.Close()
+
+	r := newReport(&Config{
+		Endpoint:      ts.URL + "/api/traces",
+		BatchSize:     2,
+		FlushInterval: xtime.Duration(time.Hour),
+	})
+	sp1, sp2 := newSpans(trace.NewTracer("service1", r, true))
+	// the spans are reused after finished.
+	ctx1, ctx2 := sp1.(*trace.Span).Context(), sp2.(*trace.Span).Context()
+	err := errors.New("boom")
+	sp2.Finish(&err)
+	sp1.Finish(nil)
+	// the first batch is retried after 503, but not after closed.
+	for i := 0; i < 100; i++ {
+		mu.Lock()
+		n := len(batches)
+		mu.Unlock()
+		if n >= 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	r.Close()
+	assert.Len(t, batches, 2)
+	for _, b := range batches {
+		assertBatch(t, b, ctx2.TraceID, ctx2.SpanID, ctx1.SpanID)
+	}
+}
+
+func readUDPBatch(t *testing.T, pc net.PacketConn) *j.Batch {
+	pkt := make([]byte, _udpPacketMaxLength)
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(pkt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := thrift.NewTMemoryBuffer()
+	buf.Write(pkt[:n])
+	proto := thrift.NewTCompactProtocol(buf)
+	name, typ, _, err := proto.ReadMessageBegin()
+	assert.NoError(t, err)
+	assert.Equal(t, "emitBatch", name)
+	assert.Equal(t, thrift.ONEWAY, typ)
+	args := agent.NewAgentEmitBatchArgs()
+	assert.NoError(t, args.Read(proto))
+	return args.Batch
+}
+
+func TestJaegerUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	r := newReport(&Config{AgentAddr: pc.LocalAddr().String(), BatchSize: 2})
+	sp1, sp2 := newSpans(trace.NewTracer("service1", r, true))
+	ctx1, ctx2 := sp1.(*trace.Span).Context(), sp2.(*trace.Span).Context()
+	ferr := errors.New("boom")
+	sp2.Finish(&ferr)
+	sp1.Finish(nil)
+	assertBatch(t, readUDPBatch(t, pc), ctx2.TraceID, ctx2.SpanID, ctx1.SpanID)
+	r.Close()
+}
+
+func TestJaegerUDPSplit(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	// the 5 spans do not fit in a packet.
+	r := newReport(&Config{AgentAddr: pc.LocalAddr().String(), MaxPacketSize: 400, BatchSize: 5})
+	tracer := trace.NewTracer("service1", r, true)
+	for i := 0; i < 5; i++ {
+		tracer.New("operation").Finish(nil)
+	}
+	var n int
+	for n < 5 {
+		b := readUDPBatch(t, pc)
+		assert.True(t, len(b.Spans) > 0 && len(b.Spans) < 5, "spans %d", len(b.Spans))
+		n += len(b.Spans)
+	}
+	r.Close()
+}
@@ -1,5 +1,7 @@
 package trace
 
+import "fmt"
+
 var defaultOption = option{}
 
 type option struct {
@@ -15,3 +17,22 @@ func EnableDebug() Option {
 		opt.Debug = true
 	}
 }
+
+// TracerOption is the option of NewTracer.
+type TracerOption func(*dapper)
+
+// WithPropagation sets the propagations injected by the tracer, the default is
+// PropagationKratos, the tracer extracts any propagation whatever they are.
+// It panics if a propagation is unknown.
+func WithPropagation(ps ...Propagation) TracerOption {
+	for _, p := range ps {
+		if _, ok := _codecs[p]; !ok {
+			panic(fmt.Sprintf("trace: unknown propagation: %s", p))
+		}
+	}
+	return func(d *dapper) {
+		if len(ps) > 0 {
+			d.propagations = ps
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package otlp
+
+import (
+	"github.com/gisvr/golib/conf/env"
+	"github.com/gisvr/golib/net/trace"
+	xtime "github.com/gisvr/golib/time"
+)
+
+// Config config.
+// endpoint should be the OTLP/HTTP traces endpoint to send the spans to, e.g.
+// http://localhost:4318/v1/traces
+type Config struct {
+	Endpoint string `dsn:"endpoint"`
+	// Headers are the extra request headers, e.g. the api key of the vendor.
+	Headers       map[string]string `dsn:"-"`
+	BatchSize     int               `dsn:"query.batch_size,100"`
+	FlushInterval xtime.Duration    `dsn:"query.flush_interval,1s"`
+	QueueSize     int               `dsn:"query.queue_size,2048"`
+	MaxRetry      int               `dsn:"query.max_retry,3"`
+	Timeout       xtime.Duration    `dsn:"query.timeout,5s"`
+	DisableSample bool              `dsn:"query.disable_sample"`
+}
+
+// Init init trace report.
+func Init(c *Config, opts ...trace.TracerOption) {
+	trace.SetGlobalTracer(trace.NewTracer(env.AppID, newReport(c), c.DisableSample, opts...))
+}
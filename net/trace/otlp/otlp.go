@@ -0,0 +1,283 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/gisvr/golib/conf/env"
+	"github.com/gisvr/golib/net/trace"
+	"github.com/gisvr/golib/net/trace/internal/batch"
+	protogen "github.com/gisvr/golib/net/trace/proto"
+	xtime "github.com/gisvr/golib/time"
+)
+
+const _scopeName = "github.com/gisvr/golib/net/trace"
+
+// the span kinds of OTLP.
+const (
+	_kindInternal = 1
+	_kindServer   = 2
+	_kindClient   = 3
+	_kindProducer = 4
+	_kindConsumer = 5
+)
+
+const _statusError = 2
+
+// span is the span encoded as the OTLP Span message, the trace.Span is
+// reused after WriteSpan returns.
+type span struct {
+	service string
+	data    []byte
+}
+
+type report struct {
+	c      *Config
+	client *http.Client
+	batch  *batch.Batcher
+}
+
+func newReport(c *Config) *report {
+	if c.Timeout <= 0 {
+		c.Timeout = xtime.Duration(5 * time.Second)
+	}
+	r := &report{
+		c:      c,
+		client: &http.Client{Timeout: time.Duration(c.Timeout)},
+	}
+	r.batch = batch.New(&batch.Config{
+		Name:          "otlp",
+		BatchSize:     c.BatchSize,
+		FlushInterval: time.Duration(c.FlushInterval),
+		QueueSize:     c.QueueSize,
+		MaxRetry:      c.MaxRetry,
+	}, r.send)
+	return r
+}
+
+// WriteSpan write a trace span to queue.
+func (r *report) WriteSpan(raw *trace.Span) error {
+	return r.batch.Add(&span{service: raw.ServiceName(), data: appendSpan(nil, raw)})
+}
+
+// Close close the report.
+func (r *report) Close() error {
+	return r.batch.Close()
+}
+
+// send posts the ExportTraceServiceRequest, the spans are grouped by the
+// service as the resource.
+func (r *report) send(spans []interface{}) error {
+	body, err := marshalRequest(spans)
+	if err != nil {
+		return batch.Permanent(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, r.c.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return batch.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range r.c.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("http status %d", resp.StatusCode)
+	default:
+		return batch.Permanent(fmt.Errorf("http status %d", resp.StatusCode))
+	}
+}
+
+// marshalRequest marshals the ExportTraceServiceRequest:
+// 1: resource_spans ResourceSpans
+// ResourceSpans: 1: resource Resource, 2: scope_spans ScopeSpans
+// Resource: 1: attributes KeyValue
+// ScopeSpans: 1: scope InstrumentationScope, 2: spans Span
+func marshalRequest(spans []interface{}) ([]byte, error) {
+	var services []string
+	groups := make(map[string][]*span)
+	for _, v := range spans {
+		sp, ok := v.(*span)
+		if !ok {
+			return nil, fmt.Errorf("otlp: unknown span type %T", v)
+		}
+		if _, ok := groups[sp.service]; !ok {
+			services = append(services, sp.service)
+		}
+		groups[sp.service] = append(groups[sp.service], sp)
+	}
+	var b []byte
+	for _, service := range services {
+		var resource []byte
+		resource = appendBytes(resource, 1, appendAttr(nil, "service.name", service))
+		resource = appendBytes(resource, 1, appendAttr(nil, "host.name", env.Hostname))
+
+		var scope []byte
+		scope = appendBytes(scope, 1, appendBytes(nil, 1, []byte(_scopeName)))
+		for _, sp := range groups[service] {
+			scope = appendBytes(scope, 2, sp.data)
+		}
+
+		var rs []byte
+		rs = appendBytes(rs, 1, resource)
+		rs = appendBytes(rs, 2, scope)
+		b = appendBytes(b, 1, rs)
+	}
+	return b, nil
+}
+
+// appendSpan appends the Span message:
+// 1: trace_id, 2: span_id, 3: trace_state, 4: parent_span_id, 5: name,
+// 6: kind, 7: start_time_unix_nano, 8: end_time_unix_nano, 9: attributes,
+// 11: events, 15: status
+func appendSpan(b []byte, sp *trace.Span) []byte {
+	ctx := sp.Context()
+	var id [16]byte
+	binary.BigEndian.PutUint64(id[:8], ctx.TraceIDHigh)
+	binary.BigEndian.PutUint64(id[8:], ctx.TraceID)
+	b = appendBytes(b, 1, id[:])
+	binary.BigEndian.PutUint64(id[:8], ctx.SpanID)
+	b = appendBytes(b, 2, id[:8])
+	if ctx.TraceState != "" {
+		b = appendBytes(b, 3, []byte(ctx.TraceState))
+	}
+	if ctx.ParentID != 0 {
+		binary.BigEndian.PutUint64(id[:8], ctx.ParentID)
+		b = appendBytes(b, 4, id[:8])
+	}
+	b = appendBytes(b, 5, []byte(sp.OperationName()))
+
+	kind, failed := _kindInternal, false
+	var attrs []byte
+	for _, tag := range sp.Tags() {
+		switch tag.Key {
+		case trace.TagSpanKind:
+			kind = spanKind(tag.Value)
+			continue
+		case trace.TagError:
+			failed, _ = tag.Value.(bool)
+		}
+		attrs = appendBytes(attrs, 9, appendAttr(nil, tag.Key, tag.Value))
+	}
+	b = protowire.AppendTag(b, 6, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(kind))
+	start := sp.StartTime()
+	b = protowire.AppendTag(b, 7, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, uint64(start.UnixNano()))
+	b = protowire.AppendTag(b, 8, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, uint64(start.Add(sp.Duration()).UnixNano()))
+	b = append(b, attrs...)
+
+	var message string
+	for _, lg := range sp.Logs() {
+		b = appendBytes(b, 11, appendEvent(nil, lg))
+		for _, f := range lg.Fields {
+			if f.Key == trace.LogMessage && message == "" {
+				message = string(f.Value)
+			}
+		}
+	}
+	if failed {
+		// Status: 2: message, 3: code
+		var status []byte
+		if message != "" {
+			status = appendBytes(status, 2, []byte(message))
+		}
+		status = protowire.AppendTag(status, 3, protowire.VarintType)
+		status = protowire.AppendVarint(status, _statusError)
+		b = appendBytes(b, 15, status)
+	}
+	return b
+}
+
+func spanKind(v interface{}) int {
+	switch v {
+	case "server":
+		return _kindServer
+	case "client":
+		return _kindClient
+	case "producer":
+		return _kindProducer
+	case "consumer":
+		return _kindConsumer
+	}
+	return _kindInternal
+}
+
+// appendEvent appends the Event message of the log, the name is the event
+// field or "log":
+// 1: time_unix_nano, 2: name, 3: attributes
+func appendEvent(b []byte, lg *protogen.Log) []byte {
+	name := "log"
+	b = protowire.AppendTag(b, 1, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, uint64(lg.Timestamp))
+	var attrs []byte
+	for _, f := range lg.Fields {
+		if f.Key == trace.LogEvent {
+			name = string(f.Value)
+			continue
+		}
+		attrs = appendBytes(attrs, 3, appendAttr(nil, f.Key, string(f.Value)))
+	}
+	b = appendBytes(b, 2, []byte(name))
+	return append(b, attrs...)
+}
+
+// appendAttr appends the KeyValue message:
+// 1: key, 2: value AnyValue
+// AnyValue: 1: string_value, 2: bool_value, 3: int_value, 4: double_value
+func appendAttr(b []byte, key string, val interface{}) []byte {
+	b = appendBytes(b, 1, []byte(key))
+	var v []byte
+	switch val := val.(type) {
+	case string:
+		v = appendBytes(v, 1, []byte(val))
+	case bool:
+		v = protowire.AppendTag(v, 2, protowire.VarintType)
+		v = protowire.AppendVarint(v, protowire.EncodeBool(val))
+	case int:
+		v = appendInt(v, int64(val))
+	case int32:
+		v = appendInt(v, int64(val))
+	case int64:
+		v = appendInt(v, val)
+	case float32:
+		v = appendDouble(v, float64(val))
+	case float64:
+		v = appendDouble(v, val)
+	default:
+		v = appendBytes(v, 1, []byte(fmt.Sprint(val)))
+	}
+	return appendBytes(b, 2, v)
+}
+
+func appendInt(b []byte, v int64) []byte {
+	b = protowire.AppendTag(b, 3, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(v))
+}
+
+func appendDouble(b []byte, v float64) []byte {
+	b = protowire.AppendTag(b, 4, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(v))
+}
+
+func appendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
@@ -0,0 +1,201 @@
+package otlp
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/gisvr/golib/net/trace"
+	xtime "github.com/gisvr/golib/time"
+)
+
+// message is a decoded protobuf message, the values are []byte of the bytes
+// type and uint64 of the varint and fixed64 types.
+type message map[protowire.Number][]interface{}
+
+func decode(t *testing.T, b []byte) message {
+	m := make(message)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatal(protowire.ParseError(n))
+		}
+		b = b[n:]
+		var v interface{}
+		switch typ {
+		case protowire.BytesType:
+			v, n = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			v, n = protowire.ConsumeFixed64(b)
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+		if n < 0 {
+			t.Fatal(protowire.ParseError(n))
+		}
+		b = b[n:]
+		m[num] = append(m[num], v)
+	}
+	return m
+}
+
+func (m message) msg(t *testing.T, num protowire.Number) message {
+	return decode(t, m[num][0].([]byte))
+}
+
+func (m message) str(num protowire.Number) string {
+	return string(m[num][0].([]byte))
+}
+
+// attrs decodes the KeyValue of num to the AnyValue by the key.
+func (m message) attrs(t *testing.T, num protowire.Number) map[string]message {
+	attrs := make(map[string]message)
+	for _, v := range m[num] {
+		kv := decode(t, v.([]byte))
+		attrs[kv.str(1)] = kv.msg(t, 2)
+	}
+	return attrs
+}
+
+type collector struct {
+	*httptest.Server
+	mu     sync.Mutex
+	bodies [][]byte
+	status []int
+}
+
+func newCollector(t *testing.T, status ...int) *collector {
+	c := &collector{status: status}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "secret", r.Header.Get("Api-Key"))
+		body, _ := ioutil.ReadAll(r.Body)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.bodies = append(c.bodies, body)
+		if len(c.status) > 0 {
+			w.WriteHeader(c.status[0])
+			c.status = c.status[1:]
+		}
+	}))
+	return c
+}
+
+// wait waits until n bodies are received.
+func (c *collector) wait(n int) {
+	for i := 0; i < 100; i++ {
+		c.mu.Lock()
+		l := len(c.bodies)
+		c.mu.Unlock()
+		if l >= n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func newTestReport(c *collector, batchSize int) *report {
+	return newReport(&Config{
+		Endpoint:      c.URL + "/v1/traces",
+		BatchSize:     batchSize,
+		Headers:       map[string]string{"Api-Key": "secret"},
+		FlushInterval: xtime.Duration(time.Hour),
+		MaxRetry:      2,
+	})
+}
+
+func TestOTLPPayload(t *testing.T) {
+	c := newCollector(t)
+	defer c.Close()
+	r := newTestReport(c, 0)
+	tracer := trace.NewTracer("service1", r, true)
+
+	sp1 := tracer.New("root")
+	sp2 := sp1.Fork("", "client").SetTag(trace.TagInt64("n", 3), trace.TagFloat64("f", 0.5), trace.TagBool("b", true))
+	sp2.SetLog(trace.Log(trace.LogEvent, "retry"), trace.Log("attempt", "2"))
+	// the spans are reused after finished.
+	ctx1, ctx2 := sp1.(*trace.Span).Context(), sp2.(*trace.Span).Context()
+	err := errors.New("boom")
+	sp2.Finish(&err)
+	sp1.Finish(nil)
+	assert.NoError(t, r.Close())
+
+	assert.Len(t, c.bodies, 1)
+	req := decode(t, c.bodies[0])
+	assert.Len(t, req[1], 1)
+	rs := req.msg(t, 1)
+	resource := rs.msg(t, 1).attrs(t, 1)
+	assert.Equal(t, "service1", resource["service.name"].str(1))
+	scope := rs.msg(t, 2)
+	assert.Equal(t, _scopeName, scope.msg(t, 1).str(1))
+	assert.Len(t, scope[2], 2)
+
+	// the client span is finished first.
+	span := decode(t, scope[2][0].([]byte))
+	traceID := span[1][0].([]byte)
+	assert.Len(t, traceID, 16)
+	assert.Equal(t, ctx2.TraceID, binary.BigEndian.Uint64(traceID[8:]))
+	assert.Equal(t, ctx2.SpanID, binary.BigEndian.Uint64(span[2][0].([]byte)))
+	assert.Equal(t, ctx1.SpanID, binary.BigEndian.Uint64(span[4][0].([]byte)))
+	assert.Equal(t, "client", span.str(5))
+	assert.Equal(t, uint64(_kindClient), span[6][0])
+	assert.True(t, span[8][0].(uint64) >= span[7][0].(uint64))
+
+	attrs := span.attrs(t, 9)
+	assert.Equal(t, uint64(3), attrs["n"][3][0])
+	assert.Equal(t, 0.5, math.Float64frombits(attrs["f"][4][0].(uint64)))
+	assert.Equal(t, uint64(1), attrs["b"][2][0])
+	assert.Equal(t, uint64(1), attrs[trace.TagError][2][0])
+	assert.NotContains(t, attrs, trace.TagSpanKind)
+
+	assert.Len(t, span[11], 2)
+	event := decode(t, span[11][0].([]byte))
+	assert.Equal(t, "retry", event.str(2))
+	assert.Equal(t, "2", event.attrs(t, 3)["attempt"].str(1))
+	event = decode(t, span[11][1].([]byte))
+	assert.Equal(t, "log", event.str(2))
+	assert.Equal(t, "boom", event.attrs(t, 3)[trace.LogMessage].str(1))
+
+	status := span.msg(t, 15)
+	assert.Equal(t, "boom", status.str(2))
+	assert.Equal(t, uint64(_statusError), status[3][0])
+
+	root := decode(t, scope[2][1].([]byte))
+	assert.Nil(t, root[4])
+	assert.Equal(t, uint64(_kindServer), root[6][0])
+	assert.Nil(t, root[15])
+}
+
+func TestOTLPRetry(t *testing.T) {
+	c := newCollector(t, http.StatusServiceUnavailable, http.StatusOK)
+	defer c.Close()
+	r := newTestReport(c, 1)
+	tracer := trace.NewTracer("service1", r, true)
+	tracer.New("root").Finish(nil)
+	// the batch is not retried after closed.
+	c.wait(2)
+	r.Close()
+	assert.Len(t, c.bodies, 2)
+	assert.Equal(t, c.bodies[0], c.bodies[1])
+
+	c = newCollector(t, http.StatusBadRequest)
+	defer c.Close()
+	r = newTestReport(c, 1)
+	tracer = trace.NewTracer("service1", r, true)
+	tracer.New("root").Finish(nil)
+	r.Close()
+	assert.Len(t, c.bodies, 1)
+}
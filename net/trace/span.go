@@ -126,9 +126,12 @@ func (s *Span) setLog(logs ...LogField) Trace {
 	return s
 }
 
-// Visit visits the k-v pair in trace, calling fn for each.
+// Visit visits the k-v pair in trace of the propagations of the tracer,
+// calling fn for each.
 func (s *Span) Visit(fn func(k, v string)) {
-	fn(KratosTraceID, s.context.String())
+	for _, p := range s.dapper.propagations {
+		_codecs[p].inject(s.context, fn)
+	}
 }
 
 // SetTitle reset trace title
@@ -5,8 +5,9 @@ import (
 	"net"
 	"net/http"
 
-	"github.com/golang/glog"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/gisvr/golib/log/glog"
 )
 
 type PrometheusServer struct {
@@ -27,8 +28,11 @@ func (s *PrometheusServer) Run(ctx context.Context) error {
 		defer cancel()
 
 		glog.V(4).Info("initializing Prometheus ...")
-		http.Handle("/metrics", promhttp.Handler())
-		srv := &http.Server{}
+		// the metrics are served by a private mux, the handlers registered to
+		// http.DefaultServeMux such as pprof are not exposed.
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		srv := &http.Server{Handler: mux}
 		ln, err := net.Listen("tcp", s.config.Addr)
 		if err != nil {
 			if s.fatalOnError {
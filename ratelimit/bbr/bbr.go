@@ -249,6 +249,18 @@ func NewGroup(conf *Config) *Group {
 	}
 }
 
+// Stats returns the snapshots of the limiters keyed by the key.
+func (g *Group) Stats() map[string]Stat {
+	stats := make(map[string]Stat)
+	g.group.Range(func(key string, obj interface{}) bool {
+		if l, ok := obj.(*BBR); ok {
+			stats[key] = l.Stat()
+		}
+		return true
+	})
+	return stats
+}
+
 // Get get a limiter by a specified key, if limiter not exists then make a new one.
 func (g *Group) Get(key string) limit.Limiter {
 	limiter := g.group.Get(key)